@@ -0,0 +1,314 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/github"
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/urfave/cli"
+)
+
+// defaultRequiredReviewers is how many reviewers a pull request needs when its repo's rules don't say
+const defaultRequiredReviewers = 1
+
+// reviewerAssigner requests reviews on pull requests that don't have enough reviewers yet
+// It spreads the requests out by giving them to whoever has the fewest pending review requests
+type reviewerAssigner struct {
+	writer      io.Writer
+	errorWriter io.Writer
+	client      *github.Client
+	required    int
+	dryRun      bool
+	load        map[string]int
+	codeOwners  map[string]codeOwners
+}
+
+// CmdAssignReviewers requests reviews from code owners on pull requests that don't have enough reviewers
+func CmdAssignReviewers(c *cli.Context) error {
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() != 0 {
+		return cli.NewExitError("Usage: \"prp assign-reviewers\"", 1)
+	}
+
+	profile := configData.Profiles[*profileName]
+	client, err := getGithubClient(&profile.Token, &profile.APIURL, false)
+	if err != nil {
+		return err
+	}
+
+	user, _, err := client.Users.Get(context.Background(), "")
+	if err != nil {
+		return err
+	}
+
+	// Every tracked pull request counts towards the load, even the ones that aren't selected
+	prs := selectPullRequests(newParser(client, user, &profile).getBasePullRequestData(c.App.ErrWriter), 0)
+
+	assigner := &reviewerAssigner{
+		writer:      c.App.Writer,
+		errorWriter: c.App.ErrWriter,
+		client:      client,
+		required:    c.Int("required"),
+		dryRun:      c.Bool("dry-run"),
+		codeOwners:  make(map[string]codeOwners),
+	}
+
+	return assigner.assign(prs, c.String("owner"), c.StringSlice("repo"))
+}
+
+func (a *reviewerAssigner) assign(prs []*pullRequest, owner string, repos []string) error {
+	reviewers := a.getReviewers(prs)
+	a.load = make(map[string]int)
+	for _, pr := range prs {
+		for _, reviewer := range reviewers[pr].requested {
+			a.load[reviewer]++
+		}
+	}
+
+	var completeError error
+	found := false
+	for _, pr := range sortPullRequests(prs) {
+		prReviewers, ok := reviewers[pr]
+		if !ok || pr.Draft || !pr.matchesRepoFilter(owner, repos) {
+			continue
+		}
+
+		assigned, err := a.assignPullRequest(pr, prReviewers)
+		if err != nil {
+			fmt.Fprintf(a.errorWriter, "Could not assign reviewers to PR #%d in %s/%s because: %v\n", pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, err)
+			completeError = cli.NewExitError("Unable to assign reviewers to all pull requests", 1)
+			continue
+		}
+
+		found = found || assigned
+	}
+
+	if !found && completeError == nil {
+		fmt.Fprintln(a.writer, "No pull requests need reviewers")
+	}
+
+	return completeError
+}
+
+// pullRequestReviewers are the users who were asked to review a pull request and the ones who already did
+type pullRequestReviewers struct {
+	requested []string
+	reviewed  []string
+}
+
+// all returns everyone who counts as a reviewer of pr
+func (r pullRequestReviewers) all(pr *pullRequest) []string {
+	reviewers := []string{}
+	for _, reviewer := range unique(append(append([]string{}, r.requested...), r.reviewed...)) {
+		if reviewer != pr.Owner {
+			reviewers = append(reviewers, reviewer)
+		}
+	}
+
+	return reviewers
+}
+
+func (a *reviewerAssigner) getReviewers(prs []*pullRequest) map[*pullRequest]pullRequestReviewers {
+	allReviewers := make(map[*pullRequest]pullRequestReviewers)
+	mutex := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for _, pr := range prs {
+		wg.Add(1)
+		go func(pr *pullRequest) {
+			defer wg.Done()
+			requested, _, err := pr.client.PullRequests.ListReviewers(context.Background(), pr.Repo.Owner, pr.Repo.Name, pr.PullRequestID, nil)
+			if err != nil {
+				fmt.Fprintf(a.errorWriter, "Could not load the reviewers of PR #%d in %s/%s because: %v\n", pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, err)
+				return
+			}
+
+			reviewers := pullRequestReviewers{requested: []string{}, reviewed: []string{}}
+			for _, user := range requested.Users {
+				reviewers.requested = append(reviewers.requested, user.GetLogin())
+			}
+
+			for review := range pr.getReviews() {
+				reviewers.reviewed = append(reviewers.reviewed, review.User.GetLogin())
+			}
+
+			mutex.Lock()
+			allReviewers[pr] = reviewers
+			mutex.Unlock()
+		}(pr)
+	}
+
+	wg.Wait()
+	return allReviewers
+}
+
+// assignPullRequest requests reviews on pr from the least loaded candidates until it has as many reviewers as its repo requires
+// It returns whether pr needed reviewers
+func (a *reviewerAssigner) assignPullRequest(pr *pullRequest, reviewers pullRequestReviewers) (bool, error) {
+	rules := pr.Repo.ReviewerRules
+	if rules == nil {
+		rules = &config.ReviewerRules{}
+	}
+
+	required := a.required
+	if required == 0 {
+		required = rules.Required
+	}
+
+	if required == 0 {
+		required = defaultRequiredReviewers
+	}
+
+	current := reviewers.all(pr)
+	if len(current) >= required {
+		return false, nil
+	}
+
+	candidates, err := a.getCandidates(pr, rules)
+	if err != nil {
+		return true, err
+	}
+
+	eligible := []string{}
+	for _, candidate := range candidates {
+		if candidate != pr.Owner && !stringSliceContains(candidate, current) && !stringSliceContains(candidate, rules.Exclude) {
+			eligible = append(eligible, candidate)
+		}
+	}
+
+	if len(eligible) == 0 {
+		fmt.Fprintf(a.writer, "PR #%d in %s/%s needs %d more reviewers but nobody else is eligible\n", pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, required-len(current))
+		return true, nil
+	}
+
+	sort.Slice(eligible, func(i, j int) bool {
+		if a.load[eligible[i]] != a.load[eligible[j]] {
+			return a.load[eligible[i]] < a.load[eligible[j]]
+		}
+
+		return eligible[i] < eligible[j]
+	})
+
+	if len(eligible) > required-len(current) {
+		eligible = eligible[:required-len(current)]
+	}
+
+	if a.dryRun {
+		fmt.Fprintf(a.writer, "Would request reviews from %s on PR #%d in %s/%s: %s\n", strings.Join(eligible, ", "), pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, pr.Title)
+	} else {
+		_, _, err = pr.client.PullRequests.RequestReviewers(context.Background(), pr.Repo.Owner, pr.Repo.Name, pr.PullRequestID, github.ReviewersRequest{Reviewers: eligible})
+		if err != nil {
+			return true, err
+		}
+
+		fmt.Fprintf(a.writer, "Requested reviews from %s on PR #%d in %s/%s: %s\n", strings.Join(eligible, ", "), pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, pr.Title)
+	}
+
+	for _, reviewer := range eligible {
+		a.load[reviewer]++
+	}
+
+	return true, nil
+}
+
+// getCandidates returns the code owners of the files pr changes with their teams replaced by the teams' members
+// When none of the files have owners every member of the repo's teams is a candidate
+func (a *reviewerAssigner) getCandidates(pr *pullRequest, rules *config.ReviewerRules) ([]string, error) {
+	owners, err := a.getCodeOwners(pr.Repo)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := pr.listFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := []string{}
+	for _, file := range files {
+		for _, owner := range owners.ownersOf(file) {
+			// Teams look like org/team, their members come from the rules because Github only lets org members list them
+			if strings.Contains(owner, "/") {
+				candidates = append(candidates, rules.Teams[owner]...)
+				continue
+			}
+
+			candidates = append(candidates, owner)
+		}
+	}
+
+	if len(candidates) == 0 {
+		for _, members := range rules.Teams {
+			candidates = append(candidates, members...)
+		}
+	}
+
+	return unique(candidates), nil
+}
+
+func (a *reviewerAssigner) getCodeOwners(repo *config.Repo) (codeOwners, error) {
+	fullName := fmt.Sprintf("%s/%s", repo.Owner, repo.Name)
+	if owners, ok := a.codeOwners[fullName]; ok {
+		return owners, nil
+	}
+
+	owners, err := getCodeOwners(a.client, repo.Owner, repo.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	a.codeOwners[fullName] = owners
+	return owners, nil
+}
+
+func (pr pullRequest) listFiles() ([]string, error) {
+	opt := &github.ListOptions{PerPage: 100}
+	files := []string{}
+	for {
+		page, resp, err := pr.client.PullRequests.ListFiles(context.Background(), pr.Repo.Owner, pr.Repo.Name, pr.PullRequestID, opt)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range page {
+			files = append(files, file.GetFilename())
+		}
+
+		if resp.NextPage == 0 {
+			return files, nil
+		}
+
+		opt.Page = resp.NextPage
+	}
+}
+
+// CompleteAssignReviewers handles bash autocompletion for the 'assign-reviewers' command
+func CompleteAssignReviewers(c *cli.Context) {
+	lastParam := os.Args[len(os.Args)-2]
+	if lastParam != "--repo" && lastParam != "--owner" {
+		completeFlags(c, "assign-reviewers")
+		return
+	}
+
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return
+	}
+
+	profile := configData.Profiles[*profileName]
+	if lastParam == "--owner" {
+		completeUser(&profile, c.App.Writer, c.App.ErrWriter)
+		return
+	}
+
+	completeRepo(c.StringSlice("repo"), profile, c.App.Writer)
+}
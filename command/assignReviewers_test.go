@@ -0,0 +1,228 @@
+package command_test
+
+import (
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/guywithnose/pull-request-parser/command"
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+// assignReviewersCodeOwners makes the own/core team own everything and carol own markdown files
+const assignReviewersCodeOwners = "# Owners\n* @own/core\n*.md @carol # docs\n"
+
+func TestCmdAssignReviewers(t *testing.T) {
+	rules := func(repo *config.Repo) {
+		repo.ReviewerRules = &config.ReviewerRules{Required: 2, Teams: map[string][]string{"own/core": {"guy", "guy2", "alice", "bob"}}}
+	}
+	var testCases = []struct {
+		name             string
+		modifyRepo       func(*config.Repo)
+		setFlags         func(*flag.FlagSet)
+		codeOwners       string
+		expectedRequests []string
+		expectedOutput   []string
+	}{
+		{
+			"CodeOwners",
+			rules,
+			func(*flag.FlagSet) {},
+			assignReviewersCodeOwners,
+			[]string{
+				`POST /repos/own/rep/pulls/1/requested_reviewers {"reviewers":["guy2"]}`,
+				`POST /repos/own/rep/pulls/2/requested_reviewers {"reviewers":["carol"]}`,
+			},
+			[]string{
+				"Requested reviews from guy2 on PR #1 in own/rep: prOne",
+				"Requested reviews from carol on PR #2 in own/rep: Really long Pull Request Title",
+			},
+		},
+		{
+			"LeastLoaded",
+			rules,
+			func(set *flag.FlagSet) { set.Int("required", 3, "doc") },
+			assignReviewersCodeOwners,
+			[]string{
+				`POST /repos/own/rep/pulls/1/requested_reviewers {"reviewers":["guy2","bob"]}`,
+				`POST /repos/own/rep/pulls/2/requested_reviewers {"reviewers":["carol"]}`,
+			},
+			[]string{
+				"Requested reviews from guy2, bob on PR #1 in own/rep: prOne",
+				"Requested reviews from carol on PR #2 in own/rep: Really long Pull Request Title",
+			},
+		},
+		{
+			"Exclude",
+			func(repo *config.Repo) {
+				rules(repo)
+				repo.ReviewerRules.Exclude = []string{"guy2", "carol"}
+			},
+			func(*flag.FlagSet) {},
+			assignReviewersCodeOwners,
+			[]string{`POST /repos/own/rep/pulls/1/requested_reviewers {"reviewers":["bob"]}`},
+			[]string{
+				"Requested reviews from bob on PR #1 in own/rep: prOne",
+				"PR #2 in own/rep needs 1 more reviewers but nobody else is eligible",
+			},
+		},
+		{
+			"NoCodeOwners",
+			rules,
+			func(set *flag.FlagSet) { set.String("owner", "guy2", "doc") },
+			"",
+			[]string{`POST /repos/own/rep/pulls/2/requested_reviewers {"reviewers":["bob"]}`},
+			[]string{"Requested reviews from bob on PR #2 in own/rep: Really long Pull Request Title"},
+		},
+		{
+			"DryRun",
+			rules,
+			func(set *flag.FlagSet) {
+				set.Bool("dry-run", true, "doc")
+				set.String("owner", "guy", "doc")
+			},
+			assignReviewersCodeOwners,
+			[]string{},
+			[]string{"Would request reviews from guy2 on PR #1 in own/rep: prOne"},
+		},
+		{
+			"EnoughReviewers",
+			func(*config.Repo) {},
+			func(*flag.FlagSet) {},
+			assignReviewersCodeOwners,
+			[]string{},
+			[]string{"No pull requests need reviewers"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := []string{}
+			inner := getParseTestServer("")
+			defer inner.Close()
+			ts := getAssignReviewersTestServer(inner, tc.codeOwners, &requests, map[string]string{})
+			defer ts.Close()
+			writer, errWriter, err := runAgainstServer(t, ts, command.CmdAssignReviewers, withRepo(tc.modifyRepo), tc.setFlags)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedRequests, requests)
+			assert.Equal(t, strings.Join(append(tc.expectedOutput, ""), "\n"), writer.String())
+			assert.Equal(t, "", errWriter.String())
+		})
+	}
+}
+
+func TestCmdAssignReviewersFailure(t *testing.T) {
+	requests := []string{}
+	inner := getParseTestServer("")
+	defer inner.Close()
+	ts := getAssignReviewersTestServer(inner, assignReviewersCodeOwners, &requests, map[string]string{"/repos/own/rep/pulls/1/files?per_page=100": "not json"})
+	defer ts.Close()
+	writer, errWriter, err := runAgainstServer(t, ts, command.CmdAssignReviewers, withRepo(func(repo *config.Repo) {
+		repo.ReviewerRules = &config.ReviewerRules{Required: 2}
+	}), func(*flag.FlagSet) {})
+	assert.EqualError(t, err, "Unable to assign reviewers to all pull requests")
+	assert.Equal(t, []string{`POST /repos/own/rep/pulls/2/requested_reviewers {"reviewers":["carol"]}`}, requests)
+	assert.Equal(t, "Requested reviews from carol on PR #2 in own/rep: Really long Pull Request Title\n", writer.String())
+	assert.Contains(t, errWriter.String(), "Could not assign reviewers to PR #1 in own/rep because: ")
+}
+
+func TestCmdAssignReviewersCodeOwnersFailure(t *testing.T) {
+	requests := []string{}
+	inner := getParseTestServer("")
+	defer inner.Close()
+	ts := getAssignReviewersTestServer(inner, "", &requests, map[string]string{"/repos/own/rep/contents/.github/CODEOWNERS": "not json"})
+	defer ts.Close()
+	_, errWriter, err := runAgainstServer(t, ts, command.CmdAssignReviewers, withRepo(func(repo *config.Repo) {
+		repo.ReviewerRules = &config.ReviewerRules{Required: 2}
+	}), func(*flag.FlagSet) {})
+	assert.EqualError(t, err, "Unable to assign reviewers to all pull requests")
+	assert.Equal(t, []string{}, requests)
+	assert.Contains(t, errWriter.String(), "Could not assign reviewers to PR #1 in own/rep because: ")
+}
+
+func TestCmdAssignReviewersNoConfig(t *testing.T) {
+	app, _, _ := appWithTestWriters()
+	err := command.CmdAssignReviewers(cli.NewContext(app, flag.NewFlagSet("test", 0), nil))
+	assert.EqualError(t, err, "You must specify a config file")
+}
+
+func TestCmdAssignReviewersUsage(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"foo"}))
+	app, _, _ := appWithTestWriters()
+	err := command.CmdAssignReviewers(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Usage: \"prp assign-reviewers\"")
+}
+
+func TestCompleteAssignReviewersFlags(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	app, writer, _ := appWithTestWriters()
+	app.Commands = []cli.Command{
+		{
+			Name: "assign-reviewers",
+			Flags: []cli.Flag{
+				cli.StringFlag{Name: "owner, o"},
+				cli.IntFlag{Name: "required"},
+			},
+		},
+	}
+	// The exit code helper that auto-rebase's tests use runs os.Args[0]
+	defer func(args []string) { os.Args = args }(os.Args)
+	os.Args = []string{"assign-reviewers", "--completion"}
+	command.CompleteAssignReviewers(cli.NewContext(app, set, nil))
+	assert.Equal(t, "--owner\n--required\n", writer.String())
+}
+
+func TestCompleteAssignReviewersRepo(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	app, writer, _ := appWithTestWriters()
+	// The exit code helper that auto-rebase's tests use runs os.Args[0]
+	defer func(args []string) { os.Args = args }(os.Args)
+	os.Args = []string{"assign-reviewers", "--repo", "--completion"}
+	command.CompleteAssignReviewers(cli.NewContext(app, set, nil))
+	assert.Equal(t, "foo/bar\nown/rep\n", writer.String())
+}
+
+// getAssignReviewersTestServer serves PR #1 in own/rep changing main.go and PR #2 changing README.md
+// alice has two pending review requests on foo/bar and bob has one
+// own/rep has the given CODEOWNERS file unless it is empty, and every request that isn't a GET is recorded
+func getAssignReviewersTestServer(inner *httptest.Server, codeOwners string, requests *[]string, responses map[string]string) *httptest.Server {
+	notFound := &httptest.Server{Config: &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/contents/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		inner.Config.Handler.ServeHTTP(w, r)
+	})}}
+
+	defaultResponses := map[string]string{
+		"/repos/own/rep/pulls/1/requested_reviewers": `{"users":[]}`,
+		"/repos/own/rep/pulls/2/requested_reviewers": `{"users":[]}`,
+		"/repos/foo/bar/pulls/1/requested_reviewers": `{"users":[{"login":"alice"}]}`,
+		"/repos/foo/bar/pulls/2/requested_reviewers": `{"users":[{"login":"alice"},{"login":"bob"}]}`,
+		"/repos/own/rep/pulls/1/files?per_page=100":  `[{"filename":"main.go"}]`,
+		"/repos/own/rep/pulls/2/files?per_page=100":  `[{"filename":"README.md"}]`,
+	}
+	if codeOwners != "" {
+		defaultResponses["/repos/own/rep/contents/.github/CODEOWNERS"] = fmt.Sprintf(
+			`{"type":"file","encoding":"base64","content":"%s"}`,
+			base64.StdEncoding.EncodeToString([]byte(codeOwners)),
+		)
+	}
+
+	return getRecordingOverridingTestServer(notFound, requests, mergeResponses(defaultResponses, responses))
+}
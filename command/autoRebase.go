@@ -11,6 +11,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/google/go-github/github"
 	"github.com/guywithnose/pull-request-parser/config"
 	"github.com/guywithnose/runner"
 	"github.com/urfave/cli"
@@ -30,54 +31,131 @@ func cmdAutoRebaseHelper(c *cli.Context, cmdWrapper runner.Builder) error {
 		return err
 	}
 
-	if c.NArg() != 0 {
-		return cli.NewExitError("Usage: \"prp auto-rebase\"", 1)
+	undo := c.NArg() == 1 && c.Args().First() == "undo"
+	if c.NArg() != 0 && !undo {
+		return cli.NewExitError("Usage: \"prp auto-rebase [undo]\"", 1)
 	}
 
 	profile := configData.Profiles[*profileName]
 
-	pullRequests, err := getValidPullRequests(&profile, c.StringSlice("repo"), c.Bool("use-cache"), c.App.ErrWriter)
+	verboseWriter := ioutil.Discard
+	if c.Bool("verbose") {
+		verboseWriter = c.App.ErrWriter
+	}
+
+	nativeGit, err := useNativeGit(c.String("git-backend"))
 	if err != nil {
 		return err
 	}
 
-	verboseWriter := ioutil.Discard
-	if c.Bool("verbose") {
-		verboseWriter = c.App.ErrWriter
+	options := rebaseOptions{
+		leaveConflicts: c.Bool("leave-conflicts"),
+		backupRef:      c.Bool("backup-ref"),
+		comment:        c.Bool("comment"),
+		autosquash:     c.Bool("autosquash"),
+		nativeGit:      nativeGit,
+		policy:         rebasePolicyFromFlags(c),
+	}
+	stacks, err := loadStackRecord(stacksFile(c))
+	if err != nil {
+		return err
+	}
+
+	rebaser := newRebaser(c.App.ErrWriter, verboseWriter, cmdWrapper, newJournal(journalFile(c)), stacks, options)
+	if undo {
+		return rebaser.undoPushes(c.StringSlice("repo"), c.Int("pull-request-number"), c.App.Writer, os.Stdin, c.Bool("yes"))
+	}
+
+	if c.Bool("continue") {
+		return rebaser.continueRebases(&profile, c.StringSlice("repo"), c.Int("pull-request-number"))
+	}
+
+	owner := c.String("owner")
+	if c.Bool("all-owners") {
+		owner = allOwners
+	}
+
+	if c.Bool("watch") {
+		return newWatcher(rebaser, &profile, c.App.Writer, watchOptionsFromFlags(c)).watch(owner, c.StringSlice("repo"), c.Int("pull-request-number"))
+	}
+
+	pullRequests, err := getPullRequestsByOwner(&profile, owner, c.StringSlice("repo"), c.Bool("use-cache"), c.App.ErrWriter)
+	if err != nil {
+		return err
+	}
+
+	summary, err := rebaser.rebaseSelectedPullRequests(selectPullRequests(pullRequests, c.Int("pull-request-number")))
+	if c.Bool("wait-for-ci") {
+		newBuildWaiter(verboseWriter, c.Duration("ci-timeout")).waitForBuilds(summary.rebased, c.App.Writer)
 	}
 
-	return newRebaser(c.App.ErrWriter, verboseWriter, cmdWrapper).rebasePullRequests(pullRequests, c.Int("pull-request-number"))
+	return err
 }
 
-func getValidPullRequests(profile *config.Profile, repos []string, useCache bool, errWriter io.Writer) (<-chan *pullRequest, error) {
+// allOwners can be passed to getPullRequestsByOwner to get everyone's pull requests
+const allOwners = "*"
+
+// getPullRequestsByOwner returns all of owner's pull requests along with whether they are rebased
+// An empty owner means the authenticated user
+// Pull requests that are already rebased are included because they may be stacked on one that isn't
+func getPullRequestsByOwner(profile *config.Profile, owner string, repos []string, useCache bool, errWriter io.Writer) (<-chan *pullRequest, error) {
 	client, err := getGithubClient(&profile.Token, &profile.APIURL, useCache)
 	if err != nil {
 		return nil, err
 	}
 
+	return getPullRequestsWithClient(client, profile, owner, repos, errWriter)
+}
+
+func getPullRequestsWithClient(client *github.Client, profile *config.Profile, owner string, repos []string, errWriter io.Writer) (<-chan *pullRequest, error) {
 	user, _, err := client.Users.Get(context.Background(), "")
 	if err != nil {
 		return nil, err
 	}
 
 	prs := newParser(client, user, profile).getBasePullRequestData(errWriter)
-	prs = filterPullRequestsByRepo(prs, *user.Login, repos)
+	switch owner {
+	case "":
+		owner = user.GetLogin()
+	case allOwners:
+		owner = ""
+	}
 
-	filteredPullRequests := make(chan *pullRequest, 5)
+	prs = filterPullRequestsByRepo(prs, owner, repos)
+
+	comparedPullRequests := make(chan *pullRequest, 5)
 	go func() {
 		wg := sync.WaitGroup{}
 		for pr := range prs {
 			wg.Add(1)
 			go func(pr *pullRequest) {
 				pr.getCommitComparison()
-				if !pr.Rebased {
-					filteredPullRequests <- pr
-				}
+				comparedPullRequests <- pr
 				wg.Done()
 			}(pr)
 		}
 
 		wg.Wait()
+		close(comparedPullRequests)
+	}()
+
+	return comparedPullRequests, nil
+}
+
+func getValidPullRequests(profile *config.Profile, repos []string, useCache bool, errWriter io.Writer) (<-chan *pullRequest, error) {
+	prs, err := getPullRequestsByOwner(profile, "", repos, useCache, errWriter)
+	if err != nil {
+		return nil, err
+	}
+
+	filteredPullRequests := make(chan *pullRequest, 5)
+	go func() {
+		for pr := range prs {
+			if !pr.Rebased {
+				filteredPullRequests <- pr
+			}
+		}
+
 		close(filteredPullRequests)
 	}()
 
@@ -117,19 +195,19 @@ func handleCompletion(c *cli.Context) {
 	}
 
 	selectedRepos := c.StringSlice("repo")
-	completions := completeRepoValues(profile, prs, selectedRepos)
+	completions := completeRepoValues(profile, prs, selectedRepos, os.Args[len(os.Args)-2] == "--pull-request-number")
 
 	completions = unique(completions)
 	sort.Strings(completions)
 	fmt.Fprintln(c.App.Writer, strings.Join(completions, "\n"))
 }
 
-func completeRepoValues(profile config.Profile, prs <-chan *pullRequest, selectedRepos []string) []string {
-	lastParam := os.Args[len(os.Args)-2]
+// completeRepoValues lists the repos of prs that aren't selected yet, or the numbers of the ones in the selected repos if numbers is set
+func completeRepoValues(profile config.Profile, prs <-chan *pullRequest, selectedRepos []string, numbers bool) []string {
 	completions := make([]string, 0, len(profile.TrackedRepos))
 	for pr := range prs {
 		fullRepoName := fmt.Sprintf("%s/%s", pr.Repo.Owner, pr.Repo.Name)
-		if lastParam == "--pull-request-number" {
+		if numbers {
 			if len(selectedRepos) == 0 || stringSliceContains(fullRepoName, selectedRepos) {
 				completions = append(completions, strconv.Itoa(pr.PullRequestID))
 			}
@@ -2,15 +2,22 @@ package command_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/google/go-github/github"
 	"github.com/guywithnose/pull-request-parser/command"
+	"github.com/guywithnose/pull-request-parser/config"
 	"github.com/guywithnose/runner"
 	"github.com/stretchr/testify/assert"
 	"github.com/urfave/cli"
@@ -36,7 +43,8 @@ func TestCmdAutoRebase(t *testing.T) {
 				runner.NewExpectedCommand(repoDir, "git checkout -b prp-ref1", "", 0),
 				runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
 				runner.NewExpectedCommand(repoDir, "git rebase upstream/baseRef1", "", 0),
-				runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force", "", 0),
+				runner.NewExpectedCommand(repoDir, "git rev-parse origin/ref1 HEAD", "oldSHA\nnewSHA", 0),
+				runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force-with-lease=ref1:oldSHA", "", 0),
 				runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
 				runner.NewExpectedCommand(repoDir, "git branch -D prp-ref1", "", 0),
 			},
@@ -55,7 +63,8 @@ func TestCmdAutoRebase(t *testing.T) {
 				runner.NewExpectedCommand(repoDir, "git checkout -b prp-ref1", "", 0),
 				runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
 				runner.NewExpectedCommand(repoDir, "git rebase upstream/baseRef1", "", 0),
-				runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force", "", 0),
+				runner.NewExpectedCommand(repoDir, "git rev-parse origin/ref1 HEAD", "oldSHA\nnewSHA", 0),
+				runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force-with-lease=ref1:oldSHA", "", 0),
 				runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
 				runner.NewExpectedCommand(repoDir, "git branch -D prp-ref1", "", 0),
 			},
@@ -90,7 +99,8 @@ func TestCmdAutoRebase(t *testing.T) {
 				runner.NewExpectedCommand(repoDir, "git checkout -b prp-ref1", "", 0),
 				runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
 				runner.NewExpectedCommand(repoDir, "git rebase upstream/baseRef1", "", 0),
-				runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force", "", 0),
+				runner.NewExpectedCommand(repoDir, "git rev-parse origin/ref1 HEAD", "oldSHA\nnewSHA", 0),
+				runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force-with-lease=ref1:oldSHA", "", 0),
 				runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
 				runner.NewExpectedCommand(repoDir, "git branch -D prp-ref1", "", 0),
 				runner.NewExpectedCommand(repoDir, "git stash pop", "", 0),
@@ -187,7 +197,8 @@ func TestCmdAutoRebase(t *testing.T) {
 				runner.NewExpectedCommand(repoDir, "git checkout -b prp-ref1", "", 0),
 				runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
 				runner.NewExpectedCommand(repoDir, "git rebase upstream/baseRef1", "", 0),
-				runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force", "", 0),
+				runner.NewExpectedCommand(repoDir, "git rev-parse origin/ref1 HEAD", "oldSHA\nnewSHA", 0),
+				runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force-with-lease=ref1:oldSHA", "", 0),
 				runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "checkout failure", 1),
 				runner.NewExpectedCommand(repoDir, "git stash pop", "", 0),
 			},
@@ -226,7 +237,8 @@ func TestCmdAutoRebase(t *testing.T) {
 				runner.NewExpectedCommand(repoDir, "git checkout -b prp-ref1", "", 0),
 				runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
 				runner.NewExpectedCommand(repoDir, "git rebase upstream/baseRef1", "", 0),
-				runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force", "", 0),
+				runner.NewExpectedCommand(repoDir, "git rev-parse origin/ref1 HEAD", "oldSHA\nnewSHA", 0),
+				runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force-with-lease=ref1:oldSHA", "", 0),
 				runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
 				runner.NewExpectedCommand(repoDir, "git branch -D prp-ref1", "delete branch failure", 1),
 				runner.NewExpectedCommand(repoDir, "git stash pop", "", 0),
@@ -267,7 +279,8 @@ func TestCmdAutoRebase(t *testing.T) {
 				runner.NewExpectedCommand(repoDir, "git checkout -b prp-ref1", "", 0),
 				runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
 				runner.NewExpectedCommand(repoDir, "git rebase upstream/baseRef1", "", 0),
-				runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force", "", 0),
+				runner.NewExpectedCommand(repoDir, "git rev-parse origin/ref1 HEAD", "oldSHA\nnewSHA", 0),
+				runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force-with-lease=ref1:oldSHA", "", 0),
 				runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
 				runner.NewExpectedCommand(repoDir, "git branch -D prp-ref1", "", 0),
 				runner.NewExpectedCommand(repoDir, "git stash pop", "stash pop failure", 1),
@@ -308,7 +321,8 @@ func TestCmdAutoRebase(t *testing.T) {
 				runner.NewExpectedCommand(repoDir, "git checkout -b prp-ref1", "", 0),
 				runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
 				runner.NewExpectedCommand(repoDir, "git rebase upstream/baseRef1", "", 0),
-				runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force", "push failure", 1),
+				runner.NewExpectedCommand(repoDir, "git rev-parse origin/ref1 HEAD", "oldSHA\nnewSHA", 0),
+				runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force-with-lease=ref1:oldSHA", "push failure", 1),
 				runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
 				runner.NewExpectedCommand(repoDir, "git branch -D prp-ref1", "", 0),
 				runner.NewExpectedCommand(repoDir, "git stash pop", "", 0),
@@ -400,7 +414,8 @@ func TestCmdAutoRebase(t *testing.T) {
 				runner.NewExpectedCommand(repoDir, "git checkout -b prp-ref1", "", 0),
 				runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
 				runner.NewExpectedCommand(repoDir, "git rebase upstream/baseRef1", "", 0),
-				runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force", "", 0),
+				runner.NewExpectedCommand(repoDir, "git rev-parse origin/ref1 HEAD", "oldSHA\nnewSHA", 0),
+				runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force-with-lease=ref1:oldSHA", "", 0),
 				runner.NewExpectedCommand(repoDir, "git checkout abcdefg", "", 0),
 				runner.NewExpectedCommand(repoDir, "git branch -D prp-ref1", "", 0),
 				runner.NewExpectedCommand(repoDir, "git stash pop", "", 0),
@@ -563,6 +578,9 @@ func TestCmdAutoRebase(t *testing.T) {
 				runner.NewExpectedCommand(repoDir, "git checkout -b prp-ref1", "", 0),
 				runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
 				runner.NewExpectedCommand(repoDir, "git rebase upstream/baseRef1", "rebase failure", 1),
+				runner.NewExpectedCommand(repoDir, "git log -1 --format=%h %s REBASE_HEAD", "abc1234 Change things\n", 0),
+				runner.NewExpectedCommand(repoDir, "git diff --name-only --diff-filter=U", "file1\nfile2\n", 0),
+				runner.NewExpectedCommand(repoDir, "git diff --shortstat", " 2 files changed, 3 insertions(+)\n", 0),
 				runner.NewExpectedCommand(repoDir, "git rebase --abort", "", 0),
 				runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
 				runner.NewExpectedCommand(repoDir, "git branch -D prp-ref1", "", 0),
@@ -585,6 +603,11 @@ func TestCmdAutoRebase(t *testing.T) {
 				"Popping the stash",
 				"Could not rebase PR #1 in own/rep because: Unable to rebase against upstream/baseRef1, there may be a conflict",
 				"rebase failure",
+				"Conflict while applying abc1234 Change things",
+				"Conflicted files:",
+				"  file1",
+				"  file2",
+				"2 files changed, 3 insertions(+)",
 				"",
 			},
 			true,
@@ -602,6 +625,9 @@ func TestCmdAutoRebase(t *testing.T) {
 				runner.NewExpectedCommand(repoDir, "git checkout -b prp-ref1", "", 0),
 				runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
 				runner.NewExpectedCommand(repoDir, "git rebase upstream/baseRef1", "rebase failure", 1),
+				runner.NewExpectedCommand(repoDir, "git log -1 --format=%h %s REBASE_HEAD", "", 128),
+				runner.NewExpectedCommand(repoDir, "git diff --name-only --diff-filter=U", "", 128),
+				runner.NewExpectedCommand(repoDir, "git diff --shortstat", "", 128),
 				runner.NewExpectedCommand(repoDir, "git rebase --abort", "", 1),
 				runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
 				runner.NewExpectedCommand(repoDir, "git branch -D prp-ref1", "", 0),
@@ -681,6 +707,2003 @@ func TestCmdAutoRebase(t *testing.T) {
 	}
 }
 
+func TestCmdAutoRebaseMergeStrategy(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	var testCases = []struct {
+		name             string
+		expectedCommands []*runner.ExpectedCommand
+		output           []string
+		expectedError    bool
+	}{
+		{
+			"Normal",
+			[]*runner.ExpectedCommand{
+				runner.NewExpectedCommand(repoDir, "git remote -v", "origin\tlabelSSHURL (push)\nupstream\tbaseLabel1SSHURL (fetch)", 0),
+				runner.NewExpectedCommand(repoDir, "git diff-index --quiet HEAD", "", 0),
+				runner.NewExpectedCommand(repoDir, "git fetch origin", "", 0),
+				runner.NewExpectedCommand(repoDir, "git fetch upstream", "", 0),
+				runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "currentBranch", 0),
+				runner.NewExpectedCommand(repoDir, "git checkout -b prp-ref1", "", 0),
+				runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
+				runner.NewExpectedCommand(repoDir, "git merge --no-edit upstream/baseRef1", "", 0),
+				runner.NewExpectedCommand(repoDir, "git rev-parse origin/ref1 HEAD", "oldSHA\nnewSHA", 0),
+				runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1", "", 0),
+				runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
+				runner.NewExpectedCommand(repoDir, "git branch -D prp-ref1", "", 0),
+			},
+			[]string{
+				"Requesting repo data from config",
+				"Analyzing remotes",
+				"Checking for local changes",
+				"Fetching from remote: origin",
+				"Fetching from remote: upstream",
+				"Saving current branch name",
+				"Current branch name is currentBranch",
+				"Checking out temporary branch: prp-ref1",
+				"Resetting code to origin/ref1",
+				"Merging upstream/baseRef1",
+				"Pushing to origin/ref1",
+				"Going back to branch currentBranch",
+				"Deleting temporary branch prp-ref1",
+				"",
+			},
+			false,
+		},
+		{
+			"MergeFailure",
+			[]*runner.ExpectedCommand{
+				runner.NewExpectedCommand(repoDir, "git remote -v", "origin\tlabelSSHURL (push)\nupstream\tbaseLabel1SSHURL (fetch)", 0),
+				runner.NewExpectedCommand(repoDir, "git diff-index --quiet HEAD", "", 0),
+				runner.NewExpectedCommand(repoDir, "git fetch origin", "", 0),
+				runner.NewExpectedCommand(repoDir, "git fetch upstream", "", 0),
+				runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "currentBranch", 0),
+				runner.NewExpectedCommand(repoDir, "git checkout -b prp-ref1", "", 0),
+				runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
+				runner.NewExpectedCommand(repoDir, "git merge --no-edit upstream/baseRef1", "merge failure", 1),
+				runner.NewExpectedCommand(repoDir, "git merge --abort", "", 1),
+				runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
+				runner.NewExpectedCommand(repoDir, "git branch -D prp-ref1", "", 0),
+			},
+			[]string{
+				"Requesting repo data from config",
+				"Analyzing remotes",
+				"Checking for local changes",
+				"Fetching from remote: origin",
+				"Fetching from remote: upstream",
+				"Saving current branch name",
+				"Current branch name is currentBranch",
+				"Checking out temporary branch: prp-ref1",
+				"Resetting code to origin/ref1",
+				"Merging upstream/baseRef1",
+				"Could not abort merge PR #1 in own/rep because: exit status 1",
+				"Going back to branch currentBranch",
+				"Deleting temporary branch prp-ref1",
+				"Could not rebase PR #1 in own/rep because: Unable to merge upstream/baseRef1, there may be a conflict",
+				"merge failure",
+				"",
+			},
+			true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := &runner.Test{ExpectedCommands: tc.expectedCommands}
+			writer := runBaseCommandWithRepo(t, repoDir, runner, true, tc.expectedError, func(repo *config.Repo) {
+				repo.UpdateStrategy = "merge"
+			})
+			assert.Equal(t, tc.output, strings.Split(writer.String(), "\n"))
+			removeFile(t, repoDir)
+		})
+	}
+}
+
+func TestCmdAutoRebaseAPIStrategy(t *testing.T) {
+	ts := getAutoRebaseTestServer("")
+	defer ts.Close()
+	conf, configFileName := getConfigWithAPIURL(t, ts.URL)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	conf.Profiles["foo"].TrackedRepos[1].UpdateStrategy = "api"
+	assert.Nil(t, conf.Write(configFileName))
+	set := getBaseFlagSet(configFileName)
+	set.Bool("verbose", true, "doc")
+	app, _, writer := appWithTestWriters()
+	cb := &runner.Test{}
+	assert.Nil(t, command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil)))
+	assert.Equal(t, []error(nil), cb.Errors)
+	assert.Equal(t, "Requesting a branch update from the API\n", writer.String())
+}
+
+func TestCmdAutoRebaseAPIStrategyFailure(t *testing.T) {
+	ts := getAutoRebaseTestServer("/repos/own/rep/pulls/1/update-branch")
+	defer ts.Close()
+	conf, configFileName := getConfigWithAPIURL(t, ts.URL)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	conf.Profiles["foo"].TrackedRepos[1].UpdateStrategy = "api"
+	assert.Nil(t, conf.Write(configFileName))
+	set := getBaseFlagSet(configFileName)
+	app, _, writer := appWithTestWriters()
+	cb := &runner.Test{}
+	err := command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Unable to rebase all pull requests")
+	assert.Equal(
+		t,
+		fmt.Sprintf(
+			"Could not rebase PR #1 in own/rep because: Unable to update branch through the API\nPUT %s/repos/own/rep/pulls/1/update-branch: 500  []\n",
+			ts.URL,
+		),
+		writer.String(),
+	)
+}
+
+func TestCmdAutoRebaseStackedPullRequests(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	remotes := "origin\town:feature-aSSHURL (push)\norigin\town:feature-bSSHURL (push)\nupstream\town:masterSSHURL (fetch)\nupstream\town:feature-aSSHURL (fetch)"
+	var testCases = []struct {
+		name             string
+		expectedCommands []*runner.ExpectedCommand
+		output           []string
+		expectedError    bool
+	}{
+		{
+			"Normal",
+			[]*runner.ExpectedCommand{
+				runner.NewExpectedCommand(repoDir, "git remote -v", remotes, 0),
+				runner.NewExpectedCommand(repoDir, "git diff-index --quiet HEAD", "", 0),
+				runner.NewExpectedCommand(repoDir, "git fetch origin", "", 0),
+				runner.NewExpectedCommand(repoDir, "git fetch upstream", "", 0),
+				runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "currentBranch", 0),
+				runner.NewExpectedCommand(repoDir, "git checkout -b prp-feature-a", "", 0),
+				runner.NewExpectedCommand(repoDir, "git reset --hard origin/feature-a", "", 0),
+				runner.NewExpectedCommand(repoDir, "git rebase upstream/master", "", 0),
+				runner.NewExpectedCommand(repoDir, "git rev-parse origin/feature-a HEAD", "oldSHA\nnewSHA", 0),
+				runner.NewExpectedCommand(repoDir, "git push origin prp-feature-a:feature-a --force-with-lease=feature-a:oldSHA", "", 0),
+				runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
+				runner.NewExpectedCommand(repoDir, "git branch -D prp-feature-a", "", 0),
+				runner.NewExpectedCommand(repoDir, "git remote -v", remotes, 0),
+				runner.NewExpectedCommand(repoDir, "git diff-index --quiet HEAD", "", 0),
+				runner.NewExpectedCommand(repoDir, "git fetch origin", "", 0),
+				runner.NewExpectedCommand(repoDir, "git fetch upstream", "", 0),
+				runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "currentBranch", 0),
+				runner.NewExpectedCommand(repoDir, "git checkout -b prp-feature-b", "", 0),
+				runner.NewExpectedCommand(repoDir, "git reset --hard origin/feature-b", "", 0),
+				runner.NewExpectedCommand(repoDir, "git rebase --onto upstream/feature-a shaA", "", 0),
+				runner.NewExpectedCommand(repoDir, "git rev-parse origin/feature-b HEAD", "oldSHA\nnewSHA", 0),
+				runner.NewExpectedCommand(repoDir, "git push origin prp-feature-b:feature-b --force-with-lease=feature-b:oldSHA", "", 0),
+				runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
+				runner.NewExpectedCommand(repoDir, "git branch -D prp-feature-b", "", 0),
+			},
+			[]string{
+				"Requesting repo data from config",
+				"Analyzing remotes",
+				"Checking for local changes",
+				"Fetching from remote: origin",
+				"Fetching from remote: upstream",
+				"Saving current branch name",
+				"Current branch name is currentBranch",
+				"Checking out temporary branch: prp-feature-a",
+				"Resetting code to origin/feature-a",
+				"Rebasing against upstream/master",
+				"Pushing to origin/feature-a",
+				"Going back to branch currentBranch",
+				"Deleting temporary branch prp-feature-a",
+				"Parent PR #3 was rebased, replaying PR #4 onto it",
+				"Requesting repo data from config",
+				"Analyzing remotes",
+				"Checking for local changes",
+				"Fetching from remote: origin",
+				"Fetching from remote: upstream",
+				"Saving current branch name",
+				"Current branch name is currentBranch",
+				"Checking out temporary branch: prp-feature-b",
+				"Resetting code to origin/feature-b",
+				"Rebasing commits after shaA onto upstream/feature-a",
+				"Pushing to origin/feature-b",
+				"Going back to branch currentBranch",
+				"Deleting temporary branch prp-feature-b",
+				"",
+			},
+			false,
+		},
+		{
+			"ParentFailure",
+			[]*runner.ExpectedCommand{
+				runner.NewExpectedCommand(repoDir, "git remote -v", remotes, 0),
+				runner.NewExpectedCommand(repoDir, "git diff-index --quiet HEAD", "", 0),
+				runner.NewExpectedCommand(repoDir, "git fetch origin", "", 0),
+				runner.NewExpectedCommand(repoDir, "git fetch upstream", "", 0),
+				runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "currentBranch", 0),
+				runner.NewExpectedCommand(repoDir, "git checkout -b prp-feature-a", "", 0),
+				runner.NewExpectedCommand(repoDir, "git reset --hard origin/feature-a", "", 0),
+				runner.NewExpectedCommand(repoDir, "git rebase upstream/master", "rebase failure", 1),
+				runner.NewExpectedCommand(repoDir, "git log -1 --format=%h %s REBASE_HEAD", "", 0),
+				runner.NewExpectedCommand(repoDir, "git diff --name-only --diff-filter=U", "", 0),
+				runner.NewExpectedCommand(repoDir, "git diff --shortstat", "", 0),
+				runner.NewExpectedCommand(repoDir, "git rebase --abort", "", 0),
+				runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
+				runner.NewExpectedCommand(repoDir, "git branch -D prp-feature-a", "", 0),
+			},
+			[]string{
+				"Requesting repo data from config",
+				"Analyzing remotes",
+				"Checking for local changes",
+				"Fetching from remote: origin",
+				"Fetching from remote: upstream",
+				"Saving current branch name",
+				"Current branch name is currentBranch",
+				"Checking out temporary branch: prp-feature-a",
+				"Resetting code to origin/feature-a",
+				"Rebasing against upstream/master",
+				"Going back to branch currentBranch",
+				"Deleting temporary branch prp-feature-a",
+				"Could not rebase PR #3 in own/rep because: Unable to rebase against upstream/master, there may be a conflict",
+				"rebase failure",
+				"Could not rebase PR #4 in own/rep because: Its parent PR #3 could not be rebased",
+				"",
+			},
+			true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := getStackTestServer(3, 4)
+			defer ts.Close()
+			cb := &runner.Test{ExpectedCommands: tc.expectedCommands}
+			writer := runCommandAgainstServer(t, ts, repoDir, cb, tc.expectedError)
+			assert.Equal(t, tc.output, strings.Split(writer.String(), "\n"))
+		})
+	}
+}
+
+func TestCmdAutoRebaseRetargetMergedParent(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	ts := getStackTestServer(5)
+	defer ts.Close()
+	cb := &runner.Test{
+		ExpectedCommands: []*runner.ExpectedCommand{
+			runner.NewExpectedCommand(repoDir, "git remote -v", "origin\town:feature-cSSHURL (push)\nupstream\town:feature-oldSSHURL (fetch)", 0),
+			runner.NewExpectedCommand(repoDir, "git diff-index --quiet HEAD", "", 0),
+			runner.NewExpectedCommand(repoDir, "git fetch origin", "", 0),
+			runner.NewExpectedCommand(repoDir, "git fetch upstream", "", 0),
+			runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "currentBranch", 0),
+			runner.NewExpectedCommand(repoDir, "git checkout -b prp-feature-c", "", 0),
+			runner.NewExpectedCommand(repoDir, "git reset --hard origin/feature-c", "", 0),
+			runner.NewExpectedCommand(repoDir, "git rebase --onto upstream/master shaOld", "", 0),
+			runner.NewExpectedCommand(repoDir, "git rev-parse origin/feature-c HEAD", "oldSHA\nnewSHA", 0),
+			runner.NewExpectedCommand(repoDir, "git push origin prp-feature-c:feature-c --force-with-lease=feature-c:oldSHA", "", 0),
+			runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
+			runner.NewExpectedCommand(repoDir, "git branch -D prp-feature-c", "", 0),
+		},
+	}
+	writer := runCommandAgainstServerWithStacks(t, ts, repoDir, cb, false, `{"own/rep#5": "feature-old"}`)
+	assert.Equal(
+		t,
+		[]string{
+			"Parent PR #9 was merged, retargeting PR #5 to master",
+			"Requesting repo data from config",
+			"Analyzing remotes",
+			"Checking for local changes",
+			"Fetching from remote: origin",
+			"Fetching from remote: upstream",
+			"Saving current branch name",
+			"Current branch name is currentBranch",
+			"Checking out temporary branch: prp-feature-c",
+			"Resetting code to origin/feature-c",
+			"Rebasing commits after shaOld onto upstream/master",
+			"Pushing to origin/feature-c",
+			"Going back to branch currentBranch",
+			"Deleting temporary branch prp-feature-c",
+			"",
+		},
+		strings.Split(writer.String(), "\n"),
+	)
+}
+
+// Without a local clone the API would replay the merged parent's commits too
+func TestCmdAutoRebaseRetargetMergedParentWithoutPath(t *testing.T) {
+	inner := getStackTestServer(5)
+	defer inner.Close()
+	closedURL := "/repos/own/rep/pulls?head=own%3Afeature-old&per_page=100&state=closed"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.String() {
+		case closedURL:
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/own/rep/pulls?head=own%%3Afeature-old&page=2&per_page=100&state=closed>; rel="next"`, inner.URL))
+			fmt.Fprint(w, `[{"number":2,"state":"closed"}]`)
+		case "/repos/own/rep/pulls?head=own%3Afeature-old&page=2&per_page=100&state=closed":
+			r.URL, _ = url.Parse(closedURL)
+			inner.Config.Handler.ServeHTTP(w, r)
+		default:
+			inner.Config.Handler.ServeHTTP(w, r)
+		}
+	}))
+	defer ts.Close()
+	cb := &runner.Test{ExpectedCommands: []*runner.ExpectedCommand{}}
+	writer := runCommandAgainstServerWithStacks(t, ts, "", cb, true, `{"own/rep#5": "feature-old"}`)
+	assert.Equal(
+		t,
+		"Could not rebase PR #5 in own/rep because: Its parent PR #9 was merged, moving only its own commits onto master needs a local path\n",
+		writer.String(),
+	)
+}
+
+func TestCmdAutoRebaseMergedParentNotStacked(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	ts := getStackTestServer(5)
+	defer ts.Close()
+	cb := &runner.Test{ExpectedCommands: []*runner.ExpectedCommand{}}
+	writer := runCommandAgainstServer(t, ts, repoDir, cb, true)
+	assert.Equal(
+		t,
+		"Could not rebase PR #5 in own/rep because: Its target branch feature-old was merged in PR #9, run 'prp retarget' to move it onto master\n",
+		writer.String(),
+	)
+}
+
+// A git-flow pull request into develop must not be moved onto master just because develop was merged into it before
+func TestCmdAutoRebaseLongLivedTargetMergedEarlier(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	ts := getStackTestServer(10)
+	defer ts.Close()
+	cb := &runner.Test{ExpectedCommands: []*runner.ExpectedCommand{}}
+	writer := runCommandAgainstServerWithStacks(t, ts, repoDir, cb, false, `{"own/rep#10": "develop"}`)
+	assert.Equal(t, "", writer.String())
+}
+
+// Only a deleted target blocks auto-rebase, feature-landed being behind master without commits of its own
+// is how a git-flow branch looks most of the time, so PR #7 is rebased like any other
+func TestCmdAutoRebaseDeadTarget(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	ts := getStackTestServer(6, 7, 8, 12)
+	defer ts.Close()
+	cb := &runner.Test{ExpectedCommands: []*runner.ExpectedCommand{runner.NewExpectedCommand(repoDir, "git remote -v", "", 0)}}
+	writer := runCommandAgainstServer(t, ts, repoDir, cb, true)
+	assert.Equal(
+		t,
+		[]string{
+			"Could not rebase PR #6 in own/rep because: Its target branch feature-gone was deleted, run 'prp retarget' to move it onto master",
+			"Requesting repo data from config",
+			"Analyzing remotes",
+			fmt.Sprintf("Could not rebase PR #7 in own/rep because: No remote exists in %s that points to own:feature-eSSHURL", repoDir),
+			"",
+		},
+		strings.Split(writer.String(), "\n"),
+	)
+}
+
+// newMergedComparison compares the default branch to a branch that was merged into it at sha
+func newMergedComparison(sha string) *github.CommitsComparison {
+	comparison := newCommitsComparison(0)
+	behindBy := 1
+	comparison.BehindBy = &behindBy
+	comparison.MergeBaseCommit = &github.RepositoryCommit{SHA: &sha}
+	return comparison
+}
+
+func getStackTestServer(pullRequestNumbers ...int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.String() == "/repos/own/rep/compare/master...feature-gone" || r.URL.String() == "/repos/own/rep/branches/feature-gone" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Branch not found"}`)
+			return
+		}
+
+		response := handleUserRequest(r, "guy")
+		if response == nil {
+			response = handleStackRequests(r, pullRequestNumbers)
+		}
+
+		if response == nil {
+			panic(r.URL.String())
+		}
+
+		fmt.Fprint(w, *response)
+	}))
+}
+
+func handleStackRequests(r *http.Request, pullRequestNumbers []int) *string {
+	responses := make(map[string]interface{})
+	master := "master"
+	prs := []*github.PullRequest{
+		newPullRequest(3, "Feature A", "guy", "own:feature-a", "feature-a", "shaA", "own:master", "master"),
+		newPullRequest(4, "Feature B", "guy", "own:feature-b", "feature-b", "shaB", "own:feature-a", "feature-a"),
+		newPullRequest(5, "Feature C", "guy", "own:feature-c", "feature-c", "shaC", "own:feature-old", "feature-old"),
+		newPullRequest(6, "Feature D", "guy", "own:feature-d", "feature-d", "shaD", "own:feature-gone", "feature-gone"),
+		newPullRequest(7, "Feature E", "guy", "own:feature-e", "feature-e", "shaE", "own:feature-landed", "feature-landed"),
+		newPullRequest(8, "Feature F", "guy", "own:feature-f", "feature-f", "shaF", "own:feature-alive", "feature-alive"),
+		newPullRequest(10, "Feature G", "guy", "own:feature-g", "feature-g", "shaG", "own:develop", "develop"),
+		newPullRequest(12, "Feature H", "guy", "own:feature-h", "feature-h", "shaH", "own:release", "release"),
+	}
+	goneSHA := "shaGone"
+	prs[3].Base.SHA = &goneSHA
+	listedPullRequests := []*github.PullRequest{}
+	for _, pr := range prs {
+		pr.Base.Repo.DefaultBranch = &master
+		for _, number := range pullRequestNumbers {
+			if pr.GetNumber() == number {
+				listedPullRequests = append(listedPullRequests, pr)
+			}
+		}
+	}
+
+	responses["/repos/own/rep/pulls?per_page=100"] = listedPullRequests
+	responses["/repos/foo/bar/pulls?per_page=100"] = []*github.PullRequest{}
+	responses["/repos/own/rep/compare/own:feature-a...own:master"] = newCommitsComparison(1)
+	responses["/repos/own/rep/compare/own:feature-b...own:feature-a"] = newCommitsComparison(0)
+	responses["/repos/own/rep/compare/own:feature-c...own:feature-old"] = newCommitsComparison(0)
+	mergedAt := time.Now()
+	mergedParent := newPullRequest(9, "Old Feature", "guy", "own:feature-old", "feature-old", "shaOld", "own:master", "master")
+	mergedParent.MergedAt = &mergedAt
+	responses["/repos/own/rep/pulls?head=own%3Afeature-old&per_page=100&state=closed"] = []*github.PullRequest{mergedParent}
+	oldSHA := "shaOld"
+	responses["/repos/own/rep/branches/feature-old"] = &github.Branch{Commit: &github.RepositoryCommit{SHA: &oldSHA}}
+	responses["/repos/own/rep/compare/master...feature-old"] = newMergedComparison(oldSHA)
+	responses["/repos/own/rep/pulls/5"] = prs[2]
+	responses["/repos/own/rep/compare/own:feature-d...own:feature-gone"] = newCommitsComparison(1)
+	responses["/repos/own/rep/pulls?head=own%3Afeature-gone&per_page=100&state=closed"] = []*github.PullRequest{}
+	responses["/repos/own/rep/pulls/6"] = prs[3]
+	landedSHA := "shaLanded"
+	responses["/repos/own/rep/compare/own:feature-e...own:feature-landed"] = newCommitsComparison(1)
+	responses["/repos/own/rep/pulls?head=own%3Afeature-landed&per_page=100&state=closed"] = []*github.PullRequest{}
+	responses["/repos/own/rep/compare/master...feature-landed"] = newMergedComparison(landedSHA)
+	responses["/repos/own/rep/branches/feature-landed"] = &github.Branch{Commit: &github.RepositoryCommit{SHA: &landedSHA}}
+	responses["/repos/own/rep/pulls/7"] = prs[4]
+	responses["/repos/own/rep/compare/own:feature-f...own:feature-alive"] = newCommitsComparison(0)
+	responses["/repos/own/rep/pulls?head=own%3Afeature-alive&per_page=100&state=closed"] = []*github.PullRequest{}
+	responses["/repos/own/rep/compare/master...feature-alive"] = newCommitsComparison(2)
+	aliveSHA := "shaAlive"
+	responses["/repos/own/rep/branches/feature-alive"] = &github.Branch{Commit: &github.RepositoryCommit{SHA: &aliveSHA}}
+	developSHA := "shaDevelop"
+	mergedDevelop := newPullRequest(11, "Release", "guy", "own:develop", "develop", "shaDevelopOld", "own:master", "master")
+	mergedDevelop.MergedAt = &mergedAt
+	responses["/repos/own/rep/compare/own:feature-g...own:develop"] = newCommitsComparison(0)
+	responses["/repos/own/rep/pulls?head=own%3Adevelop&per_page=100&state=closed"] = []*github.PullRequest{mergedDevelop}
+	responses["/repos/own/rep/branches/develop"] = &github.Branch{Commit: &github.RepositoryCommit{SHA: &developSHA}}
+	responses["/repos/own/rep/compare/master...develop"] = newCommitsComparison(2)
+	// release was just cut from master so it is even with it
+	responses["/repos/own/rep/compare/own:feature-h...own:release"] = newCommitsComparison(0)
+	responses["/repos/own/rep/pulls?head=own%3Arelease&per_page=100&state=closed"] = []*github.PullRequest{}
+	responses["/repos/own/rep/compare/master...release"] = newCommitsComparison(0)
+	releaseSHA := "shaRelease"
+	responses["/repos/own/rep/branches/release"] = &github.Branch{Commit: &github.RepositoryCommit{SHA: &releaseSHA}}
+
+	data, ok := responses[r.URL.String()]
+	if !ok {
+		return nil
+	}
+
+	bytes, _ := json.Marshal(data)
+	response := string(bytes)
+	return &response
+}
+
+func runCommandAgainstServer(t *testing.T, ts *httptest.Server, repoDir string, cb *runner.Test, expectedError bool) *bytes.Buffer {
+	t.Helper()
+	return runCommandAgainstServerWithStacks(t, ts, repoDir, cb, expectedError, "")
+}
+
+// runCommandAgainstServerWithStacks runs auto-rebase with stacks as the pull requests it remembers seeing stacked
+func runCommandAgainstServerWithStacks(t *testing.T, ts *httptest.Server, repoDir string, cb *runner.Test, expectedError bool, stacks string) *bytes.Buffer {
+	t.Helper()
+	if repoDir != "" {
+		assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/.git", repoDir), 0777))
+		defer removeFile(t, repoDir)
+	}
+
+	_, configFileName := getConfigWithAPIURLAndPath(t, ts.URL, repoDir)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	defer removeFile(t, fmt.Sprintf("%s.stacks", configFileName))
+	if stacks != "" {
+		assert.Nil(t, ioutil.WriteFile(fmt.Sprintf("%s.stacks", configFileName), []byte(stacks), 0644))
+	}
+
+	set := getBaseFlagSet(configFileName)
+	set.Bool("verbose", true, "doc")
+	app, _, writer := appWithTestWriters()
+	err := command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil))
+	if expectedError {
+		assert.EqualError(t, err, "Unable to rebase all pull requests")
+	} else {
+		assert.Nil(t, err)
+	}
+
+	assert.Equal(t, []*runner.ExpectedCommand{}, cb.ExpectedCommands)
+	assert.Equal(t, []error(nil), cb.Errors)
+	return writer
+}
+
+func TestCmdAutoRebaseLeaveConflicts(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	worktree := fmt.Sprintf("%s-prp-1", repoDir)
+	assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/.git", repoDir), 0777))
+	defer removeFile(t, repoDir)
+	ts := getAutoRebaseTestServer("")
+	defer ts.Close()
+	_, configFileName := getConfigWithAPIURLAndPath(t, ts.URL, repoDir)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	set := getBaseFlagSet(configFileName)
+	set.Bool("leave-conflicts", true, "doc")
+	app, _, writer := appWithTestWriters()
+	cb := &runner.Test{
+		ExpectedCommands: []*runner.ExpectedCommand{
+			runner.NewExpectedCommand(repoDir, "git remote -v", "origin\tlabelSSHURL (push)\nupstream\tbaseLabel1SSHURL (fetch)", 0),
+			runner.NewExpectedCommand(repoDir, "git diff-index --quiet HEAD", "", 0),
+			runner.NewExpectedCommand(repoDir, "git fetch origin", "", 0),
+			runner.NewExpectedCommand(repoDir, "git fetch upstream", "", 0),
+			runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "currentBranch", 0),
+			runner.NewExpectedCommand(repoDir, "git checkout -b prp-ref1", "", 0),
+			runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
+			runner.NewExpectedCommand(repoDir, "git rebase upstream/baseRef1", "rebase failure", 1),
+			runner.NewExpectedCommand(repoDir, "git log -1 --format=%h %s REBASE_HEAD", "", 0),
+			runner.NewExpectedCommand(repoDir, "git diff --name-only --diff-filter=U", "file1", 0),
+			runner.NewExpectedCommand(repoDir, "git diff --shortstat", "", 0),
+			runner.NewExpectedCommand(repoDir, "git rebase --abort", "", 0),
+			runner.NewExpectedCommand(repoDir, "git rev-parse origin/ref1", "fetchedSHA", 0),
+			runner.NewExpectedCommand(repoDir, fmt.Sprintf("git worktree add --detach %s fetchedSHA", worktree), "", 0),
+			runner.NewExpectedCommand(worktree, "git rebase upstream/baseRef1", "rebase failure", 1),
+			runner.NewExpectedCommand(repoDir, "git rev-parse --git-dir", ".git", 0),
+			runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
+			runner.NewExpectedCommand(repoDir, "git branch -D prp-ref1", "", 0),
+		},
+	}
+	err := command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Unable to rebase all pull requests")
+	assert.Equal(t, []*runner.ExpectedCommand{}, cb.ExpectedCommands)
+	assert.Equal(t, []error(nil), cb.Errors)
+	assert.Equal(
+		t,
+		[]string{
+			"Could not rebase PR #1 in own/rep because: Unable to rebase against upstream/baseRef1, there may be a conflict",
+			"rebase failure",
+			"Conflicted files:",
+			"  file1",
+			fmt.Sprintf("Resolve the conflicts in %s and then run 'prp auto-rebase --continue'", worktree),
+			"",
+		},
+		strings.Split(writer.String(), "\n"),
+	)
+
+	conflicts, err := ioutil.ReadFile(fmt.Sprintf("%s/.git/prp-conflicts.json", repoDir))
+	assert.Nil(t, err)
+	assert.Equal(t, getConflictsJSON(worktree), string(conflicts))
+}
+
+func TestCmdAutoRebaseContinue(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	worktree := fmt.Sprintf("%s-prp-1", repoDir)
+	_, configFileName := writeConflictsAndConfig(t, repoDir, worktree)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	defer removeFile(t, repoDir)
+	set := getBaseFlagSet(configFileName)
+	set.Bool("continue", true, "doc")
+	set.Bool("verbose", true, "doc")
+	app, _, writer := appWithTestWriters()
+	cb := &runner.Test{
+		ExpectedCommands: []*runner.ExpectedCommand{
+			runner.NewExpectedCommand(repoDir, "git rev-parse --git-dir", ".git", 0),
+			runner.NewExpectedCommand(worktree, "git -c core.editor=true rebase --continue", "", 0),
+			runner.NewExpectedCommand(worktree, "git rev-parse origin/ref1 HEAD", "oldSHA\nnewSHA", 0),
+			runner.NewExpectedCommand(worktree, "git push origin HEAD:ref1 --force-with-lease=ref1:fetchedSHA", "", 0),
+			runner.NewExpectedCommand(repoDir, fmt.Sprintf("git worktree remove --force %s", worktree), "", 0),
+		},
+	}
+	assert.Nil(t, command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil)))
+	assert.Equal(t, []*runner.ExpectedCommand{}, cb.ExpectedCommands)
+	assert.Equal(t, []error(nil), cb.Errors)
+	assert.Equal(
+		t,
+		[]string{
+			fmt.Sprintf("Continuing rebase in %s", worktree),
+			"Pushing to origin/ref1",
+			fmt.Sprintf("Removing worktree %s", worktree),
+			"",
+		},
+		strings.Split(writer.String(), "\n"),
+	)
+
+	_, err := os.Stat(fmt.Sprintf("%s/.git/prp-conflicts.json", repoDir))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCmdAutoRebaseContinueStillConflicted(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	worktree := fmt.Sprintf("%s-prp-1", repoDir)
+	_, configFileName := writeConflictsAndConfig(t, repoDir, worktree)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	defer removeFile(t, repoDir)
+	set := getBaseFlagSet(configFileName)
+	set.Bool("continue", true, "doc")
+	app, _, writer := appWithTestWriters()
+	cb := &runner.Test{
+		ExpectedCommands: []*runner.ExpectedCommand{
+			runner.NewExpectedCommand(repoDir, "git rev-parse --git-dir", ".git", 0),
+			runner.NewExpectedCommand(worktree, "git -c core.editor=true rebase --continue", "needs merge", 1),
+		},
+	}
+	err := command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Unable to continue all rebases")
+	assert.Equal(t, []*runner.ExpectedCommand{}, cb.ExpectedCommands)
+	assert.Equal(t, []error(nil), cb.Errors)
+	assert.Equal(
+		t,
+		fmt.Sprintf("Could not continue rebase of PR #1 in own/rep because: Unable to continue the rebase in %s, conflicts may remain\nneeds merge\n", worktree),
+		writer.String(),
+	)
+
+	conflicts, err := ioutil.ReadFile(fmt.Sprintf("%s/.git/prp-conflicts.json", repoDir))
+	assert.Nil(t, err)
+	assert.Equal(t, getConflictsJSON(worktree), string(conflicts))
+}
+
+func TestCmdAutoRebaseContinueOtherPullRequest(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	worktree := fmt.Sprintf("%s-prp-1", repoDir)
+	_, configFileName := writeConflictsAndConfig(t, repoDir, worktree)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, repoDir)
+	set := getBaseFlagSet(configFileName)
+	set.Bool("continue", true, "doc")
+	set.Int("pull-request-number", 2, "doc")
+	app, _, _ := appWithTestWriters()
+	cb := &runner.Test{ExpectedCommands: []*runner.ExpectedCommand{
+		runner.NewExpectedCommand(repoDir, "git rev-parse --git-dir", ".git", 0),
+	}}
+	assert.Nil(t, command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil)))
+	assert.Equal(t, []*runner.ExpectedCommand{}, cb.ExpectedCommands)
+	assert.Equal(t, []error(nil), cb.Errors)
+
+	conflicts, err := ioutil.ReadFile(fmt.Sprintf("%s/.git/prp-conflicts.json", repoDir))
+	assert.Nil(t, err)
+	assert.Equal(t, getConflictsJSON(worktree), string(conflicts))
+}
+
+func TestCmdAutoRebaseContinueSeparateGitDir(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	gitDir := fmt.Sprintf("%s/repo.git", os.TempDir())
+	worktree := fmt.Sprintf("%s-prp-1", repoDir)
+	assert.Nil(t, os.MkdirAll(repoDir, 0777))
+	defer removeFile(t, repoDir)
+	assert.Nil(t, os.MkdirAll(gitDir, 0777))
+	defer removeFile(t, gitDir)
+	assert.Nil(t, ioutil.WriteFile(fmt.Sprintf("%s/prp-conflicts.json", gitDir), []byte(getConflictsJSON(worktree)), 0644))
+	_, configFileName := getConfigWithAPIURLAndPath(t, "", repoDir)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	set := getBaseFlagSet(configFileName)
+	set.Bool("continue", true, "doc")
+	app, _, writer := appWithTestWriters()
+	cb := &runner.Test{
+		ExpectedCommands: []*runner.ExpectedCommand{
+			runner.NewExpectedCommand(repoDir, "git rev-parse --git-dir", gitDir, 0),
+			runner.NewExpectedCommand(worktree, "git -c core.editor=true rebase --continue", "", 0),
+			runner.NewExpectedCommand(worktree, "git rev-parse origin/ref1 HEAD", "oldSHA\nnewSHA", 0),
+			runner.NewExpectedCommand(worktree, "git push origin HEAD:ref1 --force-with-lease=ref1:fetchedSHA", "", 0),
+			runner.NewExpectedCommand(repoDir, fmt.Sprintf("git worktree remove --force %s", worktree), "", 0),
+		},
+	}
+	assert.Nil(t, command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil)))
+	assert.Equal(t, []*runner.ExpectedCommand{}, cb.ExpectedCommands)
+	assert.Equal(t, []error(nil), cb.Errors)
+	assert.Equal(t, "", writer.String())
+
+	_, err := os.Stat(fmt.Sprintf("%s/prp-conflicts.json", gitDir))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCmdAutoRebaseLeaveConflictsOnto(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	worktree := fmt.Sprintf("%s-prp-5", repoDir)
+	assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/.git", repoDir), 0777))
+	defer removeFile(t, repoDir)
+	ts := getStackTestServer(5)
+	defer ts.Close()
+	_, configFileName := getConfigWithAPIURLAndPath(t, ts.URL, repoDir)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	defer removeFile(t, fmt.Sprintf("%s.stacks", configFileName))
+	assert.Nil(t, ioutil.WriteFile(fmt.Sprintf("%s.stacks", configFileName), []byte(`{"own/rep#5": "feature-old"}`), 0644))
+	set := getBaseFlagSet(configFileName)
+	set.Bool("leave-conflicts", true, "doc")
+	app, _, _ := appWithTestWriters()
+	cb := &runner.Test{
+		ExpectedCommands: []*runner.ExpectedCommand{
+			runner.NewExpectedCommand(repoDir, "git remote -v", "origin\town:feature-cSSHURL (push)\nupstream\town:feature-oldSSHURL (fetch)", 0),
+			runner.NewExpectedCommand(repoDir, "git diff-index --quiet HEAD", "", 0),
+			runner.NewExpectedCommand(repoDir, "git fetch origin", "", 0),
+			runner.NewExpectedCommand(repoDir, "git fetch upstream", "", 0),
+			runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "currentBranch", 0),
+			runner.NewExpectedCommand(repoDir, "git checkout -b prp-feature-c", "", 0),
+			runner.NewExpectedCommand(repoDir, "git reset --hard origin/feature-c", "", 0),
+			runner.NewExpectedCommand(repoDir, "git rebase --onto upstream/master shaOld", "rebase failure", 1),
+			runner.NewExpectedCommand(repoDir, "git log -1 --format=%h %s REBASE_HEAD", "", 0),
+			runner.NewExpectedCommand(repoDir, "git diff --name-only --diff-filter=U", "", 0),
+			runner.NewExpectedCommand(repoDir, "git diff --shortstat", "", 0),
+			runner.NewExpectedCommand(repoDir, "git rebase --abort", "", 0),
+			runner.NewExpectedCommand(repoDir, "git rev-parse origin/feature-c", "fetchedSHA", 0),
+			runner.NewExpectedCommand(repoDir, fmt.Sprintf("git worktree add --detach %s fetchedSHA", worktree), "", 0),
+			runner.NewExpectedCommand(worktree, "git rebase --onto upstream/master shaOld", "rebase failure", 1),
+			runner.NewExpectedCommand(repoDir, "git rev-parse --git-dir", ".git", 0),
+			runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
+			runner.NewExpectedCommand(repoDir, "git branch -D prp-feature-c", "", 0),
+		},
+	}
+	err := command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Unable to rebase all pull requests")
+	assert.Equal(t, []*runner.ExpectedCommand{}, cb.ExpectedCommands)
+	assert.Equal(t, []error(nil), cb.Errors)
+
+	conflicts, err := ioutil.ReadFile(fmt.Sprintf("%s/.git/prp-conflicts.json", repoDir))
+	assert.Nil(t, err)
+	assert.Contains(t, string(conflicts), `"upstream": "upstream/master"`)
+}
+
+func TestCmdAutoRebaseLeaveConflictsMerge(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	worktree := fmt.Sprintf("%s-prp-1", repoDir)
+	assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/.git", repoDir), 0777))
+	defer removeFile(t, repoDir)
+	ts := getAutoRebaseTestServer("")
+	defer ts.Close()
+	conf, configFileName := getConfigWithAPIURLAndPath(t, ts.URL, repoDir)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	conf.Profiles["foo"].TrackedRepos[1].UpdateStrategy = config.UpdateStrategyMerge
+	assert.Nil(t, conf.Write(configFileName))
+	set := getBaseFlagSet(configFileName)
+	set.Bool("leave-conflicts", true, "doc")
+	app, _, writer := appWithTestWriters()
+	cb := &runner.Test{
+		ExpectedCommands: []*runner.ExpectedCommand{
+			runner.NewExpectedCommand(repoDir, "git remote -v", "origin\tlabelSSHURL (push)\nupstream\tbaseLabel1SSHURL (fetch)", 0),
+			runner.NewExpectedCommand(repoDir, "git diff-index --quiet HEAD", "", 0),
+			runner.NewExpectedCommand(repoDir, "git fetch origin", "", 0),
+			runner.NewExpectedCommand(repoDir, "git fetch upstream", "", 0),
+			runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "currentBranch", 0),
+			runner.NewExpectedCommand(repoDir, "git checkout -b prp-ref1", "", 0),
+			runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
+			runner.NewExpectedCommand(repoDir, "git merge --no-edit upstream/baseRef1", "merge failure", 1),
+			runner.NewExpectedCommand(repoDir, "git merge --abort", "", 0),
+			runner.NewExpectedCommand(repoDir, "git rev-parse origin/ref1", "fetchedSHA", 0),
+			runner.NewExpectedCommand(repoDir, fmt.Sprintf("git worktree add --detach %s fetchedSHA", worktree), "", 0),
+			runner.NewExpectedCommand(worktree, "git merge --no-edit upstream/baseRef1", "merge failure", 1),
+			runner.NewExpectedCommand(repoDir, "git rev-parse --git-dir", ".git", 0),
+			runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
+			runner.NewExpectedCommand(repoDir, "git branch -D prp-ref1", "", 0),
+		},
+	}
+	err := command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Unable to rebase all pull requests")
+	assert.Equal(t, []*runner.ExpectedCommand{}, cb.ExpectedCommands)
+	assert.Equal(t, []error(nil), cb.Errors)
+	assert.Equal(
+		t,
+		[]string{
+			"Could not rebase PR #1 in own/rep because: Unable to merge upstream/baseRef1, there may be a conflict",
+			"merge failure",
+			fmt.Sprintf("Resolve the conflicts in %s and then run 'prp auto-rebase --continue'", worktree),
+			"",
+		},
+		strings.Split(writer.String(), "\n"),
+	)
+
+	conflicts, err := ioutil.ReadFile(fmt.Sprintf("%s/.git/prp-conflicts.json", repoDir))
+	assert.Nil(t, err)
+	assert.Contains(t, string(conflicts), `"operation": "merge"`)
+}
+
+// A left merge is continued with git merge and pushed without rewriting the branch
+func TestCmdAutoRebaseContinueMerge(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	worktree := fmt.Sprintf("%s-prp-1", repoDir)
+	_, configFileName := writeConflictsAndConfig(t, repoDir, worktree)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	defer removeFile(t, repoDir)
+	conflicts := strings.Replace(getConflictsJSON(worktree), `"upstream": "upstream/baseRef1",`, `"upstream": "upstream/baseRef1",
+    "operation": "merge",`, 1)
+	assert.Nil(t, ioutil.WriteFile(fmt.Sprintf("%s/.git/prp-conflicts.json", repoDir), []byte(conflicts), 0644))
+	set := getBaseFlagSet(configFileName)
+	set.Bool("continue", true, "doc")
+	set.Bool("verbose", true, "doc")
+	app, _, writer := appWithTestWriters()
+	cb := &runner.Test{
+		ExpectedCommands: []*runner.ExpectedCommand{
+			runner.NewExpectedCommand(repoDir, "git rev-parse --git-dir", ".git", 0),
+			runner.NewExpectedCommand(worktree, "git -c core.editor=true merge --continue", "", 0),
+			runner.NewExpectedCommand(worktree, "git rev-parse origin/ref1 HEAD", "oldSHA\nnewSHA", 0),
+			runner.NewExpectedCommand(worktree, "git push origin HEAD:ref1", "", 0),
+			runner.NewExpectedCommand(repoDir, fmt.Sprintf("git worktree remove --force %s", worktree), "", 0),
+		},
+	}
+	assert.Nil(t, command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil)))
+	assert.Equal(t, []*runner.ExpectedCommand{}, cb.ExpectedCommands)
+	assert.Equal(t, []error(nil), cb.Errors)
+	assert.Equal(
+		t,
+		[]string{
+			fmt.Sprintf("Continuing merge in %s", worktree),
+			"Pushing to origin/ref1",
+			fmt.Sprintf("Removing worktree %s", worktree),
+			"",
+		},
+		strings.Split(writer.String(), "\n"),
+	)
+}
+
+func writeConflictsAndConfig(t *testing.T, repoDir, worktree string) (string, string) {
+	t.Helper()
+	assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/.git", repoDir), 0777))
+	assert.Nil(t, ioutil.WriteFile(fmt.Sprintf("%s/.git/prp-conflicts.json", repoDir), []byte(getConflictsJSON(worktree)), 0644))
+	_, configFileName := getConfigWithAPIURLAndPath(t, "", repoDir)
+	return repoDir, configFileName
+}
+
+func getConflictsJSON(worktree string) string {
+	return fmt.Sprintf(`[
+  {
+    "pullRequestId": 1,
+    "branch": "ref1",
+    "remote": "origin",
+    "worktree": "%s",
+    "upstream": "upstream/baseRef1",
+    "fetchedSha": "fetchedSHA"
+  }
+]`, worktree)
+}
+
+func TestCmdAutoRebaseBackupRef(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/.git", repoDir), 0777))
+	defer removeFile(t, repoDir)
+	ts := getAutoRebaseTestServer("")
+	defer ts.Close()
+	_, configFileName := getConfigWithAPIURLAndPath(t, ts.URL, repoDir)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	set := getBaseFlagSet(configFileName)
+	set.Bool("backup-ref", true, "doc")
+	app, _, writer := appWithTestWriters()
+	cb := &runner.Test{
+		ExpectedCommands: []*runner.ExpectedCommand{
+			runner.NewExpectedCommand(repoDir, "git remote -v", "origin\tlabelSSHURL (push)\nupstream\tbaseLabel1SSHURL (fetch)", 0),
+			runner.NewExpectedCommand(repoDir, "git diff-index --quiet HEAD", "", 0),
+			runner.NewExpectedCommand(repoDir, "git fetch origin", "", 0),
+			runner.NewExpectedCommand(repoDir, "git fetch upstream", "", 0),
+			runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "currentBranch", 0),
+			runner.NewExpectedCommand(repoDir, "git checkout -b prp-ref1", "", 0),
+			runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
+			runner.NewExpectedCommand(repoDir, "git rebase upstream/baseRef1", "", 0),
+			runner.NewExpectedCommand(repoDir, "git rev-parse origin/ref1 HEAD", "oldSHA\nnewSHA", 0),
+			runner.NewExpectedCommand(repoDir, "git update-ref refs/prp/backup/ref1 oldSHA", "", 0),
+			runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force-with-lease=ref1:oldSHA", "", 0),
+			runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
+			runner.NewExpectedCommand(repoDir, "git branch -D prp-ref1", "", 0),
+		},
+	}
+	assert.Nil(t, command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil)))
+	assert.Equal(t, []*runner.ExpectedCommand{}, cb.ExpectedCommands)
+	assert.Equal(t, []error(nil), cb.Errors)
+	assert.Equal(t, "", writer.String())
+
+	entries := readJournal(t, configFileName)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "own/rep", entries[0]["repo"])
+	assert.Equal(t, float64(1), entries[0]["pullRequestId"])
+	assert.Equal(t, "ref1", entries[0]["branch"])
+	assert.Equal(t, repoDir, entries[0]["path"])
+	assert.Equal(t, "origin", entries[0]["remote"])
+	assert.Equal(t, "oldSHA", entries[0]["oldSha"])
+	assert.Equal(t, "newSHA", entries[0]["newSha"])
+	assert.Equal(t, "pushed", entries[0]["outcome"])
+}
+
+func TestCmdAutoRebaseBackupRefFailure(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/.git", repoDir), 0777))
+	defer removeFile(t, repoDir)
+	ts := getAutoRebaseTestServer("")
+	defer ts.Close()
+	_, configFileName := getConfigWithAPIURLAndPath(t, ts.URL, repoDir)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	set := getBaseFlagSet(configFileName)
+	set.Bool("backup-ref", true, "doc")
+	app, _, writer := appWithTestWriters()
+	cb := &runner.Test{
+		ExpectedCommands: []*runner.ExpectedCommand{
+			runner.NewExpectedCommand(repoDir, "git remote -v", "origin\tlabelSSHURL (push)\nupstream\tbaseLabel1SSHURL (fetch)", 0),
+			runner.NewExpectedCommand(repoDir, "git diff-index --quiet HEAD", "", 0),
+			runner.NewExpectedCommand(repoDir, "git fetch origin", "", 0),
+			runner.NewExpectedCommand(repoDir, "git fetch upstream", "", 0),
+			runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "currentBranch", 0),
+			runner.NewExpectedCommand(repoDir, "git checkout -b prp-ref1", "", 0),
+			runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
+			runner.NewExpectedCommand(repoDir, "git rebase upstream/baseRef1", "", 0),
+			runner.NewExpectedCommand(repoDir, "git rev-parse origin/ref1 HEAD", "oldSHA\nnewSHA", 0),
+			runner.NewExpectedCommand(repoDir, "git update-ref refs/prp/backup/ref1 oldSHA", "update-ref failure", 1),
+			runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
+			runner.NewExpectedCommand(repoDir, "git branch -D prp-ref1", "", 0),
+		},
+	}
+	err := command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Unable to rebase all pull requests")
+	assert.Equal(t, []*runner.ExpectedCommand{}, cb.ExpectedCommands)
+	assert.Equal(t, []error(nil), cb.Errors)
+	assert.Equal(t, "Could not rebase PR #1 in own/rep because: Unable to save backup ref refs/prp/backup/ref1\nupdate-ref failure\n", writer.String())
+
+	entries := readJournal(t, configFileName)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "failed", entries[0]["outcome"])
+}
+
+func TestCmdAutoRebaseUndo(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	configFileName := writeJournalAndConfig(t, repoDir)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	set := getBaseFlagSet(configFileName)
+	set.Bool("yes", true, "doc")
+	assert.Nil(t, set.Parse([]string{"undo"}))
+	app, writer, errWriter := appWithTestWriters()
+	cb := &runner.Test{
+		ExpectedCommands: []*runner.ExpectedCommand{
+			runner.NewExpectedCommand(repoDir, "git push --force-with-lease=ref1:sha3 origin sha2:refs/heads/ref1", "", 0),
+		},
+	}
+	assert.Nil(t, command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil)))
+	assert.Equal(t, []*runner.ExpectedCommand{}, cb.ExpectedCommands)
+	assert.Equal(t, []error(nil), cb.Errors)
+	assert.Equal(t, "", errWriter.String())
+	assert.Equal(t, "The following pushes will be undone:\n  PR #1 in own/rep: ref1 from sha3 back to sha2\n", writer.String())
+
+	entries := readJournal(t, configFileName)
+	assert.Equal(t, 5, len(entries))
+	assert.Equal(t, "sha3", entries[4]["oldSha"])
+	assert.Equal(t, "sha2", entries[4]["newSha"])
+	assert.Equal(t, "undone", entries[4]["outcome"])
+}
+
+func TestCmdAutoRebaseUndoPullRequestNumber(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	configFileName := writeJournalAndConfig(t, repoDir)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	set := getBaseFlagSet(configFileName)
+	set.Int("pull-request-number", 2, "doc")
+	assert.Nil(t, set.Parse([]string{"undo"}))
+	app, writer, _ := appWithTestWriters()
+	cb := &runner.Test{}
+	assert.Nil(t, command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil)))
+	assert.Equal(t, []error(nil), cb.Errors)
+	assert.Equal(t, "Nothing to undo\n", writer.String())
+}
+
+func TestCmdAutoRebaseUndoDeclined(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	configFileName := writeJournalAndConfig(t, repoDir)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	stdin, err := ioutil.TempFile("", "stdin")
+	assert.Nil(t, err)
+	defer removeFile(t, stdin.Name())
+	_, err = stdin.WriteString("n\n")
+	assert.Nil(t, err)
+	_, err = stdin.Seek(0, 0)
+	assert.Nil(t, err)
+	oldStdin := os.Stdin
+	os.Stdin = stdin
+	defer func() { os.Stdin = oldStdin }()
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"undo"}))
+	app, writer, _ := appWithTestWriters()
+	cb := &runner.Test{}
+	assert.Nil(t, command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil)))
+	assert.Equal(t, []error(nil), cb.Errors)
+	assert.Equal(t, "The following pushes will be undone:\n  PR #1 in own/rep: ref1 from sha3 back to sha2\nContinue? [y/N] ", writer.String())
+	assert.Equal(t, 4, len(readJournal(t, configFileName)))
+}
+
+func TestCmdAutoRebaseUndoFailure(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	configFileName := writeJournalAndConfig(t, repoDir)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	set := getBaseFlagSet(configFileName)
+	set.Bool("yes", true, "doc")
+	assert.Nil(t, set.Parse([]string{"undo"}))
+	app, _, errWriter := appWithTestWriters()
+	cb := &runner.Test{
+		ExpectedCommands: []*runner.ExpectedCommand{
+			runner.NewExpectedCommand(repoDir, "git push --force-with-lease=ref1:sha3 origin sha2:refs/heads/ref1", "stale info", 1),
+		},
+	}
+	err := command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Unable to undo all pushes")
+	assert.Equal(t, []*runner.ExpectedCommand{}, cb.ExpectedCommands)
+	assert.Equal(t, []error(nil), cb.Errors)
+	assert.Equal(
+		t,
+		"Could not undo PR #1 in own/rep because: Unable to push sha2 to origin/ref1, it may have changed since it was rebased\nstale info\n",
+		errWriter.String(),
+	)
+	assert.Equal(t, 4, len(readJournal(t, configFileName)))
+}
+
+func TestCmdAutoRebaseUndoNothing(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"undo"}))
+	app, writer, _ := appWithTestWriters()
+	cb := &runner.Test{}
+	assert.Nil(t, command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil)))
+	assert.Equal(t, "Nothing to undo\n", writer.String())
+}
+
+func writeJournalAndConfig(t *testing.T, repoDir string) string {
+	t.Helper()
+	_, configFileName := getConfigWithAPIURLAndPath(t, "", repoDir)
+	entry := `{"time":"2017-01-01T00:00:00Z","repo":"own/rep","pullRequestId":%d,"branch":"%s","path":"%s","remote":"origin","oldSha":"%s","newSha":"%s","outcome":"%s"}`
+	journal := strings.Join(
+		[]string{
+			fmt.Sprintf(entry, 1, "ref1", repoDir, "sha1", "sha2", "pushed"),
+			fmt.Sprintf(entry, 2, "ref2", repoDir, "sha4", "sha5", "pushed"),
+			fmt.Sprintf(entry, 1, "ref1", repoDir, "sha2", "sha3", "pushed"),
+			fmt.Sprintf(entry, 2, "ref2", repoDir, "sha5", "sha4", "undone"),
+			"",
+		},
+		"\n",
+	)
+	assert.Nil(t, ioutil.WriteFile(fmt.Sprintf("%s.journal", configFileName), []byte(journal), 0644))
+	return configFileName
+}
+
+func readJournal(t *testing.T, configFileName string) []map[string]interface{} {
+	t.Helper()
+	journal, err := ioutil.ReadFile(fmt.Sprintf("%s.journal", configFileName))
+	assert.Nil(t, err)
+	entries := []map[string]interface{}{}
+	for _, line := range strings.Split(strings.TrimSpace(string(journal)), "\n") {
+		var entry map[string]interface{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+func TestCmdAutoRebasePolicySkips(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		setFlags func(*flag.FlagSet)
+		output   string
+	}{
+		{
+			"MinApprovals",
+			func(set *flag.FlagSet) { set.Int("min-approvals", 4, "doc") },
+			"Skipping PR #1 in own/rep because it has 2 of 4 required approvals\n",
+		},
+		{
+			"RequiredLabel",
+			func(set *flag.FlagSet) {
+				labels := cli.StringSlice{"label1", "ready"}
+				set.Var(&labels, "require-label", "doc")
+			},
+			"Skipping PR #1 in own/rep because it does not have the label ready\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := getAutoRebaseTestServer("")
+			defer ts.Close()
+			repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+			assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/.git", repoDir), 0777))
+			defer removeFile(t, repoDir)
+			_, configFileName := getConfigWithAPIURLAndPath(t, ts.URL, repoDir)
+			defer removeFile(t, configFileName)
+			set := getBaseFlagSet(configFileName)
+			set.Bool("verbose", true, "doc")
+			tc.setFlags(set)
+			app, _, writer := appWithTestWriters()
+			cb := &runner.Test{}
+			assert.Nil(t, command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil)))
+			assert.Equal(t, []error(nil), cb.Errors)
+			assert.Equal(t, tc.output, writer.String())
+		})
+	}
+}
+
+func TestCmdAutoRebaseRepoPolicyAllows(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	defer removeFile(t, repoDir)
+	cb := &runner.Test{
+		ExpectedCommands: []*runner.ExpectedCommand{
+			runner.NewExpectedCommand(repoDir, "git remote -v", "origin\tlabelSSHURL (push)\nupstream\tbaseLabel1SSHURL (fetch)", 0),
+			runner.NewExpectedCommand(repoDir, "git diff-index --quiet HEAD", "", 0),
+			runner.NewExpectedCommand(repoDir, "git fetch origin", "", 0),
+			runner.NewExpectedCommand(repoDir, "git fetch upstream", "", 0),
+			runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "currentBranch", 0),
+			runner.NewExpectedCommand(repoDir, "git checkout -b prp-ref1", "", 0),
+			runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
+			runner.NewExpectedCommand(repoDir, "git rebase upstream/baseRef1", "", 0),
+			runner.NewExpectedCommand(repoDir, "git rev-parse origin/ref1 HEAD", "oldSHA\nnewSHA", 0),
+			runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force-with-lease=ref1:oldSHA", "", 0),
+			runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
+			runner.NewExpectedCommand(repoDir, "git branch -D prp-ref1", "", 0),
+		},
+	}
+	writer := runBaseCommandWithRepo(t, repoDir, cb, false, false, func(repo *config.Repo) {
+		repo.RebasePolicy = &config.RebasePolicy{
+			MinApprovals:         2,
+			RequiredLabels:       []string{"label1"},
+			SkipDrafts:           true,
+			RequirePassingBuilds: true,
+		}
+	})
+	assert.Equal(t, "", writer.String())
+}
+
+func TestCmdAutoRebaseRepoPolicySkips(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		draft  bool
+		state  string
+		policy config.RebasePolicy
+		output string
+	}{
+		{
+			"Draft",
+			true,
+			"success",
+			config.RebasePolicy{SkipDrafts: true},
+			"Skipping PR #1 in own/rep because it is a draft\n",
+		},
+		{
+			"FailingBuild",
+			false,
+			"failure",
+			config.RebasePolicy{RequirePassingBuilds: true},
+			"Skipping PR #1 in own/rep because the build1 build is not passing\n",
+		},
+		{
+			"NoBuilds",
+			false,
+			"",
+			config.RebasePolicy{RequirePassingBuilds: true},
+			"Skipping PR #1 in own/rep because it has no builds\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := getPolicyTestServer(tc.draft, tc.state)
+			defer ts.Close()
+			repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+			assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/.git", repoDir), 0777))
+			defer removeFile(t, repoDir)
+			conf, configFileName := getConfigWithAPIURLAndPath(t, ts.URL, repoDir)
+			defer removeFile(t, configFileName)
+			policy := tc.policy
+			conf.Profiles["foo"].TrackedRepos[1].RebasePolicy = &policy
+			assert.Nil(t, conf.Write(configFileName))
+			set := getBaseFlagSet(configFileName)
+			set.Bool("verbose", true, "doc")
+			app, _, writer := appWithTestWriters()
+			cb := &runner.Test{}
+			assert.Nil(t, command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil)))
+			assert.Equal(t, []error(nil), cb.Errors)
+			assert.Equal(t, tc.output, writer.String())
+		})
+	}
+}
+
+func getPolicyTestServer(draft bool, state string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := handleUserRequest(r, "guy")
+		if response != nil {
+			fmt.Fprint(w, *response)
+			return
+		}
+
+		switch r.URL.String() {
+		case "/repos/own/rep/pulls?per_page=100":
+			bytes, _ := json.Marshal([]interface{}{
+				struct {
+					*github.PullRequest
+					Draft bool `json:"draft"`
+				}{newPullRequest(1, "prOne", "guy", "label", "ref1", "sha1", "baseLabel1", "baseRef1"), draft},
+			})
+			fmt.Fprint(w, string(bytes))
+		case "/repos/foo/bar/pulls?per_page=100":
+			fmt.Fprint(w, "[]")
+		case "/repos/own/rep/compare/label...baseLabel1":
+			bytes, _ := json.Marshal(newCommitsComparison(1))
+			fmt.Fprint(w, string(bytes))
+		case "/repos/own/rep/commits/sha1/statuses?per_page=100":
+			statuses := []*github.RepoStatus{}
+			if state != "" {
+				statuses = append(statuses, newStatus("build1", state))
+			}
+
+			bytes, _ := json.Marshal(statuses)
+			fmt.Fprint(w, string(bytes))
+		case "/repos/own/rep/commits/sha1/check-runs?per_page=100":
+			fmt.Fprint(w, `{"check_runs":[]}`)
+		default:
+			panic(r.URL.String())
+		}
+	}))
+}
+
+func TestCmdAutoRebaseAllOwners(t *testing.T) {
+	ts := getAutoRebaseTestServer("")
+	defer ts.Close()
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/.git", repoDir), 0777))
+	defer removeFile(t, repoDir)
+	_, configFileName := getConfigWithAPIURLAndPath(t, ts.URL, repoDir)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	set := getBaseFlagSet(configFileName)
+	set.Bool("all-owners", true, "doc")
+	set.Bool("verbose", true, "doc")
+	app, _, writer := appWithTestWriters()
+	cb := &runner.Test{
+		ExpectedCommands: []*runner.ExpectedCommand{
+			runner.NewExpectedCommand(repoDir, "git remote -v", "origin\tlabelSSHURL (push)\nupstream\tbaseLabel1SSHURL (fetch)", 0),
+			runner.NewExpectedCommand(repoDir, "git diff-index --quiet HEAD", "", 0),
+			runner.NewExpectedCommand(repoDir, "git fetch origin", "", 0),
+			runner.NewExpectedCommand(repoDir, "git fetch upstream", "", 0),
+			runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "currentBranch", 0),
+			runner.NewExpectedCommand(repoDir, "git checkout -b prp-ref1", "", 0),
+			runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
+			runner.NewExpectedCommand(repoDir, "git rebase upstream/baseRef1", "", 0),
+			runner.NewExpectedCommand(repoDir, "git rev-parse origin/ref1 HEAD", "oldSHA\nnewSHA", 0),
+			runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force-with-lease=ref1:oldSHA", "", 0),
+			runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
+			runner.NewExpectedCommand(repoDir, "git branch -D prp-ref1", "", 0),
+		},
+	}
+	assert.Nil(t, command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil)))
+	assert.Equal(t, []*runner.ExpectedCommand{}, cb.ExpectedCommands)
+	assert.Equal(t, []error(nil), cb.Errors)
+	assert.Contains(t, writer.String(), "Skipping PR #2 in foo/bar because its fork does not allow edits from maintainers\n")
+}
+
+func TestCmdAutoRebaseContributorFork(t *testing.T) {
+	commented := false
+	ts := getContributorTestServer(t, &commented)
+	defer ts.Close()
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/.git", repoDir), 0777))
+	defer removeFile(t, repoDir)
+	_, configFileName := getConfigWithAPIURLAndPath(t, ts.URL, repoDir)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	set := getBaseFlagSet(configFileName)
+	set.String("owner", "contributor", "doc")
+	set.Bool("comment", true, "doc")
+	app, _, writer := appWithTestWriters()
+	cb := &runner.Test{
+		ExpectedCommands: []*runner.ExpectedCommand{
+			runner.NewExpectedCommand(repoDir, "git remote -v", "origin\tlabelSSHURL (push)\nupstream\tbaseLabel1SSHURL (fetch)", 0),
+			runner.NewExpectedCommand(repoDir, "git remote add prp-contributor contributorLabelSSHURL", "", 0),
+			runner.NewExpectedCommand(repoDir, "git diff-index --quiet HEAD", "", 0),
+			runner.NewExpectedCommand(repoDir, "git fetch prp-contributor", "", 0),
+			runner.NewExpectedCommand(repoDir, "git fetch upstream", "", 0),
+			runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "currentBranch", 0),
+			runner.NewExpectedCommand(repoDir, "git checkout -b prp-feature", "", 0),
+			runner.NewExpectedCommand(repoDir, "git reset --hard prp-contributor/feature", "", 0),
+			runner.NewExpectedCommand(repoDir, "git rebase upstream/baseRef1", "", 0),
+			runner.NewExpectedCommand(repoDir, "git rev-parse prp-contributor/feature HEAD", "oldSHA\nnewSHA", 0),
+			runner.NewExpectedCommand(repoDir, "git push prp-contributor prp-feature:feature --force-with-lease=feature:oldSHA", "", 0),
+			runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
+			runner.NewExpectedCommand(repoDir, "git branch -D prp-feature", "", 0),
+		},
+	}
+	assert.Nil(t, command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil)))
+	assert.Equal(t, []*runner.ExpectedCommand{}, cb.ExpectedCommands)
+	assert.Equal(t, []error(nil), cb.Errors)
+	assert.Equal(t, "", writer.String())
+	assert.True(t, commented)
+}
+
+func TestCmdAutoRebaseContributorForkRemoteFailure(t *testing.T) {
+	commented := false
+	ts := getContributorTestServer(t, &commented)
+	defer ts.Close()
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/.git", repoDir), 0777))
+	defer removeFile(t, repoDir)
+	_, configFileName := getConfigWithAPIURLAndPath(t, ts.URL, repoDir)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	set := getBaseFlagSet(configFileName)
+	set.String("owner", "contributor", "doc")
+	set.Bool("comment", true, "doc")
+	app, _, writer := appWithTestWriters()
+	cb := &runner.Test{
+		ExpectedCommands: []*runner.ExpectedCommand{
+			runner.NewExpectedCommand(repoDir, "git remote -v", "origin\tlabelSSHURL (push)\nupstream\tbaseLabel1SSHURL (fetch)", 0),
+			runner.NewExpectedCommand(repoDir, "git remote add prp-contributor contributorLabelSSHURL", "remote failure", 1),
+		},
+	}
+	err := command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Unable to rebase all pull requests")
+	assert.Equal(t, []*runner.ExpectedCommand{}, cb.ExpectedCommands)
+	assert.Equal(t, []error(nil), cb.Errors)
+	assert.Equal(t, "Could not rebase PR #3 in own/rep because: Unable to add remote prp-contributor for contributorLabelSSHURL\nremote failure\n", writer.String())
+	assert.False(t, commented)
+}
+
+func TestCmdAutoRebaseContributorForkRemoteNameTaken(t *testing.T) {
+	commented := false
+	ts := getContributorTestServer(t, &commented)
+	defer ts.Close()
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/.git", repoDir), 0777))
+	defer removeFile(t, repoDir)
+	_, configFileName := getConfigWithAPIURLAndPath(t, ts.URL, repoDir)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	set := getBaseFlagSet(configFileName)
+	set.String("owner", "contributor", "doc")
+	app, _, writer := appWithTestWriters()
+	cb := &runner.Test{
+		ExpectedCommands: []*runner.ExpectedCommand{
+			runner.NewExpectedCommand(
+				repoDir,
+				"git remote -v",
+				"origin\tlabelSSHURL (push)\nupstream\tbaseLabel1SSHURL (fetch)\nprp-contributor\totherSSHURL (fetch)\nprp-contributor\totherSSHURL (push)",
+				0,
+			),
+			runner.NewExpectedCommand(repoDir, "git remote add prp-contributor-2 contributorLabelSSHURL", "remote failure", 1),
+		},
+	}
+	err := command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Unable to rebase all pull requests")
+	assert.Equal(t, []*runner.ExpectedCommand{}, cb.ExpectedCommands)
+	assert.Equal(t, []error(nil), cb.Errors)
+	assert.Equal(t, "Could not rebase PR #3 in own/rep because: Unable to add remote prp-contributor-2 for contributorLabelSSHURL\nremote failure\n", writer.String())
+}
+
+func getContributorTestServer(t *testing.T, commented *bool) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := handleUserRequest(r, "guy")
+		if response != nil {
+			fmt.Fprint(w, *response)
+			return
+		}
+
+		switch r.URL.String() {
+		case "/repos/own/rep/pulls?per_page=100":
+			pr := newPullRequest(3, "prThree", "contributor", "contributorLabel", "feature", "sha3", "baseLabel1", "baseRef1")
+			maintainerCanModify := true
+			pr.MaintainerCanModify = &maintainerCanModify
+			bytes, _ := json.Marshal([]*github.PullRequest{pr})
+			fmt.Fprint(w, string(bytes))
+		case "/repos/foo/bar/pulls?per_page=100":
+			fmt.Fprint(w, "[]")
+		case "/repos/own/rep/compare/contributorLabel...baseLabel1":
+			bytes, _ := json.Marshal(newCommitsComparison(1))
+			fmt.Fprint(w, string(bytes))
+		case "/repos/own/rep/issues/3/comments":
+			var comment github.IssueComment
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&comment))
+			assert.Equal(t, "Updated this branch with the latest changes from baseRef1 using prp auto-rebase.", comment.GetBody())
+			*commented = true
+			fmt.Fprint(w, "{}")
+		default:
+			panic(r.URL.String())
+		}
+	}))
+}
+
+func TestCmdAutoRebaseAutosquash(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	countFixups := regexp.QuoteMeta("git rev-list --count --grep=^fixup! --grep=^squash! upstream/baseRef1..origin/ref1")
+	autosquash := "git -c sequence.editor=: rebase -i --autosquash upstream/baseRef1"
+	var testCases = []struct {
+		name             string
+		expectedCommands []*runner.ExpectedCommand
+		output           string
+		expectedError    bool
+	}{
+		{
+			"Folded",
+			[]*runner.ExpectedCommand{
+				runner.NewExpectedCommand(repoDir, countFixups, "2", 0),
+				runner.NewExpectedCommand(repoDir, "git log -1 --format=%T", "tree1", 0),
+				runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
+				runner.NewExpectedCommand(repoDir, autosquash, "", 0),
+				runner.NewExpectedCommand(repoDir, "git log -1 --format=%T", "tree1", 0),
+				runner.NewExpectedCommand(repoDir, "git rev-parse origin/ref1 HEAD", "oldSHA\nnewSHA", 0),
+				runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force-with-lease=ref1:oldSHA", "", 0),
+			},
+			"Folded 2 fixup commits into PR #1\n",
+			false,
+		},
+		{
+			"NoFixups",
+			[]*runner.ExpectedCommand{
+				runner.NewExpectedCommand(repoDir, countFixups, "0", 0),
+				runner.NewExpectedCommand(repoDir, "git rev-parse origin/ref1 HEAD", "oldSHA\nnewSHA", 0),
+				runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force-with-lease=ref1:oldSHA", "", 0),
+			},
+			"No fixup commits to squash\n",
+			false,
+		},
+		{
+			"TreeChanged",
+			[]*runner.ExpectedCommand{
+				runner.NewExpectedCommand(repoDir, countFixups, "1", 0),
+				runner.NewExpectedCommand(repoDir, "git log -1 --format=%T", "tree1", 0),
+				runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
+				runner.NewExpectedCommand(repoDir, autosquash, "", 0),
+				runner.NewExpectedCommand(repoDir, "git log -1 --format=%T", "tree2", 0),
+			},
+			"Could not rebase PR #1 in own/rep because: Squashing the fixup commits would change the result of the rebase (tree2 instead of tree1)\n",
+			true,
+		},
+		{
+			"Conflict",
+			[]*runner.ExpectedCommand{
+				runner.NewExpectedCommand(repoDir, countFixups, "1", 0),
+				runner.NewExpectedCommand(repoDir, "git log -1 --format=%T", "tree1", 0),
+				runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
+				runner.NewExpectedCommand(repoDir, autosquash, "conflict", 1),
+				runner.NewExpectedCommand(repoDir, "git rebase --abort", "", 0),
+			},
+			"Could not rebase PR #1 in own/rep because: Unable to squash the fixup commits, they may conflict\nconflict\n",
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer removeFile(t, repoDir)
+			expectedCommands := []*runner.ExpectedCommand{
+				runner.NewExpectedCommand(repoDir, "git remote -v", "origin\tlabelSSHURL (push)\nupstream\tbaseLabel1SSHURL (fetch)", 0),
+				runner.NewExpectedCommand(repoDir, "git diff-index --quiet HEAD", "", 0),
+				runner.NewExpectedCommand(repoDir, "git fetch origin", "", 0),
+				runner.NewExpectedCommand(repoDir, "git fetch upstream", "", 0),
+				runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "currentBranch", 0),
+				runner.NewExpectedCommand(repoDir, "git checkout -b prp-ref1", "", 0),
+				runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
+				runner.NewExpectedCommand(repoDir, "git rebase upstream/baseRef1", "", 0),
+			}
+			expectedCommands = append(expectedCommands, tc.expectedCommands...)
+			expectedCommands = append(
+				expectedCommands,
+				runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
+				runner.NewExpectedCommand(repoDir, "git branch -D prp-ref1", "", 0),
+			)
+			cb := &runner.Test{ExpectedCommands: expectedCommands}
+			writer := runBaseCommandWithRepo(t, repoDir, cb, !tc.expectedError, tc.expectedError, func(repo *config.Repo) {
+				repo.Autosquash = true
+			})
+			assert.Contains(t, writer.String(), tc.output)
+		})
+	}
+}
+
+func TestCmdAutoRebaseHooks(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	env := "env PRP_REPO=own/rep PRP_PULL_REQUEST_NUMBER=1 PRP_BRANCH=ref1 PRP_TARGET_BRANCH=baseRef1 PRP_OLD_SHA=oldSHA"
+	var testCases = []struct {
+		name             string
+		expectedCommands []*runner.ExpectedCommand
+		output           string
+		expectedError    bool
+	}{
+		{
+			"Success",
+			[]*runner.ExpectedCommand{
+				runner.NewExpectedCommand(repoDir, fmt.Sprintf("%s PRP_NEW_SHA=newSHA sh -c make lock", env), "", 0),
+				runner.NewExpectedCommand(repoDir, "git diff-index --quiet HEAD", "", 0),
+				runner.NewExpectedCommand(repoDir, "git rev-parse origin/ref1 HEAD", "oldSHA\nlockSHA", 0),
+				runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force-with-lease=ref1:oldSHA", "", 0),
+				runner.NewExpectedCommand(repoDir, fmt.Sprintf("%s PRP_NEW_SHA=lockSHA sh -c notify", env), "", 0),
+			},
+			"",
+			false,
+		},
+		{
+			"PrePushFailure",
+			[]*runner.ExpectedCommand{
+				runner.NewExpectedCommand(repoDir, fmt.Sprintf("%s PRP_NEW_SHA=newSHA sh -c make lock", env), "make failure", 1),
+			},
+			"Could not rebase PR #1 in own/rep because: Hook failed: make lock\nmake failure\n",
+			true,
+		},
+		{
+			"PrePushUncommittedChanges",
+			[]*runner.ExpectedCommand{
+				runner.NewExpectedCommand(repoDir, fmt.Sprintf("%s PRP_NEW_SHA=newSHA sh -c make lock", env), "", 0),
+				runner.NewExpectedCommand(repoDir, "git diff-index --quiet HEAD", "", 1),
+			},
+			"Could not rebase PR #1 in own/rep because: Pre-push hooks left uncommitted changes in " + repoDir + ", hooks must commit the changes they make\n",
+			true,
+		},
+		{
+			"PostPushFailure",
+			[]*runner.ExpectedCommand{
+				runner.NewExpectedCommand(repoDir, fmt.Sprintf("%s PRP_NEW_SHA=newSHA sh -c make lock", env), "", 0),
+				runner.NewExpectedCommand(repoDir, "git diff-index --quiet HEAD", "", 0),
+				runner.NewExpectedCommand(repoDir, "git rev-parse origin/ref1 HEAD", "oldSHA\nnewSHA", 0),
+				runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force-with-lease=ref1:oldSHA", "", 0),
+				runner.NewExpectedCommand(repoDir, fmt.Sprintf("%s PRP_NEW_SHA=newSHA sh -c notify", env), "notify failure", 1),
+			},
+			"Hook failed: notify\nnotify failure\nWarning: Post-push hooks failed for PR #1 in own/rep\n",
+			false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer removeFile(t, repoDir)
+			expectedCommands := []*runner.ExpectedCommand{
+				runner.NewExpectedCommand(repoDir, "git remote -v", "origin\tlabelSSHURL (push)\nupstream\tbaseLabel1SSHURL (fetch)", 0),
+				runner.NewExpectedCommand(repoDir, "git diff-index --quiet HEAD", "", 0),
+				runner.NewExpectedCommand(repoDir, "git fetch origin", "", 0),
+				runner.NewExpectedCommand(repoDir, "git fetch upstream", "", 0),
+				runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "currentBranch", 0),
+				runner.NewExpectedCommand(repoDir, "git checkout -b prp-ref1", "", 0),
+				runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
+				runner.NewExpectedCommand(repoDir, "git rebase upstream/baseRef1", "", 0),
+				runner.NewExpectedCommand(repoDir, "git rev-parse origin/ref1 HEAD", "oldSHA\nnewSHA", 0),
+			}
+			expectedCommands = append(expectedCommands, tc.expectedCommands...)
+			expectedCommands = append(
+				expectedCommands,
+				runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
+				runner.NewExpectedCommand(repoDir, "git branch -D prp-ref1", "", 0),
+			)
+			cb := &runner.Test{ExpectedCommands: expectedCommands}
+			writer := runBaseCommandWithRepo(t, repoDir, cb, false, tc.expectedError, func(repo *config.Repo) {
+				repo.PrePushHooks = []string{"make lock"}
+				repo.PostPushHooks = []string{"notify"}
+			})
+			assert.Equal(t, tc.output, writer.String())
+		})
+	}
+}
+
+func TestCmdAutoRebaseWatch(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	var testCases = []struct {
+		name          string
+		targets       []string
+		pushExitCodes []int
+		output        []string
+		expectedError bool
+	}{
+		{
+			"TargetMoved",
+			[]string{"base1", "base1", "base2"},
+			[]int{0, 0},
+			[]string{
+				"cycle 1: 1 rebased, 0 up to date, 0 skipped, 0 failed, next cycle in 1ms\n",
+				"baseRef1 has not moved since PR #1 in own/rep was updated\n",
+				"cycle 2: 0 rebased, 1 up to date, 0 skipped, 0 failed, next cycle in 1ms\n",
+				"cycle 3: 1 rebased, 0 up to date, 0 skipped, 0 failed, next cycle in 1ms\n",
+			},
+			false,
+		},
+		{
+			"Backoff",
+			[]string{"base1", "base1", "base1"},
+			[]int{1, 1, 0},
+			[]string{
+				"cycle 1: 0 rebased, 0 up to date, 0 skipped, 1 failed, next cycle in 2ms\n",
+				"cycle 2: 0 rebased, 0 up to date, 0 skipped, 1 failed, next cycle in 4ms\n",
+				"cycle 3: 1 rebased, 0 up to date, 0 skipped, 0 failed, next cycle in 1ms\n",
+			},
+			false,
+		},
+		{
+			"FailedLastCycle",
+			[]string{"base1"},
+			[]int{1},
+			[]string{"cycle 1: 0 rebased, 0 up to date, 0 skipped, 1 failed, next cycle in 2ms\n"},
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer removeFile(t, repoDir)
+			assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/.git", repoDir), 0777))
+			expectedCommands := []*runner.ExpectedCommand{}
+			for _, pushExitCode := range tc.pushExitCodes {
+				expectedCommands = append(
+					expectedCommands,
+					runner.NewExpectedCommand(repoDir, "git remote -v", "origin\tlabelSSHURL (push)\nupstream\tbaseLabel1SSHURL (fetch)", 0),
+					runner.NewExpectedCommand(repoDir, "git diff-index --quiet HEAD", "", 0),
+					runner.NewExpectedCommand(repoDir, "git fetch origin", "", 0),
+					runner.NewExpectedCommand(repoDir, "git fetch upstream", "", 0),
+					runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "currentBranch", 0),
+					runner.NewExpectedCommand(repoDir, "git checkout -b prp-ref1", "", 0),
+					runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
+					runner.NewExpectedCommand(repoDir, "git rebase upstream/baseRef1", "", 0),
+					runner.NewExpectedCommand(repoDir, "git rev-parse origin/ref1 HEAD", "oldSHA\nnewSHA", 0),
+					runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force-with-lease=ref1:oldSHA", "", pushExitCode),
+					runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
+					runner.NewExpectedCommand(repoDir, "git branch -D prp-ref1", "", 0),
+				)
+			}
+
+			cb := &runner.Test{ExpectedCommands: expectedCommands}
+			inner := getAutoRebaseTestServer("")
+			defer inner.Close()
+			ts := getWatchTestServer(inner, tc.targets)
+			defer ts.Close()
+			_, configFileName := getConfigWithAPIURLAndPath(t, ts.URL, repoDir)
+			defer removeFile(t, configFileName)
+			defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+			set := getBaseFlagSet(configFileName)
+			set.Bool("verbose", true, "doc")
+			set.Bool("watch", true, "doc")
+			set.Duration("interval", time.Millisecond, "doc")
+			set.Int("max-cycles", len(tc.targets), "doc")
+			app, writer, errWriter := appWithTestWriters()
+			err := command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil))
+			if tc.expectedError {
+				assert.EqualError(t, err, "Unable to rebase all pull requests")
+			} else {
+				assert.Nil(t, err)
+			}
+
+			assert.Equal(t, []*runner.ExpectedCommand{}, cb.ExpectedCommands)
+			assert.Equal(t, []error(nil), cb.Errors)
+			for _, output := range tc.output {
+				assert.Contains(t, writer.String()+errWriter.String(), output)
+			}
+		})
+	}
+}
+
+func TestCmdAutoRebaseWatchWaitForCI(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	defer removeFile(t, repoDir)
+	assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/.git", repoDir), 0777))
+	cb := &runner.Test{ExpectedCommands: []*runner.ExpectedCommand{
+		runner.NewExpectedCommand(repoDir, "git remote -v", "origin\tlabelSSHURL (push)\nupstream\tbaseLabel1SSHURL (fetch)", 0),
+		runner.NewExpectedCommand(repoDir, "git diff-index --quiet HEAD", "", 0),
+		runner.NewExpectedCommand(repoDir, "git fetch origin", "", 0),
+		runner.NewExpectedCommand(repoDir, "git fetch upstream", "", 0),
+		runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "currentBranch", 0),
+		runner.NewExpectedCommand(repoDir, "git checkout -b prp-ref1", "", 0),
+		runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
+		runner.NewExpectedCommand(repoDir, "git rebase upstream/baseRef1", "", 0),
+		runner.NewExpectedCommand(repoDir, "git rev-parse origin/ref1 HEAD", "oldSHA\nnewSHA", 0),
+		runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force-with-lease=ref1:oldSHA", "", 0),
+		runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
+		runner.NewExpectedCommand(repoDir, "git branch -D prp-ref1", "", 0),
+	}}
+	inner := getAutoRebaseTestServer("")
+	defer inner.Close()
+	statuses := getOverridingTestServer(inner, map[string]string{
+		"/repos/own/rep/commits/newSHA/statuses?per_page=100":   `[{"context":"build1","state":"success"}]`,
+		"/repos/own/rep/commits/newSHA/check-runs?per_page=100": `{"check_runs":[]}`,
+	})
+	defer statuses.Close()
+	ts := getWatchTestServer(statuses, []string{"base1"})
+	defer ts.Close()
+	_, configFileName := getConfigWithAPIURLAndPath(t, ts.URL, repoDir)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	set := getBaseFlagSet(configFileName)
+	set.Bool("watch", true, "doc")
+	set.Bool("wait-for-ci", true, "doc")
+	set.Duration("interval", time.Millisecond, "doc")
+	set.Int("max-cycles", 1, "doc")
+	app, writer, _ := appWithTestWriters()
+	assert.Nil(t, command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil)))
+	assert.Equal(t, []*runner.ExpectedCommand{}, cb.ExpectedCommands)
+	assert.Equal(t, []error(nil), cb.Errors)
+	assert.True(t, strings.HasPrefix(writer.String(), "Repo|ID|Branch|SHA   |Result|Builds\nrep |1 |ref1  |newSHA|passed|build1:success\n"))
+	assert.Contains(t, writer.String(), "cycle 1: 1 rebased, 0 up to date, 0 skipped, 0 failed, next cycle in 1ms\n")
+}
+
+func TestCmdAutoRebaseWatchInvalidInterval(t *testing.T) {
+	ts := getAutoRebaseTestServer("")
+	defer ts.Close()
+	_, configFileName := getConfigWithAPIURLAndPath(t, ts.URL, "")
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	set.Bool("watch", true, "doc")
+	app, _, _ := appWithTestWriters()
+	err := command.CmdAutoRebase(&runner.Test{})(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Invalid interval: 0s (must be positive)")
+}
+
+// getWatchTestServer serves inner's responses with baseRef1 pointing to the next of targets on every request
+func getWatchTestServer(inner *httptest.Server, targets []string) *httptest.Server {
+	requests := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.String() == "/repos/own/rep/git/refs/heads/baseRef1" {
+			target := targets[len(targets)-1]
+			if requests < len(targets) {
+				target = targets[requests]
+			}
+
+			requests++
+			fmt.Fprintf(w, `{"ref":"refs/heads/baseRef1","object":{"sha":"%s"}}`, target)
+			return
+		}
+
+		inner.Config.Handler.ServeHTTP(w, r)
+	}))
+}
+
+func TestCmdAutoRebaseWaitForCI(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	var testCases = []struct {
+		name          string
+		statuses      string
+		checkRuns     string
+		ignoredBuilds []string
+		output        string
+	}{
+		{
+			"Passed",
+			`[{"context":"build1","state":"success"},{"context":"build1","state":"pending"}]`,
+			`{"check_runs":[]}`,
+			nil,
+			"Repo|ID|Branch|SHA   |Result|Builds\nrep |1 |ref1  |newSHA|passed|build1:success\n",
+		},
+		{
+			"Failed",
+			`[{"context":"build1","state":"success"},{"context":"build2","state":"failure"}]`,
+			`{"check_runs":[]}`,
+			nil,
+			"Repo|ID|Branch|SHA   |Result|Builds\nrep |1 |ref1  |newSHA|failed|build1:success,build2:failure\n",
+		},
+		{
+			"IgnoredBuild",
+			`[{"context":"build1","state":"success"},{"context":"build2","state":"failure"}]`,
+			`{"check_runs":[]}`,
+			[]string{"build2"},
+			"Repo|ID|Branch|SHA   |Result|Builds\nrep |1 |ref1  |newSHA|passed|build1:success\n",
+		},
+		{
+			"TimedOut",
+			`[{"context":"build1","state":"pending"}]`,
+			`{"check_runs":[]}`,
+			nil,
+			"Repo|ID|Branch|SHA   |Result   |Builds\nrep |1 |ref1  |newSHA|timed out|build1:pending\n",
+		},
+		{
+			"NoBuilds",
+			`[]`,
+			`{"check_runs":[]}`,
+			nil,
+			"Repo|ID|Branch|SHA   |Result   |Builds\nrep |1 |ref1  |newSHA|no builds|\n",
+		},
+		{
+			"CheckRuns",
+			`[{"context":"build1","state":"success"}]`,
+			`{"check_runs":[{"name":"lint","status":"completed","conclusion":"neutral"},{"name":"test","status":"completed","conclusion":"failure"}]}`,
+			nil,
+			"Repo|ID|Branch|SHA   |Result|Builds\nrep |1 |ref1  |newSHA|failed|build1:success,lint:neutral,test:failure\n",
+		},
+		{
+			"CheckRunInProgress",
+			`[]`,
+			`{"check_runs":[{"name":"test","status":"in_progress"}]}`,
+			nil,
+			"Repo|ID|Branch|SHA   |Result   |Builds\nrep |1 |ref1  |newSHA|timed out|test:pending\n",
+		},
+		{
+			"IgnoredCheckRun",
+			`[{"context":"build1","state":"success"}]`,
+			`{"check_runs":[{"name":"test","status":"completed","conclusion":"failure"}]}`,
+			[]string{"test"},
+			"Repo|ID|Branch|SHA   |Result|Builds\nrep |1 |ref1  |newSHA|passed|build1:success\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer removeFile(t, repoDir)
+			cb := &runner.Test{
+				ExpectedCommands: []*runner.ExpectedCommand{
+					runner.NewExpectedCommand(repoDir, "git remote -v", "origin\tlabelSSHURL (push)\nupstream\tbaseLabel1SSHURL (fetch)", 0),
+					runner.NewExpectedCommand(repoDir, "git diff-index --quiet HEAD", "", 0),
+					runner.NewExpectedCommand(repoDir, "git fetch origin", "", 0),
+					runner.NewExpectedCommand(repoDir, "git fetch upstream", "", 0),
+					runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "currentBranch", 0),
+					runner.NewExpectedCommand(repoDir, "git checkout -b prp-ref1", "", 0),
+					runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
+					runner.NewExpectedCommand(repoDir, "git rebase upstream/baseRef1", "", 0),
+					runner.NewExpectedCommand(repoDir, "git rev-parse origin/ref1 HEAD", "oldSHA\nnewSHA", 0),
+					runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force-with-lease=ref1:oldSHA", "", 0),
+					runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
+					runner.NewExpectedCommand(repoDir, "git branch -D prp-ref1", "", 0),
+				},
+			}
+			inner := getAutoRebaseTestServer("")
+			defer inner.Close()
+			ts := getOverridingTestServer(inner, map[string]string{
+				"/repos/own/rep/commits/newSHA/statuses?per_page=100":   tc.statuses,
+				"/repos/own/rep/commits/newSHA/check-runs?per_page=100": tc.checkRuns,
+			})
+			defer ts.Close()
+			writer := runAutoRebaseAgainstServer(t, ts, repoDir, cb, func(repo *config.Repo) {
+				repo.IgnoredBuilds = tc.ignoredBuilds
+			}, func(set *flag.FlagSet) {
+				set.Bool("wait-for-ci", true, "doc")
+			})
+			assert.Equal(t, tc.output, writer.String())
+		})
+	}
+}
+
+func TestCmdAutoRebaseWaitForCIThroughAPI(t *testing.T) {
+	inner := getAutoRebaseTestServer("")
+	defer inner.Close()
+	ts := getOverridingTestServer(inner, map[string]string{
+		"/repos/own/rep/pulls/1":                                `{"number":1,"head":{"sha":"apiSHA"}}`,
+		"/repos/own/rep/commits/apiSHA/statuses?per_page=100":   `[{"context":"build1","state":"success"}]`,
+		"/repos/own/rep/commits/apiSHA/check-runs?per_page=100": `{"check_runs":[]}`,
+	})
+	defer ts.Close()
+	writer := runAutoRebaseAgainstServer(t, ts, "", &runner.Test{ExpectedCommands: []*runner.ExpectedCommand{}}, func(*config.Repo) {}, func(set *flag.FlagSet) {
+		set.Bool("wait-for-ci", true, "doc")
+	})
+	assert.Equal(t, "Repo|ID|Branch|SHA   |Result|Builds\nrep |1 |ref1  |apiSHA|passed|build1:success\n", writer.String())
+}
+
+func TestCmdAutoRebaseWaitForCIPaginatesStatuses(t *testing.T) {
+	inner := getAutoRebaseTestServer("")
+	defer inner.Close()
+	overriding := getOverridingTestServer(inner, map[string]string{
+		"/repos/own/rep/pulls/1": `{"number":1,"head":{"sha":"apiSHA"}}`,
+		"/repos/own/rep/commits/apiSHA/statuses?page=2&per_page=100": `[{"context":"build2","state":"failure"}]`,
+		"/repos/own/rep/commits/apiSHA/check-runs?per_page=100":      `{"check_runs":[]}`,
+	})
+	defer overriding.Close()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.String() == "/repos/own/rep/commits/apiSHA/statuses?per_page=100" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/own/rep/commits/apiSHA/statuses?page=2&per_page=100>; rel="next"`, overriding.URL))
+			fmt.Fprint(w, `[{"context":"build1","state":"success"}]`)
+			return
+		}
+
+		overriding.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+	writer := runAutoRebaseAgainstServer(t, ts, "", &runner.Test{ExpectedCommands: []*runner.ExpectedCommand{}}, func(*config.Repo) {}, func(set *flag.FlagSet) {
+		set.Bool("wait-for-ci", true, "doc")
+	})
+	assert.Equal(t, "Repo|ID|Branch|SHA   |Result|Builds\nrep |1 |ref1  |apiSHA|failed|build1:success,build2:failure\n", writer.String())
+}
+
+// getOverridingTestServer serves responses for the URLs it contains and inner's responses for everything else
+func getOverridingTestServer(inner *httptest.Server, responses map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if response, ok := responses[r.URL.String()]; ok {
+			fmt.Fprint(w, response)
+			return
+		}
+
+		inner.Config.Handler.ServeHTTP(w, r)
+	}))
+}
+
+func runAutoRebaseAgainstServer(t *testing.T, ts *httptest.Server, repoDir string, cb *runner.Test, modifyRepo func(*config.Repo), setFlags func(*flag.FlagSet)) *bytes.Buffer {
+	t.Helper()
+	if repoDir != "" {
+		assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/.git", repoDir), 0777))
+	}
+
+	conf, configFileName := getConfigWithAPIURLAndPath(t, ts.URL, repoDir)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	modifyRepo(&conf.Profiles["foo"].TrackedRepos[1])
+	assert.Nil(t, conf.Write(configFileName))
+	set := getBaseFlagSet(configFileName)
+	setFlags(set)
+	app, writer, _ := appWithTestWriters()
+	assert.Nil(t, command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil)))
+	assert.Equal(t, []*runner.ExpectedCommand{}, cb.ExpectedCommands)
+	assert.Equal(t, []error(nil), cb.Errors)
+	return writer
+}
+
+func TestCmdAutoRebaseSignCommits(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	verify := regexp.QuoteMeta("git log --format=%H %G? upstream/baseRef1..HEAD")
+	var testCases = []struct {
+		name           string
+		updateStrategy string
+		signatures     string
+		output         string
+		expectedError  bool
+	}{
+		{"Rebase", "", "sha2 G\nsha1 U", "Verifying the signatures of upstream/baseRef1..HEAD\n", false},
+		{"Merge", config.UpdateStrategyMerge, "sha2 G\nsha1 G", "Verifying the signatures of upstream/baseRef1..HEAD\n", false},
+		{"Unsigned", "", "sha2 G\nsha1 N", "Could not rebase PR #1 in own/rep because: Refusing to push commit sha1 because it is not signed\n", true},
+		{"BadSignature", "", "sha2 B", "Could not rebase PR #1 in own/rep because: Refusing to push commit sha2 because its signature is bad\n", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer removeFile(t, repoDir)
+			update := runner.NewExpectedCommand(repoDir, "git rebase --gpg-sign upstream/baseRef1", "", 0)
+			push := runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force-with-lease=ref1:oldSHA", "", 0)
+			if tc.updateStrategy == config.UpdateStrategyMerge {
+				update = runner.NewExpectedCommand(repoDir, "git merge --no-edit --gpg-sign upstream/baseRef1", "", 0)
+				push = runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1", "", 0)
+			}
+
+			expectedCommands := []*runner.ExpectedCommand{
+				runner.NewExpectedCommand(repoDir, "git remote -v", "origin\tlabelSSHURL (push)\nupstream\tbaseLabel1SSHURL (fetch)", 0),
+				runner.NewExpectedCommand(repoDir, "git diff-index --quiet HEAD", "", 0),
+				runner.NewExpectedCommand(repoDir, "git fetch origin", "", 0),
+				runner.NewExpectedCommand(repoDir, "git fetch upstream", "", 0),
+				runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "currentBranch", 0),
+				runner.NewExpectedCommand(repoDir, "git checkout -b prp-ref1", "", 0),
+				runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
+				update,
+				runner.NewExpectedCommand(repoDir, "git rev-parse origin/ref1 HEAD", "oldSHA\nnewSHA", 0),
+				runner.NewExpectedCommand(repoDir, verify, tc.signatures, 0),
+			}
+			if !tc.expectedError {
+				expectedCommands = append(expectedCommands, push)
+			}
+
+			expectedCommands = append(
+				expectedCommands,
+				runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
+				runner.NewExpectedCommand(repoDir, "git branch -D prp-ref1", "", 0),
+			)
+			cb := &runner.Test{ExpectedCommands: expectedCommands}
+			writer := runBaseCommandWithRepo(t, repoDir, cb, true, tc.expectedError, func(repo *config.Repo) {
+				repo.SignCommits = true
+				repo.UpdateStrategy = tc.updateStrategy
+			})
+			assert.Contains(t, writer.String(), tc.output)
+		})
+	}
+}
+
+func TestCmdAutoRebaseContinueSignCommits(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	worktree := fmt.Sprintf("%s-prp-1", repoDir)
+	_, conflictsConfigFileName := writeConflictsAndConfig(t, repoDir, worktree)
+	removeFile(t, conflictsConfigFileName)
+	defer removeFile(t, repoDir)
+	conf, configFileName := getConfigWithAPIURLAndPath(t, "", repoDir)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	conf.Profiles["foo"].TrackedRepos[1].SignCommits = true
+	assert.Nil(t, conf.Write(configFileName))
+	set := getBaseFlagSet(configFileName)
+	set.Bool("continue", true, "doc")
+	app, _, writer := appWithTestWriters()
+	cb := &runner.Test{
+		ExpectedCommands: []*runner.ExpectedCommand{
+			runner.NewExpectedCommand(repoDir, "git rev-parse --git-dir", ".git", 0),
+			runner.NewExpectedCommand(worktree, "git -c core.editor=true rebase --continue", "", 0),
+			runner.NewExpectedCommand(worktree, "git rev-parse origin/ref1 HEAD", "oldSHA\nnewSHA", 0),
+			runner.NewExpectedCommand(worktree, regexp.QuoteMeta("git log --format=%H %G? upstream/baseRef1..HEAD"), "sha1 N", 0),
+		},
+	}
+	err := command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Unable to continue all rebases")
+	assert.Equal(t, []*runner.ExpectedCommand{}, cb.ExpectedCommands)
+	assert.Equal(t, []error(nil), cb.Errors)
+	assert.Equal(t, "Could not continue rebase of PR #1 in own/rep because: Refusing to push commit sha1 because it is not signed\n", writer.String())
+}
+
 func TestCmdAutoRebasePullRequestNumber(t *testing.T) {
 	ts := getAutoRebaseTestServer("")
 	defer ts.Close()
@@ -729,7 +2752,7 @@ func TestCmdAutoRebaseUsage(t *testing.T) {
 	app := cli.NewApp()
 	cb := &runner.Test{}
 	err := command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil))
-	assert.EqualError(t, err, "Usage: \"prp auto-rebase\"")
+	assert.EqualError(t, err, "Usage: \"prp auto-rebase [undo]\"")
 }
 
 func TestCmdAutoRebaseNoConfig(t *testing.T) {
@@ -745,12 +2768,88 @@ func TestCmdAutoRebaseNoPath(t *testing.T) {
 	defer ts.Close()
 	_, configFileName := getConfigWithAPIURL(t, ts.URL)
 	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	set := getBaseFlagSet(configFileName)
+	set.Bool("verbose", true, "doc")
+	app, _, writer := appWithTestWriters()
+	cb := &runner.Test{}
+	assert.Nil(t, command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil)))
+	assert.Equal(t, []error(nil), cb.Errors)
+	assert.Equal(t, "Requesting a branch rebase from the API\n", writer.String())
+	entries := readJournal(t, configFileName)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "updated", entries[0]["outcome"])
+	assert.Equal(t, "sha1", entries[0]["oldSha"])
+}
+
+func TestCmdAutoRebaseNoPathMergeStrategy(t *testing.T) {
+	ts := getAutoRebaseTestServer("")
+	defer ts.Close()
+	conf, configFileName := getConfigWithAPIURL(t, ts.URL)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	conf.Profiles["foo"].TrackedRepos[1].UpdateStrategy = "merge"
+	assert.Nil(t, conf.Write(configFileName))
+	set := getBaseFlagSet(configFileName)
+	set.Bool("verbose", true, "doc")
+	app, _, writer := appWithTestWriters()
+	cb := &runner.Test{}
+	assert.Nil(t, command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil)))
+	assert.Equal(t, []error(nil), cb.Errors)
+	assert.Equal(t, "Requesting a branch update from the API\n", writer.String())
+}
+
+func TestCmdAutoRebaseNoPathGraphQLFailure(t *testing.T) {
+	ts := getAutoRebaseTestServer("/graphql")
+	defer ts.Close()
+	_, configFileName := getConfigWithAPIURL(t, ts.URL)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	set := getBaseFlagSet(configFileName)
+	app, _, writer := appWithTestWriters()
+	cb := &runner.Test{}
+	err := command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Unable to rebase all pull requests")
+	assert.Equal(
+		t,
+		fmt.Sprintf("Could not rebase PR #1 in own/rep because: Unable to rebase branch through the API\nPOST %s/graphql: 500  []\n", ts.URL),
+		writer.String(),
+	)
+}
+
+func TestCmdAutoRebaseNoPathGraphQLError(t *testing.T) {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.String() == "/graphql" {
+			fmt.Fprint(w, `{"errors":[{"message":"Resource not accessible"}]}`)
+			return
+		}
+
+		response := handleUserRequest(r, "guy")
+		if response == nil {
+			response = handlePullRequestRequests(r, w, ts)
+		}
+
+		if response == nil {
+			response = handleCommitsComparisonRequests(r, w, ts)
+		}
+
+		if response == nil {
+			panic(r.URL.String())
+		}
+
+		fmt.Fprint(w, *response)
+	}))
+	defer ts.Close()
+	_, configFileName := getConfigWithAPIURL(t, ts.URL)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
 	set := getBaseFlagSet(configFileName)
 	app, _, writer := appWithTestWriters()
 	cb := &runner.Test{}
 	err := command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil))
 	assert.EqualError(t, err, "Unable to rebase all pull requests")
-	assert.Equal(t, "Could not rebase PR #1 in own/rep because: Path was not set for repo: own/rep\n", writer.String())
+	assert.Equal(t, "Could not rebase PR #1 in own/rep because: Unable to rebase branch through the API\nResource not accessible\n", writer.String())
 }
 
 func TestCmdAutoRebaseInvalidPath(t *testing.T) {
@@ -759,6 +2858,7 @@ func TestCmdAutoRebaseInvalidPath(t *testing.T) {
 	defer ts.Close()
 	_, configFileName := getConfigWithAPIURLAndPath(t, ts.URL, repoDir)
 	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
 	set := getBaseFlagSet(configFileName)
 	app, _, writer := appWithTestWriters()
 	cb := &runner.Test{}
@@ -775,6 +2875,7 @@ func TestCmdAutoRebaseNonGitPath(t *testing.T) {
 	defer ts.Close()
 	_, configFileName := getConfigWithAPIURLAndPath(t, ts.URL, repoDir)
 	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
 	set := getBaseFlagSet(configFileName)
 	app, _, writer := appWithTestWriters()
 	cb := &runner.Test{}
@@ -885,6 +2986,23 @@ func getAutoRebaseTestServer(failureURL string) *httptest.Server {
 			return
 		}
 
+		handlers := []func(*http.Request, http.ResponseWriter, *httptest.Server) *string{
+			handleUpdateBranchRequests,
+			handleGraphQLRequests,
+			handleCommentRequests,
+			handleReviewRequests,
+			handleLabelRequests,
+			handleStatusRequests,
+		}
+
+		for _, handler := range handlers {
+			response = handler(r, w, server)
+			if response != nil {
+				fmt.Fprint(w, *response)
+				return
+			}
+		}
+
 		panic(r.URL.String())
 	}))
 
@@ -892,11 +3010,103 @@ func getAutoRebaseTestServer(failureURL string) *httptest.Server {
 }
 
 func runBaseCommand(t *testing.T, repoDir string, cb *runner.Test, verbose, expectedError bool) *bytes.Buffer {
+	t.Helper()
+	return runBaseCommandWithRepo(t, repoDir, cb, verbose, expectedError, func(*config.Repo) {})
+}
+
+func TestCmdAutoRebaseNativeGit(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	var testCases = []struct {
+		name             string
+		head             string
+		expectedCommands []*runner.ExpectedCommand
+		currentBranch    string
+		output           string
+	}{
+		{
+			"Native",
+			"ref: refs/heads/currentBranch\n",
+			[]*runner.ExpectedCommand{},
+			"currentBranch",
+			"",
+		},
+		{
+			"DetachedHead",
+			"sha1\n",
+			[]*runner.ExpectedCommand{},
+			"sha1",
+			"",
+		},
+		{
+			"Fallback",
+			"ref: refs/remotes/origin/currentBranch\n",
+			[]*runner.ExpectedCommand{
+				runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "refs/heads/currentBranch", 0),
+			},
+			"currentBranch",
+			fmt.Sprintf("Unable to read HEAD in %s, falling back to git\n", repoDir),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer removeFile(t, repoDir)
+			assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/.git", repoDir), 0777))
+			assert.Nil(t, ioutil.WriteFile(fmt.Sprintf("%s/.git/HEAD", repoDir), []byte(tc.head), 0644))
+			expectedCommands := []*runner.ExpectedCommand{
+				runner.NewExpectedCommand(repoDir, "git remote -v", "origin\tlabelSSHURL (push)\nupstream\tbaseLabel1SSHURL (fetch)", 0),
+				runner.NewExpectedCommand(repoDir, "git diff-index --quiet HEAD", "", 0),
+				runner.NewExpectedCommand(repoDir, "git fetch origin", "", 0),
+				runner.NewExpectedCommand(repoDir, "git fetch upstream", "", 0),
+			}
+			expectedCommands = append(expectedCommands, tc.expectedCommands...)
+			expectedCommands = append(
+				expectedCommands,
+				runner.NewExpectedCommand(repoDir, "git checkout -b prp-ref1", "", 0),
+				runner.NewExpectedCommand(repoDir, "git reset --hard origin/ref1", "", 0),
+				runner.NewExpectedCommand(repoDir, "git rebase upstream/baseRef1", "", 0),
+				runner.NewExpectedCommand(repoDir, "git rev-parse origin/ref1 HEAD", "oldSHA\nnewSHA", 0),
+				runner.NewExpectedCommand(repoDir, "git push origin prp-ref1:ref1 --force-with-lease=ref1:oldSHA", "", 0),
+				runner.NewExpectedCommand(repoDir, fmt.Sprintf("git checkout %s", tc.currentBranch), "", 0),
+				runner.NewExpectedCommand(repoDir, "git branch -D prp-ref1", "", 0),
+			)
+			cb := &runner.Test{ExpectedCommands: expectedCommands}
+			ts := getAutoRebaseTestServer("")
+			defer ts.Close()
+			_, configFileName := getConfigWithAPIURLAndPath(t, ts.URL, repoDir)
+			defer removeFile(t, configFileName)
+			defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+			set := getBaseFlagSet(configFileName)
+			set.Bool("verbose", true, "doc")
+			set.String("git-backend", "native", "doc")
+			app, _, writer := appWithTestWriters()
+			assert.Nil(t, command.CmdAutoRebase(cb)(cli.NewContext(app, set, nil)))
+			assert.Equal(t, []*runner.ExpectedCommand{}, cb.ExpectedCommands)
+			assert.Equal(t, []error(nil), cb.Errors)
+			assert.Contains(t, writer.String(), tc.output)
+		})
+	}
+}
+
+func TestCmdAutoRebaseInvalidGitBackend(t *testing.T) {
+	_, configFileName := getConfigWithAPIURLAndPath(t, "", "")
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	set.String("git-backend", "go-git", "doc")
+	app, _, _ := appWithTestWriters()
+	err := command.CmdAutoRebase(&runner.Test{})(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Invalid git backend: go-git (must be cli or native)")
+}
+
+func runBaseCommandWithRepo(t *testing.T, repoDir string, cb *runner.Test, verbose, expectedError bool, modifyRepo func(*config.Repo)) *bytes.Buffer {
 	t.Helper()
 	assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/.git", repoDir), 0777))
 	ts := getAutoRebaseTestServer("")
-	_, configFileName := getConfigWithAPIURLAndPath(t, ts.URL, repoDir)
+	conf, configFileName := getConfigWithAPIURLAndPath(t, ts.URL, repoDir)
 	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	modifyRepo(&conf.Profiles["foo"].TrackedRepos[1])
+	assert.Nil(t, conf.Write(configFileName))
 	set := getBaseFlagSet(configFileName)
 	set.Bool("verbose", verbose, "doc")
 	app, _, writer := appWithTestWriters()
@@ -0,0 +1,75 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// autosquash redoes a successful rebase with --autosquash and refuses the result if squashing changed the tree
+func (r rebaser) autosquash(path, ownedRemote string, rebaseCommand []string, pr *pullRequest) error {
+	myRemoteBranch := fmt.Sprintf("%s/%s", ownedRemote, pr.Branch)
+	base := rebaseCommand[len(rebaseCommand)-1]
+	fixups, err := r.countFixups(path, fmt.Sprintf("%s..%s", base, myRemoteBranch))
+	if err != nil {
+		return err
+	}
+
+	if fixups == 0 {
+		fmt.Fprintln(r.verboseWriter, "No fixup commits to squash")
+		return nil
+	}
+
+	rebasedTree, err := r.getTree(path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(r.verboseWriter, "Squashing %d fixup commits\n", fixups)
+	err = r.runCommand(path, "git", "reset", "--hard", myRemoteBranch)
+	if err != nil {
+		return wrapExitError(err, fmt.Sprintf("Unable to reset the code to %s", myRemoteBranch))
+	}
+
+	autosquashCommand := append([]string{"git", "-c", "sequence.editor=:", "rebase", "-i", "--autosquash"}, rebaseCommand[2:]...)
+	err = r.runCommand(path, autosquashCommand...)
+	if err != nil {
+		r.abort(path, "rebase", pr)
+		return wrapExitError(err, "Unable to squash the fixup commits, they may conflict")
+	}
+
+	squashedTree, err := r.getTree(path)
+	if err != nil {
+		return err
+	}
+
+	if squashedTree != rebasedTree {
+		return fmt.Errorf("Squashing the fixup commits would change the result of the rebase (%s instead of %s)", squashedTree, rebasedTree)
+	}
+
+	fmt.Fprintf(r.verboseWriter, "Folded %d fixup commits into PR #%d\n", fixups, pr.PullRequestID)
+	return nil
+}
+
+func (r rebaser) countFixups(path, revisionRange string) (int, error) {
+	output, err := r.cmdWrapper.New(path, "git", "rev-list", "--count", "--grep=^fixup!", "--grep=^squash!", revisionRange).Output()
+	if err != nil {
+		return 0, wrapExitError(err, fmt.Sprintf("Unable to count the fixup commits in %s", revisionRange))
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(string(output)))
+	if err != nil {
+		return 0, fmt.Errorf("Unable to count the fixup commits in %s\n%v", revisionRange, err)
+	}
+
+	return count, nil
+}
+
+func (r rebaser) getTree(path string) (string, error) {
+	output, err := r.cmdWrapper.New(path, "git", "log", "-1", "--format=%T").Output()
+	if err != nil {
+		return "", wrapExitError(err, fmt.Sprintf("Unable to read the tree of HEAD in %s", path))
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,245 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// ciPollInterval is how long buildWaiter waits between checks of a pull request's builds
+const ciPollInterval = 15 * time.Second
+
+// ciGracePeriod is how long buildWaiter waits for the first build of a new head before deciding it has no builds
+const ciGracePeriod = 2 * time.Minute
+
+// Build results
+const (
+	buildPassed   = "passed"
+	buildFailed   = "failed"
+	buildPending  = "pending"
+	buildTimedOut = "timed out"
+	buildNoBuilds = "no builds"
+)
+
+// buildWaiter waits for the builds of rebased pull requests to finish
+type buildWaiter struct {
+	verboseWriter io.Writer
+	timeout       time.Duration
+}
+
+type buildResult struct {
+	pr       *pullRequest
+	sha      string
+	result   string
+	contexts map[string]string
+}
+
+func newBuildWaiter(verboseWriter io.Writer, timeout time.Duration) *buildWaiter {
+	return &buildWaiter{
+		verboseWriter: verboseWriter,
+		timeout:       timeout,
+	}
+}
+
+func (b buildWaiter) waitForBuilds(prs []*pullRequest, writer io.Writer) {
+	if len(prs) == 0 {
+		return
+	}
+
+	deadline := time.Now().Add(b.timeout)
+	results := make([]buildResult, len(prs))
+	wg := sync.WaitGroup{}
+	for i, pr := range prs {
+		wg.Add(1)
+		go func(i int, pr *pullRequest) {
+			results[i] = b.waitForBuild(pr, deadline)
+			wg.Done()
+		}(i, pr)
+	}
+
+	wg.Wait()
+	printBuildResults(results, writer)
+}
+
+func (b buildWaiter) waitForBuild(pr *pullRequest, deadline time.Time) buildResult {
+	fmt.Fprintf(b.verboseWriter, "Waiting for the builds of PR #%d in %s/%s\n", pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name)
+	result := buildResult{pr: pr, result: buildPending}
+	start := time.Now()
+	for {
+		if pr.pushedSHA == "" {
+			// Branches updated through the API change asynchronously
+			b.resolvePushedSHA(pr)
+		}
+
+		if pr.pushedSHA != "" {
+			result.sha = pr.pushedSHA
+			contexts, err := pr.getLatestStatuses(pr.pushedSHA)
+			if err != nil {
+				fmt.Fprintf(b.verboseWriter, "Unable to get the builds of PR #%d in %s/%s\n%v\n", pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, err)
+			} else {
+				result.contexts = contexts
+				result.result = conclusion(contexts)
+			}
+		}
+
+		if result.result != buildPending {
+			return result
+		}
+
+		if result.sha != "" && len(result.contexts) == 0 && time.Since(start) >= ciGracePeriod {
+			result.result = buildNoBuilds
+			return result
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			result.result = buildTimedOut
+			if result.sha != "" && len(result.contexts) == 0 {
+				result.result = buildNoBuilds
+			}
+
+			return result
+		}
+
+		if remaining > ciPollInterval {
+			remaining = ciPollInterval
+		}
+
+		time.Sleep(remaining)
+	}
+}
+
+func (b buildWaiter) resolvePushedSHA(pr *pullRequest) {
+	githubPR, _, err := pr.client.PullRequests.Get(context.Background(), pr.Repo.Owner, pr.Repo.Name, pr.PullRequestID)
+	if err != nil {
+		fmt.Fprintf(b.verboseWriter, "Unable to get the head of PR #%d in %s/%s\n%v\n", pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, err)
+		return
+	}
+
+	if githubPR.Head.GetSHA() != pr.SHA {
+		pr.pushedSHA = githubPR.Head.GetSHA()
+	}
+}
+
+// getLatestStatuses returns the most recent state of every build of sha that isn't ignored
+// Check runs are included with their conclusion as their state, or pending until they complete
+func (pr *pullRequest) getLatestStatuses(sha string) (map[string]string, error) {
+	contexts, err := pr.getLatestCommitStatuses(sha)
+	if err != nil {
+		return nil, err
+	}
+
+	runs, err := listCheckRuns(pr.client, pr.Repo.Owner, pr.Repo.Name, sha)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, run := range runs {
+		if _, ok := contexts[run.Name]; ok || pr.contextIsIgnored(run.Name) {
+			continue
+		}
+
+		contexts[run.Name] = "pending"
+		if run.Status == "completed" {
+			contexts[run.Name] = run.Conclusion
+		}
+	}
+
+	return contexts, nil
+}
+
+// getLatestCommitStatuses returns the most recent state of every commit status of sha that isn't ignored
+func (pr *pullRequest) getLatestCommitStatuses(sha string) (map[string]string, error) {
+	contexts := make(map[string]string)
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		statuses, resp, err := pr.client.Repositories.ListStatuses(context.Background(), pr.Repo.Owner, pr.Repo.Name, sha, opt)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, status := range statuses {
+			if pr.buildIsIgnored(status) {
+				continue
+			}
+
+			// Statuses are listed newest first
+			if _, ok := contexts[status.GetContext()]; !ok {
+				contexts[status.GetContext()] = status.GetState()
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opt.Page = resp.NextPage
+	}
+
+	return contexts, nil
+}
+
+func conclusion(contexts map[string]string) string {
+	if len(contexts) == 0 {
+		return buildPending
+	}
+
+	result := buildPassed
+	for _, state := range contexts {
+		if state == "pending" {
+			return buildPending
+		}
+
+		if !statePassed(state) {
+			result = buildFailed
+		}
+	}
+
+	return result
+}
+
+// statePassed returns whether state is the state of a commit status or the conclusion of a check run that passed
+func statePassed(state string) bool {
+	return state == "success" || state == "neutral" || state == "skipped"
+}
+
+func printBuildResults(results []buildResult, writer io.Writer) {
+	buffer := &bytes.Buffer{}
+	tabW := tabwriter.NewWriter(buffer, 0, 0, 0, ' ', tabwriter.Debug)
+	fmt.Fprintln(tabW, "Repo\tID\tBranch\tSHA\tResult\tBuilds")
+	for _, result := range results {
+		contexts := make([]string, 0, len(result.contexts))
+		for context := range result.contexts {
+			contexts = append(contexts, context)
+		}
+
+		sort.Strings(contexts)
+		builds := make([]string, 0, len(contexts))
+		for _, context := range contexts {
+			builds = append(builds, fmt.Sprintf("%s:%s", context, result.contexts[context]))
+		}
+
+		fmt.Fprintf(
+			tabW,
+			"%s\t%s\t%s\t%s\t%s\t%s\n",
+			result.pr.Repo.Name,
+			strconv.Itoa(result.pr.PullRequestID),
+			result.pr.Branch,
+			result.sha,
+			result.result,
+			strings.Join(builds, ","),
+		)
+	}
+
+	_ = tabW.Flush()
+	fmt.Fprint(writer, buffer.String())
+}
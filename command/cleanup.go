@@ -0,0 +1,336 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/guywithnose/runner"
+	"github.com/urfave/cli"
+)
+
+// staleBranch is a branch in a local clone, or on its remote for the user's fork, whose pull requests are all merged or closed
+// keepReason explains why it is left alone when its tip has commits that its pull request doesn't
+type staleBranch struct {
+	repo       *config.Repo
+	remote     string
+	name       string
+	reason     string
+	keepReason string
+}
+
+func (b staleBranch) String() string {
+	if b.remote == "" {
+		return b.name
+	}
+
+	return fmt.Sprintf("%s/%s", b.remote, b.name)
+}
+
+// branchCleaner finds and deletes the branches of merged and closed pull requests in local clones
+type branchCleaner struct {
+	cmdWrapper runner.Builder
+	git        gitBackend
+	client     *github.Client
+	user       *github.User
+	// pullRequests caches the pull requests opened from each head so a branch and its prp- temp branch share a lookup
+	pullRequests map[string][]*githubPullRequest
+}
+
+// CmdCleanup deletes the local and remote branches of merged and closed pull requests
+func CmdCleanup(cmdWrapper runner.Builder) func(*cli.Context) error {
+	return func(c *cli.Context) error {
+		return cmdCleanupHelper(c, cmdWrapper)
+	}
+}
+
+func cmdCleanupHelper(c *cli.Context, cmdWrapper runner.Builder) error {
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() != 0 {
+		return cli.NewExitError("Usage: \"prp cleanup\"", 1)
+	}
+
+	profile := configData.Profiles[*profileName]
+	client, err := getGithubClient(&profile.Token, &profile.APIURL, false)
+	if err != nil {
+		return err
+	}
+
+	user, _, err := client.Users.Get(context.Background(), "")
+	if err != nil {
+		return err
+	}
+
+	nativeGit, err := useNativeGit(c.String("git-backend"))
+	if err != nil {
+		return err
+	}
+
+	cleaner := &branchCleaner{
+		cmdWrapper:   cmdWrapper,
+		git:          newGitBackend(nativeGit, cmdWrapper, ioutil.Discard),
+		client:       client,
+		user:         user,
+		pullRequests: make(map[string][]*githubPullRequest),
+	}
+
+	var completeError error
+	branches := []staleBranch{}
+	repos := c.StringSlice("repo")
+	for i := range profile.TrackedRepos {
+		repo := &profile.TrackedRepos[i]
+		fullName := fmt.Sprintf("%s/%s", repo.Owner, repo.Name)
+		if repo.LocalPath == "" || (len(repos) != 0 && !stringSliceContains(fullName, repos)) {
+			continue
+		}
+
+		repoBranches, err := cleaner.findStaleBranches(repo)
+		if err != nil {
+			fmt.Fprintf(c.App.ErrWriter, "Could not look for branches to clean up in %s because: %v\n", fullName, err)
+			completeError = cli.NewExitError("Unable to clean up all repos", 1)
+			continue
+		}
+
+		for _, branch := range repoBranches {
+			if branch.keepReason != "" {
+				fmt.Fprintf(c.App.Writer, "Kept %s in %s because %s\n", branch, branch.repo.LocalPath, branch.keepReason)
+				continue
+			}
+
+			branches = append(branches, branch)
+		}
+	}
+
+	if len(branches) == 0 {
+		fmt.Fprintln(c.App.Writer, "No branches need to be cleaned up")
+		return completeError
+	}
+
+	fmt.Fprintln(c.App.Writer, "These branches will be deleted:")
+	var lastRepo *config.Repo
+	for _, branch := range branches {
+		if branch.repo != lastRepo {
+			fmt.Fprintf(c.App.Writer, "  %s/%s in %s\n", branch.repo.Owner, branch.repo.Name, branch.repo.LocalPath)
+			lastRepo = branch.repo
+		}
+
+		fmt.Fprintf(c.App.Writer, "    %s: %s\n", branch, branch.reason)
+	}
+
+	if !c.Bool("yes") && !confirm(os.Stdin, c.App.Writer, "Continue?") {
+		return completeError
+	}
+
+	for _, branch := range branches {
+		err = cleaner.delete(branch)
+		if err != nil {
+			fmt.Fprintf(c.App.ErrWriter, "Could not delete %s in %s because: %v\n", branch, branch.repo.LocalPath, err)
+			completeError = cli.NewExitError("Unable to clean up all repos", 1)
+			continue
+		}
+
+		fmt.Fprintf(c.App.Writer, "Deleted %s in %s\n", branch, branch.repo.LocalPath)
+	}
+
+	return completeError
+}
+
+// findStaleBranches lists the local branches of repo's clone and the branches on the remote for the user's fork whose pull requests are all merged or closed
+// The checked out branch and the default branches are never included, and branches whose tips aren't part of their pull request's head are marked to be kept
+func (b *branchCleaner) findStaleBranches(repo *config.Repo) ([]staleBranch, error) {
+	currentBranch, err := b.git.currentBranch(repo.LocalPath)
+	if err != nil {
+		return nil, err
+	}
+
+	upstream, _, err := b.client.Repositories.Get(context.Background(), repo.Owner, repo.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	keep := []string{currentBranch, upstream.GetDefaultBranch()}
+	branches := []staleBranch{}
+	localBranches, err := b.listBranches(repo.LocalPath, "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, branch := range localBranches {
+		if stringSliceContains(branch, keep) {
+			continue
+		}
+
+		// auto-rebase rebases onto prp-{branch} temp branches that are left behind when it fails
+		pr, err := b.stalePullRequest(repo, strings.TrimPrefix(branch, "prp-"))
+		if err != nil {
+			return nil, err
+		}
+
+		if pr != nil {
+			branches = append(branches, b.newStaleBranch(repo, "", branch, pr))
+		}
+	}
+
+	ownedRemote, err := b.getOwnedRemote(repo, upstream)
+	if err != nil || ownedRemote == "" {
+		return branches, err
+	}
+
+	err = b.runCommand(repo.LocalPath, "git", "fetch", "--prune", ownedRemote)
+	if err != nil {
+		return nil, wrapExitError(err, fmt.Sprintf("Unable to fetch %s", ownedRemote))
+	}
+
+	remoteBranches, err := b.listBranches(repo.LocalPath, fmt.Sprintf("refs/remotes/%s", ownedRemote))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, branch := range remoteBranches {
+		branch = strings.TrimPrefix(branch, fmt.Sprintf("%s/", ownedRemote))
+		if branch == "HEAD" || branch == ownedRemote || stringSliceContains(branch, keep) {
+			continue
+		}
+
+		pr, err := b.stalePullRequest(repo, branch)
+		if err != nil {
+			return nil, err
+		}
+
+		if pr != nil {
+			branches = append(branches, b.newStaleBranch(repo, ownedRemote, branch, pr))
+		}
+	}
+
+	return branches, nil
+}
+
+// getOwnedRemote returns the remote of repo's clone that pushes to the user's fork of upstream, or to upstream if the user owns it
+// It returns "" when there is no such remote
+func (b *branchCleaner) getOwnedRemote(repo *config.Repo, upstream *github.Repository) (string, error) {
+	owned := upstream
+	if repo.Owner != b.user.GetLogin() {
+		var response *github.Response
+		var err error
+		owned, response, err = b.client.Repositories.Get(context.Background(), b.user.GetLogin(), upstream.GetName())
+		if response != nil && response.StatusCode == http.StatusNotFound {
+			return "", nil
+		}
+
+		if err != nil {
+			return "", err
+		}
+	}
+
+	remotes, err := b.git.remotes(repo.LocalPath)
+	if err != nil {
+		return "", err
+	}
+
+	return remotes[fmt.Sprintf("%s (push)", owned.GetSSHURL())], nil
+}
+
+// stalePullRequest returns the newest pull request of branch, or nil if it has no pull requests or any of them is open
+func (b *branchCleaner) stalePullRequest(repo *config.Repo, branch string) (*githubPullRequest, error) {
+	head := fmt.Sprintf("%s:%s", b.user.GetLogin(), branch)
+	key := fmt.Sprintf("%s/%s %s", repo.Owner, repo.Name, head)
+	prs, ok := b.pullRequests[key]
+	if !ok {
+		var err error
+		prs, _, err = listPullRequests(b.client, repo.Owner, repo.Name, &github.PullRequestListOptions{Head: head, State: "all"})
+		if err != nil {
+			return nil, err
+		}
+
+		b.pullRequests[key] = prs
+	}
+
+	if len(prs) == 0 {
+		return nil, nil
+	}
+
+	for _, pr := range prs {
+		if pr.GetState() == "open" {
+			return nil, nil
+		}
+	}
+
+	// The newest pull request is listed first
+	return prs[0], nil
+}
+
+// newStaleBranch describes branch, which pr made stale
+// A branch whose tip isn't pr's head or one of its ancestors has commits that would be lost, so it is kept
+func (b *branchCleaner) newStaleBranch(repo *config.Repo, remote, branch string, pr *githubPullRequest) staleBranch {
+	stale := staleBranch{repo: repo, remote: remote, name: branch}
+	stale.reason = fmt.Sprintf("PR #%d was closed", pr.GetNumber())
+	if pr.MergedAt != nil {
+		stale.reason = fmt.Sprintf("PR #%d was merged", pr.GetNumber())
+	}
+
+	// The prp- temp branches of auto-rebase hold rebased copies of the pull request's commits, which are never its ancestors
+	if remote == "" && strings.HasPrefix(branch, "prp-") {
+		return stale
+	}
+
+	err := b.runCommand(repo.LocalPath, "git", "merge-base", "--is-ancestor", stale.String(), pr.Head.GetSHA())
+	if err != nil {
+		stale.keepReason = fmt.Sprintf("it has commits that PR #%d doesn't", pr.GetNumber())
+	}
+
+	return stale
+}
+
+func (b *branchCleaner) listBranches(path, refs string) ([]string, error) {
+	output, err := b.output(path, "git", "for-each-ref", "--format=%(refname:short)", refs)
+	if err != nil {
+		return nil, wrapExitError(err, fmt.Sprintf("Unable to list the branches in %s", path))
+	}
+
+	return nonEmptyLines(output), nil
+}
+
+func (b *branchCleaner) delete(branch staleBranch) error {
+	if branch.remote == "" {
+		// newStaleBranch already kept the branches with commits their pull request doesn't have
+		return b.runCommand(branch.repo.LocalPath, "git", "branch", "-D", branch.name)
+	}
+
+	return b.runCommand(branch.repo.LocalPath, "git", "push", branch.remote, "--delete", branch.name)
+}
+
+func (b *branchCleaner) runCommand(path string, command ...string) error {
+	_, err := b.output(path, command...)
+	return err
+}
+
+func (b *branchCleaner) output(path string, command ...string) (string, error) {
+	cmd := b.cmdWrapper.New(path, command...)
+	output, err := cmd.Output()
+	return string(output), err
+}
+
+// CompleteCleanup handles bash autocompletion for the 'cleanup' command
+func CompleteCleanup(c *cli.Context) {
+	lastParam := os.Args[len(os.Args)-2]
+	if lastParam != "--repo" {
+		completeFlags(c, "cleanup")
+		return
+	}
+
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return
+	}
+
+	completeRepo(c.StringSlice("repo"), configData.Profiles[*profileName], c.App.Writer)
+}
@@ -0,0 +1,248 @@
+package command_test
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/guywithnose/pull-request-parser/command"
+	"github.com/guywithnose/runner"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+func TestCmdCleanup(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	cb := &runner.Test{ExpectedCommands: append(
+		getCleanupExpectedCommands(repoDir),
+		runner.NewExpectedCommand(repoDir, "git branch -D ref1", "", 0),
+		runner.NewExpectedCommand(repoDir, "git branch -D prp-ref1", "", 0),
+		runner.NewExpectedCommand(repoDir, "git branch -D old", "", 0),
+		runner.NewExpectedCommand(repoDir, "git push origin --delete ref1", "", 0),
+	)}
+	writer, errWriter, err := runCleanupAgainstServer(t, repoDir, cb, map[string]string{}, func(set *flag.FlagSet) {
+		set.Bool("yes", true, "doc")
+	})
+	assert.Nil(t, err)
+	assert.Equal(
+		t,
+		strings.Join(
+			[]string{
+				"These branches will be deleted:",
+				fmt.Sprintf("  own/rep in %s", repoDir),
+				"    ref1: PR #1 was merged",
+				"    prp-ref1: PR #1 was merged",
+				"    old: PR #5 was closed",
+				"    origin/ref1: PR #1 was merged",
+				fmt.Sprintf("Deleted ref1 in %s", repoDir),
+				fmt.Sprintf("Deleted prp-ref1 in %s", repoDir),
+				fmt.Sprintf("Deleted old in %s", repoDir),
+				fmt.Sprintf("Deleted origin/ref1 in %s", repoDir),
+				"",
+			},
+			"\n",
+		),
+		writer.String(),
+	)
+	assert.Equal(t, "", errWriter.String())
+}
+
+func TestCmdCleanupDeclined(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	cb := &runner.Test{ExpectedCommands: getCleanupExpectedCommands(repoDir)}
+	defer replaceStdin(t, "n\n")()
+	writer, _, err := runCleanupAgainstServer(t, repoDir, cb, map[string]string{}, func(*flag.FlagSet) {})
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(writer.String(), "    origin/ref1: PR #1 was merged\nContinue? [y/N] "))
+}
+
+func TestCmdCleanupNothingToDo(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	cb := &runner.Test{ExpectedCommands: []*runner.ExpectedCommand{
+		runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "refs/heads/wip", 0),
+		runner.NewExpectedCommand(repoDir, "git for-each-ref --format=%\\(refname:short\\) refs/heads", "master\nwip\nlocal-only", 0),
+		runner.NewExpectedCommand(repoDir, "git remote -v", "origin\tlabelSSHURL (push)\nupstream\tbaseLabel1SSHURL (fetch)", 0),
+		runner.NewExpectedCommand(repoDir, "git fetch --prune origin", "", 0),
+		runner.NewExpectedCommand(repoDir, "git for-each-ref --format=%\\(refname:short\\) refs/remotes/origin", "origin/master\norigin/wip", 0),
+	}}
+	writer, _, err := runCleanupAgainstServer(t, repoDir, cb, map[string]string{}, func(*flag.FlagSet) {})
+	assert.Nil(t, err)
+	assert.Equal(t, "No branches need to be cleaned up\n", writer.String())
+}
+
+func TestCmdCleanupRepoFilter(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	cb := &runner.Test{ExpectedCommands: []*runner.ExpectedCommand{}}
+	writer, _, err := runCleanupAgainstServer(t, repoDir, cb, map[string]string{}, func(set *flag.FlagSet) {
+		repos := cli.StringSlice{"foo/bar"}
+		set.Var(&repos, "repo", "doc")
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "No branches need to be cleaned up\n", writer.String())
+}
+
+func TestCmdCleanupFetchFailure(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	cb := &runner.Test{ExpectedCommands: []*runner.ExpectedCommand{
+		runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "refs/heads/current", 0),
+		runner.NewExpectedCommand(repoDir, "git for-each-ref --format=%\\(refname:short\\) refs/heads", "ref1", 0),
+		runner.NewExpectedCommand(repoDir, "git merge-base --is-ancestor ref1 sha1", "", 0),
+		runner.NewExpectedCommand(repoDir, "git remote -v", "origin\tlabelSSHURL (push)\nupstream\tbaseLabel1SSHURL (fetch)", 0),
+		runner.NewExpectedCommand(repoDir, "git fetch --prune origin", "", -1),
+	}}
+	writer, errWriter, err := runCleanupAgainstServer(t, repoDir, cb, map[string]string{}, func(*flag.FlagSet) {})
+	assert.EqualError(t, err, "Unable to clean up all repos")
+	assert.Equal(t, "No branches need to be cleaned up\n", writer.String())
+	assert.Equal(t, "Could not look for branches to clean up in own/rep because: Unable to fetch origin\n", errWriter.String())
+}
+
+func TestCmdCleanupDeleteFailure(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	cb := &runner.Test{ExpectedCommands: []*runner.ExpectedCommand{
+		runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "refs/heads/current", 0),
+		runner.NewExpectedCommand(repoDir, "git for-each-ref --format=%\\(refname:short\\) refs/heads", "old", 0),
+		runner.NewExpectedCommand(repoDir, "git merge-base --is-ancestor old sha5", "", 0),
+		runner.NewExpectedCommand(repoDir, "git remote -v", "", 0),
+		runner.NewExpectedCommand(repoDir, "git branch -D old", "", -1),
+	}}
+	writer, errWriter, err := runCleanupAgainstServer(t, repoDir, cb, map[string]string{}, func(set *flag.FlagSet) {
+		set.Bool("yes", true, "doc")
+	})
+	assert.EqualError(t, err, "Unable to clean up all repos")
+	assert.Equal(t, fmt.Sprintf("These branches will be deleted:\n  own/rep in %s\n    old: PR #5 was closed\n", repoDir), writer.String())
+	assert.Equal(t, fmt.Sprintf("Could not delete old in %s because: Error running command\n", repoDir), errWriter.String())
+}
+
+func TestCmdCleanupNewCommits(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	cb := &runner.Test{ExpectedCommands: []*runner.ExpectedCommand{
+		runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "refs/heads/current", 0),
+		runner.NewExpectedCommand(repoDir, "git for-each-ref --format=%\\(refname:short\\) refs/heads", "ref1\nprp-ref1\nold", 0),
+		runner.NewExpectedCommand(repoDir, "git merge-base --is-ancestor ref1 sha1", "", 1),
+		runner.NewExpectedCommand(repoDir, "git merge-base --is-ancestor old sha5", "", 0),
+		runner.NewExpectedCommand(repoDir, "git remote -v", "origin\tlabelSSHURL (push)\nupstream\tbaseLabel1SSHURL (fetch)", 0),
+		runner.NewExpectedCommand(repoDir, "git fetch --prune origin", "", 0),
+		runner.NewExpectedCommand(repoDir, "git for-each-ref --format=%\\(refname:short\\) refs/remotes/origin", "origin/ref1", 0),
+		runner.NewExpectedCommand(repoDir, "git merge-base --is-ancestor origin/ref1 sha1", "", 1),
+		runner.NewExpectedCommand(repoDir, "git branch -D prp-ref1", "", 0),
+		runner.NewExpectedCommand(repoDir, "git branch -D old", "", 0),
+	}}
+	writer, errWriter, err := runCleanupAgainstServer(t, repoDir, cb, map[string]string{}, func(set *flag.FlagSet) {
+		set.Bool("yes", true, "doc")
+	})
+	assert.Nil(t, err)
+	assert.Equal(
+		t,
+		strings.Join(
+			[]string{
+				fmt.Sprintf("Kept ref1 in %s because it has commits that PR #1 doesn't", repoDir),
+				fmt.Sprintf("Kept origin/ref1 in %s because it has commits that PR #1 doesn't", repoDir),
+				"These branches will be deleted:",
+				fmt.Sprintf("  own/rep in %s", repoDir),
+				"    prp-ref1: PR #1 was merged",
+				"    old: PR #5 was closed",
+				fmt.Sprintf("Deleted prp-ref1 in %s", repoDir),
+				fmt.Sprintf("Deleted old in %s", repoDir),
+				"",
+			},
+			"\n",
+		),
+		writer.String(),
+	)
+	assert.Equal(t, "", errWriter.String())
+}
+
+func TestCmdCleanupUsage(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"foo"}))
+	app, _, _ := appWithTestWriters()
+	err := command.CmdCleanup(&runner.Test{})(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Usage: \"prp cleanup\"")
+}
+
+func TestCmdCleanupNoConfig(t *testing.T) {
+	app, _, _ := appWithTestWriters()
+	err := command.CmdCleanup(&runner.Test{})(cli.NewContext(app, flag.NewFlagSet("test", 0), nil))
+	assert.EqualError(t, err, "You must specify a config file")
+}
+
+func TestCompleteCleanupFlags(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	app, writer, _ := appWithTestWriters()
+	app.Commands = []cli.Command{
+		{
+			Name: "cleanup",
+			Flags: []cli.Flag{
+				cli.StringSliceFlag{Name: "repo, r"},
+				cli.BoolFlag{Name: "yes, y"},
+			},
+		},
+	}
+	// The exit code helper that auto-rebase's tests use runs os.Args[0]
+	defer func(args []string) { os.Args = args }(os.Args)
+	os.Args = []string{"cleanup", "--completion"}
+	command.CompleteCleanup(cli.NewContext(app, set, nil))
+	assert.Equal(t, "--repo\n--yes\n", writer.String())
+}
+
+func TestCompleteCleanupRepo(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	app, writer, _ := appWithTestWriters()
+	// The exit code helper that auto-rebase's tests use runs os.Args[0]
+	defer func(args []string) { os.Args = args }(os.Args)
+	os.Args = []string{"cleanup", "--repo", "--completion"}
+	command.CompleteCleanup(cli.NewContext(app, set, nil))
+	assert.Equal(t, "foo/bar\nown/rep\n", writer.String())
+}
+
+func runCleanupAgainstServer(t *testing.T, repoDir string, cb *runner.Test, responses map[string]string, setFlags func(*flag.FlagSet)) (*bytes.Buffer, *bytes.Buffer, error) {
+	t.Helper()
+	inner := getAutoRebaseTestServer("")
+	defer inner.Close()
+	requests := []string{}
+	defaultResponses := map[string]string{
+		"/repos/own/rep": `{"name":"rep","full_name":"own/rep","ssh_url":"baseLabel1SSHURL","default_branch":"master"}`,
+		"/repos/guy/rep": `{"name":"rep","full_name":"guy/rep","ssh_url":"labelSSHURL","default_branch":"master"}`,
+		"/repos/own/rep/pulls?head=guy%3Aref1&state=all":       `[{"number":1,"state":"closed","merged_at":"2026-01-01T00:00:00Z","head":{"sha":"sha1"}}]`,
+		"/repos/own/rep/pulls?head=guy%3Aold&state=all":        `[{"number":5,"state":"closed","head":{"sha":"sha5"}}]`,
+		"/repos/own/rep/pulls?head=guy%3Awip&state=all":        `[{"number":6,"state":"closed"},{"number":3,"state":"open"}]`,
+		"/repos/own/rep/pulls?head=guy%3Alocal-only&state=all": `[]`,
+	}
+	ts := getRecordingOverridingTestServer(inner, &requests, mergeResponses(defaultResponses, responses))
+	defer ts.Close()
+	_, configFileName := getConfigWithAPIURLAndPath(t, ts.URL, repoDir)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	setFlags(set)
+	app, writer, errWriter := appWithTestWriters()
+	err := command.CmdCleanup(cb)(cli.NewContext(app, set, nil))
+	assert.Equal(t, []*runner.ExpectedCommand{}, cb.ExpectedCommands)
+	assert.Equal(t, []error(nil), cb.Errors)
+	assert.Equal(t, []string{}, requests)
+	return writer, errWriter, err
+}
+
+// getCleanupExpectedCommands are the commands cleanup runs to find the branches of own/rep's merged and closed pull requests
+// current is checked out, ref1 and its prp-ref1 temp branch were merged, old was closed, wip is still open and local-only has no pull request
+// The tips of the merged and closed branches are all part of their pull requests, prp-ref1 is deleted without checking
+func getCleanupExpectedCommands(repoDir string) []*runner.ExpectedCommand {
+	return []*runner.ExpectedCommand{
+		runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "refs/heads/current", 0),
+		runner.NewExpectedCommand(repoDir, "git for-each-ref --format=%\\(refname:short\\) refs/heads", "master\ncurrent\nref1\nprp-ref1\nold\nwip\nlocal-only", 0),
+		runner.NewExpectedCommand(repoDir, "git merge-base --is-ancestor ref1 sha1", "", 0),
+		runner.NewExpectedCommand(repoDir, "git merge-base --is-ancestor old sha5", "", 0),
+		runner.NewExpectedCommand(repoDir, "git remote -v", "origin\tlabelSSHURL (push)\nupstream\tbaseLabel1SSHURL (fetch)", 0),
+		runner.NewExpectedCommand(repoDir, "git fetch --prune origin", "", 0),
+		runner.NewExpectedCommand(repoDir, "git for-each-ref --format=%\\(refname:short\\) refs/remotes/origin", "origin\norigin/master\norigin/current\norigin/ref1\norigin/wip", 0),
+		runner.NewExpectedCommand(repoDir, "git merge-base --is-ancestor origin/ref1 sha1", "", 0),
+	}
+}
@@ -0,0 +1,110 @@
+package command
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/github"
+)
+
+// codeOwnersPaths are the places Github looks for a CODEOWNERS file, in the order it looks
+var codeOwnersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// codeOwnersRule is one line of a CODEOWNERS file
+type codeOwnersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// codeOwners is a parsed CODEOWNERS file, later rules take precedence
+type codeOwners []codeOwnersRule
+
+// getCodeOwners loads the CODEOWNERS file of a repo's default branch
+// A repo without one has no code owners
+func getCodeOwners(client *github.Client, owner, name string) (codeOwners, error) {
+	for _, path := range codeOwnersPaths {
+		file, _, response, err := client.Repositories.GetContents(context.Background(), owner, name, path, nil)
+		if response != nil && response.StatusCode == http.StatusNotFound {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := file.GetContent()
+		if err != nil {
+			return nil, err
+		}
+
+		return parseCodeOwners(content), nil
+	}
+
+	return codeOwners{}, nil
+}
+
+func parseCodeOwners(content string) codeOwners {
+	rules := codeOwners{}
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		rule := codeOwnersRule{pattern: codeOwnersPattern(fields[0]), owners: []string{}}
+		for _, owner := range fields[1:] {
+			if strings.HasPrefix(owner, "#") {
+				break
+			}
+
+			// Owners can also be email addresses, which can't be requested as reviewers
+			if strings.HasPrefix(owner, "@") {
+				rule.owners = append(rule.owners, strings.TrimPrefix(owner, "@"))
+			}
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
+
+// codeOwnersPattern turns a gitignore style CODEOWNERS pattern into a regular expression that matches file paths
+func codeOwnersPattern(pattern string) *regexp.Regexp {
+	directory := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	expression := "^"
+	if !anchored {
+		expression += "(.*/)?"
+	}
+
+	replacer := strings.NewReplacer(`\*\*/`, "(.*/)?", `/\*\*`, "(/.*)?", `\*\*`, ".*", `\*`, "[^/]*", `\?`, "[^/]")
+	expression += replacer.Replace(regexp.QuoteMeta(pattern))
+
+	// A pattern matches everything inside of the directories it matches unless it ends in a wildcard like docs/*
+	lastSegment := pattern[strings.LastIndex(pattern, "/")+1:]
+	switch {
+	case directory:
+		expression += "/.*"
+	case !strings.Contains(lastSegment, "*") || lastSegment == "**":
+		expression += "(/.*)?"
+	}
+
+	return regexp.MustCompile(expression + "$")
+}
+
+// ownersOf returns the owners of the last rule that matches file
+func (rules codeOwners) ownersOf(file string) []string {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].pattern.MatchString(file) {
+			return rules[i].owners
+		}
+	}
+
+	return []string{}
+}
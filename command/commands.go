@@ -1,6 +1,8 @@
 package command
 
 import (
+	"time"
+
 	"github.com/guywithnose/runner"
 	"github.com/urfave/cli"
 )
@@ -30,6 +32,104 @@ var profileCrudFlags = []cli.Flag{
 	},
 }
 
+var rebasePolicyFlags = []cli.Flag{
+	cli.IntFlag{
+		Name:  "min-approvals, ma",
+		Usage: "Only rebase pull requests with at least this many approvals",
+	},
+	cli.StringSliceFlag{
+		Name:  "require-label, rl",
+		Usage: "Only rebase pull requests with this label",
+	},
+	cli.BoolFlag{
+		Name:  "skip-drafts, sd",
+		Usage: "Do not rebase draft pull requests",
+	},
+	cli.BoolFlag{
+		Name:  "require-passing-builds, rpb",
+		Usage: "Only rebase pull requests whose builds (statuses and check runs) have all passed",
+	},
+}
+
+var ownerFlag = cli.StringFlag{
+	Name:  "user, owner, u",
+	Usage: "Only select pull requests by owner.",
+}
+
+var repoFlag = cli.StringSliceFlag{
+	Name:  "repo, r",
+	Usage: "Only select pull requests on a repository.",
+}
+
+var gitBackendFlag = cli.StringFlag{
+	Name:  "git-backend, gb",
+	Usage: "How the checked out branch of local clones is read: cli runs git, native reads .git/HEAD (remotes, fetching, rebasing and pushing always run git)",
+	Value: "cli",
+}
+
+// selectorFlags pick the pull requests that the commands working on many pull requests at once change
+var selectorFlags = []cli.Flag{
+	ownerFlag,
+	repoFlag,
+	cli.BoolFlag{
+		Name:  "need-rebase, nr",
+		Usage: "Only select pull requests that need a rebase.",
+	},
+	cli.BoolFlag{
+		Name:  "use-cache, uc, c",
+		Usage: "Use file cache",
+	},
+}
+
+var labelFlags = append([]cli.Flag{
+	cli.BoolFlag{
+		Name:  "yes, y",
+		Usage: "Change the labels without asking for confirmation",
+	},
+}, selectorFlags...)
+
+var mergePolicyFlags = []cli.Flag{
+	cli.IntFlag{
+		Name:  "min-approvals, ma",
+		Usage: "Only merge pull requests with at least this many approvals",
+	},
+	cli.StringSliceFlag{
+		Name:  "require-label, rl",
+		Usage: "Only merge pull requests with this label",
+	},
+	cli.StringFlag{
+		Name:  "method, me",
+		Usage: "How to merge pull requests (merge, squash or rebase)",
+	},
+	cli.BoolFlag{
+		Name:  "delete-branch, db",
+		Usage: "Delete the head branch after merging",
+	},
+}
+
+var stalePolicyFlags = []cli.Flag{
+	cli.IntFlag{
+		Name:  "days, d",
+		Usage: "Consider pull requests stale after this many days without commits, comments or reviews (default 30)",
+	},
+	cli.BoolFlag{
+		Name:  "warn, w",
+		Usage: "Comment on stale pull requests to warn their authors",
+	},
+	cli.StringFlag{
+		Name:  "label, l",
+		Usage: "Add this label to stale pull requests",
+	},
+	cli.BoolFlag{
+		Name:  "close",
+		Usage: "Close pull requests that are still stale after the grace period",
+	},
+	cli.IntFlag{
+		Name:  "grace-period, gp",
+		Usage: "How many more days a stale pull request is left open before --close closes it, counted from the last warning with --warn (default 7)",
+	},
+}
+
 // Commands defines the commands that can be called on hostBuilder
 var Commands = []cli.Command{
 	{
@@ -88,6 +188,19 @@ var Commands = []cli.Command{
 				BashComplete: CompleteProfileAdd,
 				Flags:        profileCrudFlags,
 			},
+			{
+				Name:    "add-comment-template",
+				Aliases: []string{"act"},
+				Usage:   "Save a comment template that comment can post with --template",
+				Action:  CmdProfileAddCommentTemplate,
+			},
+			{
+				Name:         "remove-comment-template",
+				Aliases:      []string{"rct"},
+				Usage:        "Remove a comment template",
+				Action:       CmdProfileRemoveCommentTemplate,
+				BashComplete: CompleteProfileRemoveCommentTemplate,
+			},
 		},
 	},
 	{
@@ -130,15 +243,116 @@ var Commands = []cli.Command{
 				Action:       CmdRepoSetPath,
 				BashComplete: CompleteRepoSetPath,
 			},
+			{
+				Name:         "set-update-strategy",
+				Aliases:      []string{"sus"},
+				Usage:        "Set how auto-rebase updates pull requests (rebase, merge, or api).",
+				Action:       CmdRepoSetUpdateStrategy,
+				BashComplete: CompleteRepoSetUpdateStrategy,
+			},
+			{
+				Name:         "set-rebase-policy",
+				Aliases:      []string{"srp"},
+				Usage:        "Set the conditions a pull request must meet before auto-rebase updates it.",
+				Action:       CmdRepoSetRebasePolicy,
+				BashComplete: CompleteRepoSetRebasePolicy,
+				Flags:        rebasePolicyFlags,
+			},
+			{
+				Name:         "set-merge-policy",
+				Aliases:      []string{"smp"},
+				Usage:        "Set the conditions a pull request must meet before merge merges it and how it is merged.",
+				Action:       CmdRepoSetMergePolicy,
+				BashComplete: CompleteRepoSetMergePolicy,
+				Flags:        mergePolicyFlags,
+			},
+			{
+				Name:         "set-stale-policy",
+				Aliases:      []string{"ssp"},
+				Usage:        "Set when stale considers a pull request stale and what it does about it.",
+				Action:       CmdRepoSetStalePolicy,
+				BashComplete: CompleteRepoSetStalePolicy,
+				Flags:        stalePolicyFlags,
+			},
+			{
+				Name:         "set-pull-request-defaults",
+				Aliases:      []string{"spd"},
+				Usage:        "Set the template, reviewers and labels open uses for new pull requests.",
+				Action:       CmdRepoSetPullRequestDefaults,
+				BashComplete: CompleteRepoSetPullRequestDefaults,
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "template, t",
+						Usage: "A text/template file that renders the body, relative to the repo's local path",
+					},
+					cli.StringSliceFlag{
+						Name:  "reviewer, rv",
+						Usage: "Request a review from this user",
+					},
+					cli.StringSliceFlag{
+						Name:  "label, l",
+						Usage: "Add this label",
+					},
+				},
+			},
+			{
+				Name:         "set-reviewer-rules",
+				Aliases:      []string{"srr"},
+				Usage:        "Set who assign-reviewers asks to review pull requests.",
+				Action:       CmdRepoSetReviewerRules,
+				BashComplete: CompleteRepoSetReviewerRules,
+				Flags: []cli.Flag{
+					cli.IntFlag{
+						Name:  "required, n",
+						Usage: "How many reviewers each pull request needs",
+					},
+					cli.StringSliceFlag{
+						Name:  "team, t",
+						Usage: "The members of a team named in CODEOWNERS, like org/team=user1,user2",
+					},
+					cli.StringSliceFlag{
+						Name:  "exclude, x",
+						Usage: "Never request a review from this user",
+					},
+				},
+			},
+			{
+				Name:         "set-autosquash",
+				Aliases:      []string{"sa"},
+				Usage:        "Set whether auto-rebase squashes fixup commits.",
+				Action:       CmdRepoSetAutosquash,
+				BashComplete: CompleteRepoSetAutosquash,
+			},
+			{
+				Name:         "set-sign-commits",
+				Aliases:      []string{"ssc"},
+				Usage:        "Set whether auto-rebase signs rebased commits and refuses to push unsigned ones.",
+				Action:       CmdRepoSetSignCommits,
+				BashComplete: CompleteRepoSetSignCommits,
+			},
+			{
+				Name:         "add-hook",
+				Aliases:      []string{"ah"},
+				Usage:        "Add a command that auto-rebase runs before (pre-push) or after (post-push) pushing.",
+				Action:       CmdRepoAddHook,
+				BashComplete: CompleteRepoAddHook,
+			},
+			{
+				Name:         "remove-hook",
+				Aliases:      []string{"rh"},
+				Usage:        "Remove a hook command.",
+				Action:       CmdRepoRemoveHook,
+				BashComplete: CompleteRepoRemoveHook,
+			},
 		},
 	},
 	{
 		Name:         "auto-rebase",
 		Aliases:      []string{"a", "auto"},
-		Usage:        "Automatically rebase your pull requests with local path set",
+		Usage:        "Automatically rebase your pull requests",
 		Action:       CmdAutoRebase(runner.Real{}),
 		BashComplete: CompleteAutoRebase,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			cli.StringSliceFlag{
 				Name:  "repo, r",
 				Usage: "Only rebase these repos.",
@@ -155,6 +369,401 @@ var Commands = []cli.Command{
 				Name:  "use-cache, uc, c",
 				Usage: "Use file cache",
 			},
+			cli.BoolFlag{
+				Name:  "leave-conflicts, lc",
+				Usage: "Leave conflicted rebases and merges in a worktree so they can be resolved by hand",
+			},
+			cli.BoolFlag{
+				Name:  "continue",
+				Usage: "Finish and push rebases and merges that were left in a worktree",
+			},
+			cli.BoolFlag{
+				Name:  "backup-ref, br",
+				Usage: "Save the old head of each pull request as refs/prp/backup/<branch> before pushing",
+			},
+			cli.BoolFlag{
+				Name:  "autosquash, as",
+				Usage: "Squash fixup! and squash! commits into the commits they fix",
+			},
+			cli.BoolFlag{
+				Name:  "wait-for-ci, wfc",
+				Usage: "Wait for the builds of rebased pull requests and report the results",
+			},
+			cli.DurationFlag{
+				Name:  "ci-timeout, ct",
+				Usage: "How long to wait for builds with --wait-for-ci",
+				Value: 30 * time.Minute,
+			},
+			cli.BoolFlag{
+				Name:  "watch, w",
+				Usage: "Keep running and rebase pull requests whenever their target branch moves",
+			},
+			cli.DurationFlag{
+				Name:  "interval, i",
+				Usage: "How long to wait between cycles in watch mode",
+				Value: 10 * time.Minute,
+			},
+			cli.IntFlag{
+				Name:  "max-cycles, mc",
+				Usage: "Stop watching after this many cycles (0 watches until interrupted)",
+			},
+			gitBackendFlag,
+			cli.StringFlag{
+				Name:  "user, owner, u",
+				Usage: "Rebase this user's pull requests instead of yours",
+			},
+			cli.BoolFlag{
+				Name:  "all-owners, ao",
+				Usage: "Rebase everyone's pull requests",
+			},
+			cli.BoolFlag{
+				Name:  "comment",
+				Usage: "Leave a comment on other people's pull requests after updating them",
+			},
+			cli.BoolFlag{
+				Name:  "yes, y",
+				Usage: "Undo without asking for confirmation",
+			},
+		}, rebasePolicyFlags...),
+	},
+	{
+		Name:         "retarget",
+		Usage:        "Move pull requests whose target branch was merged or deleted onto a live branch",
+		Action:       CmdRetarget(runner.Real{}),
+		BashComplete: CompleteRetarget,
+		Flags: []cli.Flag{
+			cli.StringSliceFlag{
+				Name:  "repo, r",
+				Usage: "Only retarget pull requests on these repos.",
+			},
+			cli.IntFlag{
+				Name:  "pull-request-number, prNum, n",
+				Usage: "A specific pull request number",
+			},
+			cli.StringFlag{
+				Name:  "base, b",
+				Usage: "The branch to move pull requests onto (defaults to the branch the merged parent targeted or the default branch)",
+			},
+			cli.BoolFlag{
+				Name:  "verbose, v",
+				Usage: "Output more info",
+			},
+			cli.BoolFlag{
+				Name:  "use-cache, uc, c",
+				Usage: "Use file cache",
+			},
+			cli.BoolFlag{
+				Name:  "yes, y",
+				Usage: "Retarget without asking for confirmation",
+			},
+		},
+	},
+	{
+		Name:         "merge",
+		Aliases:      []string{"m"},
+		Usage:        "Merge your pull requests once they are ready",
+		Action:       CmdMerge,
+		BashComplete: CompleteMerge,
+		Flags: append([]cli.Flag{
+			cli.StringSliceFlag{
+				Name:  "repo, r",
+				Usage: "Only merge pull requests on these repos.",
+			},
+			cli.IntFlag{
+				Name:  "pull-request-number, prNum, n",
+				Usage: "A specific pull request number",
+			},
+			cli.BoolFlag{
+				Name:  "dry-run, dr",
+				Usage: "List which pull requests are ready to be merged without merging them",
+			},
+			cli.BoolFlag{
+				Name:  "verbose, v",
+				Usage: "Output more info",
+			},
+			cli.BoolFlag{
+				Name:  "use-cache, uc, c",
+				Usage: "Use file cache",
+			},
+			cli.BoolFlag{
+				Name:  "watch, w",
+				Usage: "Keep running and merge pull requests as soon as they are ready",
+			},
+			cli.DurationFlag{
+				Name:  "interval, i",
+				Usage: "How long to wait between cycles in watch mode",
+				Value: 10 * time.Minute,
+			},
+			cli.IntFlag{
+				Name:  "max-cycles, mc",
+				Usage: "Stop watching after this many cycles (0 watches until interrupted)",
+			},
+		}, mergePolicyFlags...),
+	},
+	{
+		Name:         "history",
+		Aliases:      []string{"hi"},
+		Usage:        "Show what auto-rebase has done",
+		Action:       CmdHistory,
+		BashComplete: CompleteHistory,
+		Flags: []cli.Flag{
+			cli.StringSliceFlag{
+				Name:  "repo, r",
+				Usage: "Only show these repos.",
+			},
+			cli.IntFlag{
+				Name:  "pull-request-number, prNum, n",
+				Usage: "Only show a specific pull request number",
+			},
+			cli.StringSliceFlag{
+				Name:  "outcome, o",
+				Usage: "Only show attempts with this outcome (pushed, updated, failed or undone)",
+			},
+			cli.DurationFlag{
+				Name:  "since, s",
+				Usage: "Only show attempts made within this long, like 24h",
+			},
+			cli.IntFlag{
+				Name:  "limit, l",
+				Usage: "Only show the most recent attempts",
+			},
+			cli.BoolFlag{
+				Name:  "json",
+				Usage: "Output the attempts as JSON",
+			},
+		},
+	},
+	{
+		Name:         "review",
+		Usage:        "Approve, request changes on or comment on a pull request",
+		Action:       CmdReview,
+		BashComplete: CompleteReview,
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "approve, a",
+				Usage: "Approve the pull request",
+			},
+			cli.BoolFlag{
+				Name:  "request-changes, rc",
+				Usage: "Request changes on the pull request",
+			},
+			cli.BoolFlag{
+				Name:  "comment, c",
+				Usage: "Comment on the pull request without approving it",
+			},
+			cli.StringFlag{
+				Name:  "message, m",
+				Usage: "The body of the review",
+			},
+		},
+	},
+	{
+		Name:         "approve",
+		Usage:        "Approve a pull request",
+		Action:       CmdApprove,
+		BashComplete: CompleteApprove,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "message, m",
+				Usage: "The body of the review",
+			},
+		},
+	},
+	{
+		Name:    "label",
+		Aliases: []string{"l"},
+		Usage:   "Add or remove a label on many pull requests",
+		Subcommands: []cli.Command{
+			{
+				Name:         "add",
+				Aliases:      []string{"a"},
+				Usage:        "Add a label to the pull requests that match the selectors",
+				Action:       CmdLabelAdd,
+				BashComplete: CompleteLabelAdd,
+				Flags:        labelFlags,
+			},
+			{
+				Name:         "remove",
+				Aliases:      []string{"rm"},
+				Usage:        "Remove a label from the pull requests that match the selectors",
+				Action:       CmdLabelRemove,
+				BashComplete: CompleteLabelRemove,
+				Flags:        labelFlags,
+			},
+		},
+	},
+	{
+		Name:         "rerun",
+		Usage:        "Rerun the failed builds of a pull request",
+		Action:       CmdRerun,
+		BashComplete: CompleteRerun,
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "all",
+				Usage: "Rerun the failed builds of all of your pull requests",
+			},
+			cli.StringSliceFlag{
+				Name:  "repo, r",
+				Usage: "Only rerun builds on a repository with --all.",
+			},
+			cli.BoolFlag{
+				Name:  "use-cache, uc, c",
+				Usage: "Use file cache",
+			},
+		},
+	},
+	{
+		Name:         "stale",
+		Usage:        "List pull requests nobody has touched for a while and warn, label or close them",
+		Action:       CmdStale,
+		BashComplete: CompleteStale,
+		Flags: append([]cli.Flag{
+			ownerFlag,
+			repoFlag,
+			cli.BoolFlag{
+				Name:  "dry-run, dr",
+				Usage: "List stale pull requests and what would be done about them without doing it",
+			},
+			cli.BoolFlag{
+				Name:  "use-cache, uc, c",
+				Usage: "Use file cache",
+			},
+		}, stalePolicyFlags...),
+	},
+	{
+		Name:         "open",
+		Aliases:      []string{"o"},
+		Usage:        "Push the current branch of a tracked clone and open a pull request for it",
+		Action:       CmdOpen(runner.Real{}),
+		BashComplete: CompleteOpen,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "path, p",
+				Usage: "The clone to open a pull request from (defaults to the working directory)",
+			},
+			cli.StringFlag{
+				Name:  "base, b",
+				Usage: "The branch to merge into (defaults to the repo's default branch)",
+			},
+			cli.StringFlag{
+				Name:  "title, t",
+				Usage: "The title (defaults to the commit subject or the branch name)",
+			},
+			cli.StringFlag{
+				Name:  "body",
+				Usage: "The body (defaults to the repo's template or the commit messages)",
+			},
+			cli.StringSliceFlag{
+				Name:  "reviewer, rv",
+				Usage: "Request a review from this user as well as the repo's default reviewers",
+			},
+			cli.StringSliceFlag{
+				Name:  "label, l",
+				Usage: "Add this label as well as the repo's default labels",
+			},
+			cli.BoolFlag{
+				Name:  "dry-run, dr",
+				Usage: "Show the pull request without pushing or opening it",
+			},
+			gitBackendFlag,
+		},
+	},
+	{
+		Name:         "assign-reviewers",
+		Aliases:      []string{"ar"},
+		Usage:        "Request reviews from the least busy code owners on pull requests that need reviewers",
+		Action:       CmdAssignReviewers,
+		BashComplete: CompleteAssignReviewers,
+		Flags: []cli.Flag{
+			ownerFlag,
+			repoFlag,
+			cli.IntFlag{
+				Name:  "required, n",
+				Usage: "How many reviewers each pull request needs (overrides the repos' rules)",
+			},
+			cli.BoolFlag{
+				Name:  "dry-run, dr",
+				Usage: "Show who would be asked to review without asking them",
+			},
+		},
+	},
+	{
+		Name:         "comment",
+		Aliases:      []string{"c"},
+		Usage:        "Post a comment on the pull requests that match the selectors",
+		Action:       CmdComment,
+		BashComplete: CompleteComment,
+		Flags: append([]cli.Flag{
+			cli.StringFlag{
+				Name:  "message, m",
+				Usage: "The comment, it can use the same fields as a template",
+			},
+			cli.StringFlag{
+				Name:  "template, t",
+				Usage: "Post the profile's comment template with this name",
+			},
+			cli.BoolFlag{
+				Name:  "yes, y",
+				Usage: "Post the comments without asking for confirmation",
+			},
+		}, selectorFlags...),
+	},
+	{
+		Name:         "ready",
+		Usage:        "Mark draft pull requests ready for review",
+		Action:       CmdReady,
+		BashComplete: CompleteReady,
+		Flags: append([]cli.Flag{
+			cli.BoolFlag{
+				Name:  "yes, y",
+				Usage: "Mark them ready without asking for confirmation",
+			},
+		}, selectorFlags...),
+	},
+	{
+		Name:         "draft",
+		Usage:        "Convert pull requests back to drafts",
+		Action:       CmdDraft,
+		BashComplete: CompleteDraft,
+		Flags: append([]cli.Flag{
+			cli.BoolFlag{
+				Name:  "yes, y",
+				Usage: "Convert them without asking for confirmation",
+			},
+		}, selectorFlags...),
+	},
+	{
+		Name:         "close",
+		Usage:        "Close pull requests without merging them",
+		Action:       CmdClose,
+		BashComplete: CompleteClose,
+		Flags: append([]cli.Flag{
+			cli.StringFlag{
+				Name:  "message, m",
+				Usage: "Leave this comment before closing, it can use the same fields as a template",
+			},
+			cli.BoolFlag{
+				Name:  "delete-branch, db",
+				Usage: "Delete the head branches of the closed pull requests when they are yours, you can push to them and nothing else depends on them",
+			},
+			cli.BoolFlag{
+				Name:  "yes, y",
+				Usage: "Close them without asking for confirmation",
+			},
+		}, selectorFlags...),
+	},
+	{
+		Name:         "cleanup",
+		Usage:        "Delete the branches of merged and closed pull requests from local clones and your forks",
+		Action:       CmdCleanup(runner.Real{}),
+		BashComplete: CompleteCleanup,
+		Flags: []cli.Flag{
+			repoFlag,
+			cli.BoolFlag{
+				Name:  "yes, y",
+				Usage: "Delete the branches without asking for confirmation",
+			},
+			gitBackendFlag,
 		},
 	},
 }
@@ -0,0 +1,188 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/google/go-github/github"
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/urfave/cli"
+)
+
+// commentData is what a comment template is rendered with
+type commentData struct {
+	Number        int
+	Title         string
+	Owner         string
+	Repo          string
+	Branch        string
+	Target        string
+	Approvals     int
+	Rebased       bool
+	Labels        []string
+	FailingBuilds []string
+}
+
+// CmdComment posts a comment on every pull request matching the parse selectors
+func CmdComment(c *cli.Context) error {
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() != 0 || (c.String("message") == "") == (c.String("template") == "") {
+		return cli.NewExitError("Usage: \"prp comment -m {message}\" or \"prp comment --template {templateName}\"", 1)
+	}
+
+	profile := configData.Profiles[*profileName]
+	commentTemplate, err := loadCommentTemplate(c, &profile, *profileName)
+	if err != nil {
+		return err
+	}
+
+	client, err := getGithubClient(&profile.Token, &profile.APIURL, c.Bool("use-cache"))
+	if err != nil {
+		return err
+	}
+
+	user, _, err := client.Users.Get(context.Background(), "")
+	if err != nil {
+		return err
+	}
+
+	parser := newParser(client, user, &profile)
+	prs := parser.parsePullRequests(parser.getBasePullRequestData(c.App.ErrWriter), c.String("owner"), c.StringSlice("repo"), c.Bool("need-rebase"))
+
+	var completeError error
+	comments := make(map[*pullRequest]string)
+	affected := []*pullRequest{}
+	for _, pr := range sortPullRequests(selectPullRequests(prs, 0)) {
+		body := &bytes.Buffer{}
+		err = commentTemplate.Execute(body, pr.commentData())
+		if err != nil {
+			fmt.Fprintf(c.App.ErrWriter, "Could not write the comment for PR #%d in %s/%s because: %v\n", pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, err)
+			completeError = cli.NewExitError("Unable to comment on all pull requests", 1)
+			continue
+		}
+
+		comments[pr] = strings.TrimSpace(body.String())
+		affected = append(affected, pr)
+	}
+
+	if len(affected) == 0 {
+		if completeError == nil {
+			fmt.Fprintln(c.App.Writer, "No pull requests match")
+		}
+
+		return completeError
+	}
+
+	fmt.Fprintln(c.App.Writer, "Comments will be posted on:")
+	for _, pr := range affected {
+		fmt.Fprintf(c.App.Writer, "  PR #%d in %s/%s: %s\n", pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, pr.Title)
+		fmt.Fprintf(c.App.Writer, "    %s\n", strings.Replace(comments[pr], "\n", "\n    ", -1))
+	}
+
+	if !c.Bool("yes") && !confirm(os.Stdin, c.App.Writer, "Continue?") {
+		return completeError
+	}
+
+	for _, pr := range affected {
+		body := comments[pr]
+		_, _, err = client.Issues.CreateComment(context.Background(), pr.Repo.Owner, pr.Repo.Name, pr.PullRequestID, &github.IssueComment{Body: &body})
+		if err != nil {
+			fmt.Fprintf(c.App.ErrWriter, "Could not comment on PR #%d in %s/%s because: %v\n", pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, err)
+			completeError = cli.NewExitError("Unable to comment on all pull requests", 1)
+		}
+	}
+
+	return completeError
+}
+
+// loadCommentTemplate parses the --message or the profile's template named by --template
+func loadCommentTemplate(c *cli.Context, profile *config.Profile, profileName string) (*template.Template, error) {
+	name := c.String("template")
+	text := c.String("message")
+	if name != "" {
+		var ok bool
+		text, ok = profile.CommentTemplates[name]
+		if !ok {
+			return nil, cli.NewExitError(fmt.Sprintf("Profile %s has no comment template named %s", profileName, name), 1)
+		}
+	}
+
+	return parseCommentTemplate(text)
+}
+
+// parseCommentTemplate parses a comment template, templates can join lists like .FailingBuilds with {{join .FailingBuilds ", "}}
+func parseCommentTemplate(text string) (*template.Template, error) {
+	commentTemplate, err := template.New("comment").Funcs(template.FuncMap{"join": strings.Join}).Parse(text)
+	if err != nil {
+		return nil, cli.NewExitError(fmt.Sprintf("Invalid comment template: %v", err), 1)
+	}
+
+	return commentTemplate, nil
+}
+
+func (pr pullRequest) commentData() commentData {
+	failingBuilds := []string{}
+	for context, state := range pr.latestBuildStates {
+		// Pending builds haven't failed yet
+		if state == "failure" || state == "error" {
+			failingBuilds = append(failingBuilds, context)
+		}
+	}
+
+	sort.Strings(failingBuilds)
+	return commentData{
+		Number:        pr.PullRequestID,
+		Title:         pr.Title,
+		Owner:         pr.Owner,
+		Repo:          fmt.Sprintf("%s/%s", pr.Repo.Owner, pr.Repo.Name),
+		Branch:        pr.Branch,
+		Target:        pr.TargetBranch,
+		Approvals:     pr.Approvals,
+		Rebased:       pr.Rebased,
+		Labels:        pr.Labels,
+		FailingBuilds: failingBuilds,
+	}
+}
+
+// CompleteComment handles bash autocompletion for the 'comment' command
+func CompleteComment(c *cli.Context) {
+	lastParam := os.Args[len(os.Args)-2]
+	if lastParam != "--user" && lastParam != "--repo" && lastParam != "--template" {
+		completeFlags(c, "comment")
+		return
+	}
+
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return
+	}
+
+	profile := configData.Profiles[*profileName]
+	switch lastParam {
+	case "--user":
+		completeUser(&profile, c.App.Writer, c.App.ErrWriter)
+	case "--repo":
+		completeRepo(c.StringSlice("repo"), profile, c.App.Writer)
+	default:
+		completeCommentTemplates(&profile, c)
+	}
+}
+
+func completeCommentTemplates(profile *config.Profile, c *cli.Context) {
+	names := []string{}
+	for name := range profile.CommentTemplates {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	fmt.Fprintln(c.App.Writer, strings.Join(names, "\n"))
+}
@@ -0,0 +1,277 @@
+package command_test
+
+import (
+	"flag"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/guywithnose/pull-request-parser/command"
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+func TestCmdComment(t *testing.T) {
+	var testCases = []struct {
+		name             string
+		setFlags         func(*flag.FlagSet)
+		expectedRequests []string
+		expectedOutput   []string
+	}{
+		{
+			"Message",
+			func(set *flag.FlagSet) {
+				set.String("message", "@{{.Owner}} please rebase {{.Branch}} onto {{.Target}}", "doc")
+				set.String("owner", "guy2", "doc")
+			},
+			[]string{`POST /repos/own/rep/issues/2/comments {"body":"@guy2 please rebase ref2 onto baseRef2"}`},
+			[]string{
+				"Comments will be posted on:",
+				"  PR #2 in own/rep: Really long Pull Request Title",
+				"    @guy2 please rebase ref2 onto baseRef2",
+			},
+		},
+		{
+			"Template",
+			func(set *flag.FlagSet) {
+				set.String("template", "builds", "doc")
+				repos := cli.StringSlice{"own/rep"}
+				set.Var(&repos, "repo", "doc")
+			},
+			[]string{
+				`POST /repos/own/rep/issues/1/comments {"body":"All builds pass on prOne"}`,
+				`POST /repos/own/rep/issues/2/comments {"body":"build1 failed on Really long Pull Request Title\nPlease fix them"}`,
+			},
+			[]string{
+				"Comments will be posted on:",
+				"  PR #1 in own/rep: prOne",
+				"    All builds pass on prOne",
+				"  PR #2 in own/rep: Really long Pull Request Title",
+				"    build1 failed on Really long Pull Request Title",
+				"    Please fix them",
+			},
+		},
+		{
+			"TemplatePendingBuild",
+			func(set *flag.FlagSet) {
+				set.String("template", "builds", "doc")
+				repos := cli.StringSlice{"foo/bar"}
+				set.Var(&repos, "repo", "doc")
+			},
+			[]string{
+				`POST /repos/foo/bar/issues/1/comments {"body":"All builds pass on fooPrOne"}`,
+				`POST /repos/foo/bar/issues/2/comments {"body":"All builds pass on fooPrTwo"}`,
+			},
+			[]string{
+				"Comments will be posted on:",
+				"  PR #1 in foo/bar: fooPrOne",
+				"    All builds pass on fooPrOne",
+				"  PR #2 in foo/bar: fooPrTwo",
+				"    All builds pass on fooPrTwo",
+			},
+		},
+		{
+			"NeedRebase",
+			func(set *flag.FlagSet) {
+				set.String("message", "Please rebase", "doc")
+				set.Bool("need-rebase", true, "doc")
+			},
+			[]string{
+				`POST /repos/foo/bar/issues/2/comments {"body":"Please rebase"}`,
+				`POST /repos/own/rep/issues/1/comments {"body":"Please rebase"}`,
+			},
+			[]string{
+				"Comments will be posted on:",
+				"  PR #2 in foo/bar: fooPrTwo",
+				"    Please rebase",
+				"  PR #1 in own/rep: prOne",
+				"    Please rebase",
+			},
+		},
+		{
+			"NoMatches",
+			func(set *flag.FlagSet) {
+				set.String("message", "Please rebase", "doc")
+				set.String("owner", "nobody", "doc")
+			},
+			[]string{},
+			[]string{"No pull requests match"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := []string{}
+			inner := getParseTestServer("")
+			defer inner.Close()
+			ts := getCommentTestServer(inner, &requests, map[string]string{})
+			defer ts.Close()
+			writer, errWriter, err := runAgainstServer(t, ts, command.CmdComment, withBuildsTemplate, func(set *flag.FlagSet) {
+				set.Bool("yes", true, "doc")
+				tc.setFlags(set)
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedRequests, requests)
+			assert.Equal(t, strings.Join(append(tc.expectedOutput, ""), "\n"), writer.String())
+			assert.Equal(t, "", errWriter.String())
+		})
+	}
+}
+
+func TestCmdCommentDeclined(t *testing.T) {
+	requests := []string{}
+	inner := getParseTestServer("")
+	defer inner.Close()
+	ts := getCommentTestServer(inner, &requests, map[string]string{})
+	defer ts.Close()
+	defer replaceStdin(t, "n\n")()
+	writer, _, err := runAgainstServer(t, ts, command.CmdComment, withBuildsTemplate, func(set *flag.FlagSet) {
+		set.String("message", "Ping", "doc")
+		set.String("owner", "guy", "doc")
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{}, requests)
+	assert.Equal(t, "Comments will be posted on:\n  PR #1 in own/rep: prOne\n    Ping\nContinue? [y/N] ", writer.String())
+}
+
+func TestCmdCommentFailure(t *testing.T) {
+	requests := []string{}
+	inner := getParseTestServer("")
+	defer inner.Close()
+	ts := getCommentTestServer(inner, &requests, map[string]string{"/repos/own/rep/issues/1/comments": "not json"})
+	defer ts.Close()
+	_, errWriter, err := runAgainstServer(t, ts, command.CmdComment, withBuildsTemplate, func(set *flag.FlagSet) {
+		set.String("message", "Ping", "doc")
+		set.String("owner", "guy", "doc")
+		set.Bool("yes", true, "doc")
+	})
+	assert.EqualError(t, err, "Unable to comment on all pull requests")
+	assert.Equal(t, []string{`POST /repos/own/rep/issues/1/comments {"body":"Ping"}`}, requests)
+	assert.Contains(t, errWriter.String(), "Could not comment on PR #1 in own/rep because: ")
+}
+
+func TestCmdCommentRenderFailure(t *testing.T) {
+	requests := []string{}
+	inner := getParseTestServer("")
+	defer inner.Close()
+	ts := getCommentTestServer(inner, &requests, map[string]string{})
+	defer ts.Close()
+	writer, errWriter, err := runAgainstServer(t, ts, command.CmdComment, withBuildsTemplate, func(set *flag.FlagSet) {
+		set.String("message", "{{.Missing}}", "doc")
+		set.String("owner", "guy", "doc")
+		set.Bool("yes", true, "doc")
+	})
+	assert.EqualError(t, err, "Unable to comment on all pull requests")
+	assert.Equal(t, []string{}, requests)
+	assert.Equal(t, "", writer.String())
+	assert.Contains(t, errWriter.String(), "Could not write the comment for PR #1 in own/rep because: ")
+}
+
+func TestCmdCommentErrors(t *testing.T) {
+	var testCases = []struct {
+		name          string
+		setFlags      func(*flag.FlagSet)
+		expectedError string
+	}{
+		{
+			"NoMessage",
+			func(*flag.FlagSet) {},
+			"Usage: \"prp comment -m {message}\" or \"prp comment --template {templateName}\"",
+		},
+		{
+			"MessageAndTemplate",
+			func(set *flag.FlagSet) {
+				set.String("message", "Ping", "doc")
+				set.String("template", "builds", "doc")
+			},
+			"Usage: \"prp comment -m {message}\" or \"prp comment --template {templateName}\"",
+		},
+		{
+			"UnknownTemplate",
+			func(set *flag.FlagSet) { set.String("template", "missing", "doc") },
+			"Profile foo has no comment template named missing",
+		},
+		{
+			"InvalidMessage",
+			func(set *flag.FlagSet) { set.String("message", "{{", "doc") },
+			"Invalid comment template: template: comment:1: unclosed action",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := runAgainstServer(t, httptest.NewUnstartedServer(nil), command.CmdComment, withBuildsTemplate, tc.setFlags)
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestCmdCommentNoConfig(t *testing.T) {
+	app, _, _ := appWithTestWriters()
+	err := command.CmdComment(cli.NewContext(app, flag.NewFlagSet("test", 0), nil))
+	assert.EqualError(t, err, "You must specify a config file")
+}
+
+func TestCompleteCommentTemplates(t *testing.T) {
+	conf, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	profile := conf.Profiles["foo"]
+	profile.CommentTemplates = map[string]string{"rebase": "Please rebase", "builds": "Please fix the builds"}
+	conf.Profiles["foo"] = profile
+	assert.Nil(t, conf.Write(configFileName))
+	set := getBaseFlagSet(configFileName)
+	app, writer, _ := appWithTestWriters()
+	os.Args = []string{"comment", "--template", "--completion"}
+	command.CompleteComment(cli.NewContext(app, set, nil))
+	assert.Equal(t, "builds\nrebase\n", writer.String())
+}
+
+func TestCompleteCommentFlags(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	app, writer, _ := appWithTestWriters()
+	app.Commands = []cli.Command{
+		{
+			Name: "comment",
+			Flags: []cli.Flag{
+				cli.StringFlag{Name: "message, m"},
+				cli.StringFlag{Name: "template, t"},
+			},
+		},
+	}
+	os.Args = []string{"comment", "--completion"}
+	command.CompleteComment(cli.NewContext(app, set, nil))
+	assert.Equal(t, "--message\n--template\n", writer.String())
+}
+
+func TestCompleteCommentRepo(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	app, writer, _ := appWithTestWriters()
+	os.Args = []string{"comment", "--repo", "--completion"}
+	command.CompleteComment(cli.NewContext(app, set, nil))
+	assert.Equal(t, "foo/bar\nown/rep\n", writer.String())
+}
+
+// withBuildsTemplate is a modifyProfile for runAgainstServer that adds the builds comment template
+func withBuildsTemplate(profile *config.Profile) {
+	profile.CommentTemplates = map[string]string{
+		"builds": "{{if .FailingBuilds}}{{join .FailingBuilds \", \"}} failed on {{.Title}}\nPlease fix them{{else}}All builds pass on {{.Title}}{{end}}",
+	}
+}
+
+// getCommentTestServer accepts comments on every pull request and records every request that isn't a GET
+func getCommentTestServer(inner *httptest.Server, requests *[]string, responses map[string]string) *httptest.Server {
+	defaultResponses := map[string]string{
+		"/repos/own/rep/issues/1/comments": "{}",
+		"/repos/own/rep/issues/2/comments": "{}",
+		"/repos/foo/bar/issues/1/comments": "{}",
+		"/repos/foo/bar/issues/2/comments": "{}",
+	}
+
+	return getRecordingOverridingTestServer(inner, requests, mergeResponses(defaultResponses, responses))
+}
@@ -25,7 +25,22 @@ func TestRootCompletion(t *testing.T) {
 				"parse:Parse your pull requests",
 				"profile:Manage profiles",
 				"repo:Manage repos.",
-				"auto-rebase:Automatically rebase your pull requests with local path set",
+				"auto-rebase:Automatically rebase your pull requests",
+				"retarget:Move pull requests whose target branch was merged or deleted onto a live branch",
+				"merge:Merge your pull requests once they are ready",
+				"history:Show what auto-rebase has done",
+				"review:Approve, request changes on or comment on a pull request",
+				"approve:Approve a pull request",
+				"label:Add or remove a label on many pull requests",
+				"rerun:Rerun the failed builds of a pull request",
+				"stale:List pull requests nobody has touched for a while and warn, label or close them",
+				"open:Push the current branch of a tracked clone and open a pull request for it",
+				"assign-reviewers:Request reviews from the least busy code owners on pull requests that need reviewers",
+				"comment:Post a comment on the pull requests that match the selectors",
+				"ready:Mark draft pull requests ready for review",
+				"draft:Convert pull requests back to drafts",
+				"close:Close pull requests without merging them",
+				"cleanup:Delete the branches of merged and closed pull requests from local clones and your forks",
 				"--config",
 				"--profile",
 				"",
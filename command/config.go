@@ -2,6 +2,8 @@ package command
 
 import (
 	"fmt"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/guywithnose/pull-request-parser/config"
@@ -22,6 +24,16 @@ func loadConfig(c *cli.Context) (*config.PrpConfig, error) {
 	return configData, nil
 }
 
+// journalFile returns the path of the journal that records the pushes made by auto-rebase
+func journalFile(c *cli.Context) string {
+	return fmt.Sprintf("%s.journal", c.GlobalString("config"))
+}
+
+// stacksFile returns the path of the file that remembers which pull requests auto-rebase saw stacked
+func stacksFile(c *cli.Context) string {
+	return fmt.Sprintf("%s.stacks", c.GlobalString("config"))
+}
+
 func loadProfile(c *cli.Context) (*config.PrpConfig, *string, error) {
 	configData, err := loadConfig(c)
 	if err != nil {
@@ -50,3 +62,51 @@ func loadRepo(profile *config.Profile, repoName string) (*config.Repo, int, erro
 
 	return nil, -1, cli.NewExitError(fmt.Sprintf("Not a valid Repo: %s", repoName), 1)
 }
+
+// loadRepoByPath finds the tracked repo whose local path contains path
+func loadRepoByPath(profile *config.Profile, path string) (*config.Repo, error) {
+	resolvedPath := resolvePath(path)
+	for _, repo := range profile.TrackedRepos {
+		if repo.LocalPath == "" {
+			continue
+		}
+
+		localPath := resolvePath(repo.LocalPath)
+		if resolvedPath == localPath || strings.HasPrefix(resolvedPath, localPath+string(filepath.Separator)) {
+			return &repo, nil
+		}
+	}
+
+	return nil, cli.NewExitError(fmt.Sprintf("%s is not inside the local path of a tracked repo", filepath.Clean(path)), 1)
+}
+
+// resolvePath makes path absolute and follows its symlinks so that different ways of naming a clone compare equal
+func resolvePath(path string) string {
+	absolutePath, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+
+	resolvedPath, err := filepath.EvalSymlinks(absolutePath)
+	if err != nil {
+		// A path that doesn't exist has no symlinks to follow
+		return absolutePath
+	}
+
+	return resolvedPath
+}
+
+// loadPullRequest resolves the {repoName} {pullRequestNumber} arguments shared by the commands that act on a single pull request
+func loadPullRequest(profile *config.Profile, repoName, pullRequestNumber string) (*config.Repo, int, error) {
+	repo, _, err := loadRepo(profile, repoName)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	number, err := strconv.Atoi(pullRequestNumber)
+	if err != nil || number <= 0 {
+		return nil, 0, cli.NewExitError(fmt.Sprintf("Not a valid pull request number: %s", pullRequestNumber), 1)
+	}
+
+	return repo, number, nil
+}
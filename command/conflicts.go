@@ -0,0 +1,273 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/urfave/cli"
+)
+
+// leftConflict describes a conflicted rebase or merge that was left in a worktree for the user to resolve
+// Operation is empty for rebases, which were the only conflicts left before merges were
+type leftConflict struct {
+	PullRequestID int    `json:"pullRequestId"`
+	Branch        string `json:"branch"`
+	Remote        string `json:"remote"`
+	Worktree      string `json:"worktree"`
+	Upstream      string `json:"upstream,omitempty"`
+	Operation     string `json:"operation,omitempty"`
+	FetchedSHA    string `json:"fetchedSha,omitempty"`
+}
+
+func (c leftConflict) operation() string {
+	if c.Operation == "" {
+		return "rebase"
+	}
+
+	return c.Operation
+}
+
+// conflictsFile returns the path of the file in the git directory of the clone at path that lists its left conflicts
+// The git directory isn't always path/.git, the clone may be a worktree or use a separate git directory
+func (r rebaser) conflictsFile(path string) (string, error) {
+	output, err := r.cmdWrapper.New(path, "git", "rev-parse", "--git-dir").Output()
+	if err != nil {
+		return "", wrapExitError(err, fmt.Sprintf("Unable to find the git directory of %s", path))
+	}
+
+	gitDir := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(path, gitDir)
+	}
+
+	return filepath.Join(gitDir, "prp-conflicts.json"), nil
+}
+
+func loadConflicts(file string) ([]leftConflict, error) {
+	conflicts := []leftConflict{}
+	conflictsJSON, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return conflicts, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(conflictsJSON, &conflicts)
+	return conflicts, err
+}
+
+func saveConflicts(file string, conflicts []leftConflict) error {
+	if len(conflicts) == 0 {
+		err := os.Remove(file)
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	conflictsJSON, _ := json.MarshalIndent(conflicts, "", "  ")
+	return ioutil.WriteFile(file, conflictsJSON, 0644)
+}
+
+func (r rebaser) conflictReport(path string) string {
+	report := []string{}
+	failingCommit, err := r.cmdWrapper.New(path, "git", "log", "-1", "--format=%h %s", "REBASE_HEAD").Output()
+	if err == nil && strings.TrimSpace(string(failingCommit)) != "" {
+		report = append(report, fmt.Sprintf("Conflict while applying %s", strings.TrimSpace(string(failingCommit))))
+	}
+
+	conflictedFiles, err := r.cmdWrapper.New(path, "git", "diff", "--name-only", "--diff-filter=U").Output()
+	if err == nil && strings.TrimSpace(string(conflictedFiles)) != "" {
+		report = append(report, "Conflicted files:")
+		for _, file := range strings.Split(strings.TrimSpace(string(conflictedFiles)), "\n") {
+			report = append(report, fmt.Sprintf("  %s", file))
+		}
+	}
+
+	diffStat, err := r.cmdWrapper.New(path, "git", "diff", "--shortstat").Output()
+	if err == nil && strings.TrimSpace(string(diffStat)) != "" {
+		report = append(report, strings.TrimSpace(string(diffStat)))
+	}
+
+	return strings.Join(report, "\n")
+}
+
+// leaveConflicts retries command, a git rebase or merge, in a worktree and leaves it conflicted there
+// upstreamBranch is recorded as the new base of the branch so the signatures of the new commits can be verified when it is continued
+func (r rebaser) leaveConflicts(path, ownedRemote, upstreamBranch string, command []string, pr *pullRequest) error {
+	operation := command[1]
+	worktree := fmt.Sprintf("%s-prp-%d", path, pr.PullRequestID)
+	fmt.Fprintf(r.verboseWriter, "Leaving the conflicted %s in %s\n", operation, worktree)
+	remoteBranch := fmt.Sprintf("%s/%s", ownedRemote, pr.Branch)
+	fetchedSHA, err := r.cmdWrapper.New(path, "git", "rev-parse", remoteBranch).Output()
+	if err != nil {
+		return wrapExitError(err, fmt.Sprintf("Unable to resolve the head of %s", remoteBranch))
+	}
+
+	err = r.runCommand(path, "git", "worktree", "add", "--detach", worktree, strings.TrimSpace(string(fetchedSHA)))
+	if err != nil {
+		return wrapExitError(err, fmt.Sprintf("Unable to create worktree %s", worktree))
+	}
+
+	err = r.runCommand(worktree, command...)
+	if err == nil {
+		_ = r.runCommand(path, "git", "worktree", "remove", "--force", worktree)
+		return fmt.Errorf("The %s did not conflict when it was retried in a worktree, try again", operation)
+	}
+
+	file, err := r.conflictsFile(path)
+	if err != nil {
+		return err
+	}
+
+	conflicts, err := loadConflicts(file)
+	if err != nil {
+		return fmt.Errorf("Unable to load conflicts from %s\n%v", file, err)
+	}
+
+	conflict := leftConflict{
+		PullRequestID: pr.PullRequestID,
+		Branch:        pr.Branch,
+		Remote:        ownedRemote,
+		Worktree:      worktree,
+		Upstream:      upstreamBranch,
+		FetchedSHA:    strings.TrimSpace(string(fetchedSHA)),
+	}
+	if operation != "rebase" {
+		conflict.Operation = operation
+	}
+
+	conflicts = append(conflicts, conflict)
+	err = saveConflicts(file, conflicts)
+	if err != nil {
+		return fmt.Errorf("Unable to save conflicts to %s\n%v", file, err)
+	}
+
+	return fmt.Errorf("Resolve the conflicts in %s and then run 'prp auto-rebase --continue'", worktree)
+}
+
+func (r rebaser) continueRebases(profile *config.Profile, repos []string, pullRequestNumber int) error {
+	var completeError error
+	for _, repo := range profile.TrackedRepos {
+		fullRepoName := fmt.Sprintf("%s/%s", repo.Owner, repo.Name)
+		if repo.LocalPath == "" || (len(repos) != 0 && !stringSliceContains(fullRepoName, repos)) {
+			continue
+		}
+
+		file, err := r.conflictsFile(repo.LocalPath)
+		if err != nil {
+			fmt.Fprintf(r.errorWriter, "Could not load conflicts in %s because: %v\n", fullRepoName, err)
+			completeError = cli.NewExitError("Unable to continue all rebases", 1)
+			continue
+		}
+
+		conflicts, err := loadConflicts(file)
+		if err != nil {
+			fmt.Fprintf(r.errorWriter, "Could not load conflicts in %s because: %v\n", fullRepoName, err)
+			completeError = cli.NewExitError("Unable to continue all rebases", 1)
+			continue
+		}
+
+		remainingConflicts := []leftConflict{}
+		for _, conflict := range conflicts {
+			if pullRequestNumber != 0 && conflict.PullRequestID != pullRequestNumber {
+				remainingConflicts = append(remainingConflicts, conflict)
+				continue
+			}
+
+			err = r.continueRebase(repo.LocalPath, fullRepoName, repo.SignCommits, conflict)
+			if err != nil {
+				r.record(journalEntry{
+					Repo:          fullRepoName,
+					PullRequestID: conflict.PullRequestID,
+					Branch:        conflict.Branch,
+					Path:          repo.LocalPath,
+					Remote:        conflict.Remote,
+					Outcome:       outcomeFailed,
+					Error:         err.Error(),
+				})
+				fmt.Fprintf(r.errorWriter, "Could not continue rebase of PR #%d in %s because: %v\n", conflict.PullRequestID, fullRepoName, err)
+				completeError = cli.NewExitError("Unable to continue all rebases", 1)
+				remainingConflicts = append(remainingConflicts, conflict)
+			}
+		}
+
+		err = saveConflicts(file, remainingConflicts)
+		if err != nil {
+			fmt.Fprintf(r.errorWriter, "Could not save conflicts in %s because: %v\n", fullRepoName, err)
+			completeError = cli.NewExitError("Unable to continue all rebases", 1)
+		}
+	}
+
+	return completeError
+}
+
+func (r rebaser) continueRebase(path, fullRepoName string, signCommits bool, conflict leftConflict) error {
+	operation := conflict.operation()
+	fmt.Fprintf(r.verboseWriter, "Continuing %s in %s\n", operation, conflict.Worktree)
+	err := r.runCommand(conflict.Worktree, "git", "-c", "core.editor=true", operation, "--continue")
+	if err != nil {
+		return wrapExitError(err, fmt.Sprintf("Unable to continue the %s in %s, conflicts may remain", operation, conflict.Worktree))
+	}
+
+	myRemoteBranch := fmt.Sprintf("%s/%s", conflict.Remote, conflict.Branch)
+	oldSHA, newSHA, err := r.getHeads(conflict.Worktree, myRemoteBranch)
+	if err != nil {
+		return err
+	}
+
+	if signCommits {
+		if conflict.Upstream == "" {
+			return fmt.Errorf("Unable to verify the signatures in %s because the rebase base was not recorded", conflict.Worktree)
+		}
+
+		err = r.verifySignatures(conflict.Worktree, fmt.Sprintf("%s..HEAD", conflict.Upstream))
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(r.verboseWriter, "Pushing to %s\n", myRemoteBranch)
+	pushCommand := []string{"git", "push", conflict.Remote, fmt.Sprintf("HEAD:%s", conflict.Branch)}
+	if operation == "rebase" {
+		// Conflicts left before the fetched head was recorded can only be leased against the remote branch
+		lease := conflict.FetchedSHA
+		if lease == "" {
+			lease = oldSHA
+		}
+
+		pushCommand = append(pushCommand, fmt.Sprintf("--force-with-lease=%s:%s", conflict.Branch, lease))
+	}
+
+	err = r.runCommand(conflict.Worktree, pushCommand...)
+	if err != nil {
+		return wrapExitError(err, fmt.Sprintf("Unable to push to %s", myRemoteBranch))
+	}
+
+	r.record(journalEntry{
+		Repo:          fullRepoName,
+		PullRequestID: conflict.PullRequestID,
+		Branch:        conflict.Branch,
+		Path:          path,
+		Remote:        conflict.Remote,
+		OldSHA:        oldSHA,
+		NewSHA:        newSHA,
+		Outcome:       outcomePushed,
+	})
+
+	fmt.Fprintf(r.verboseWriter, "Removing worktree %s\n", conflict.Worktree)
+	err = r.runCommand(path, "git", "worktree", "remove", "--force", conflict.Worktree)
+	if err != nil {
+		fmt.Fprintf(r.errorWriter, "%v\nWarning: Could not remove worktree %s\n", wrapExitError(err, ""), conflict.Worktree)
+	}
+
+	return nil
+}
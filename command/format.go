@@ -1,6 +1,7 @@
 package command
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
 	"io"
@@ -173,3 +174,10 @@ func checkPath(localPath string) error {
 
 	return nil
 }
+
+func confirm(reader io.Reader, writer io.Writer, prompt string) bool {
+	fmt.Fprintf(writer, "%s [y/N] ", prompt)
+	answer, _ := bufio.NewReader(reader).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
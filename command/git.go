@@ -0,0 +1,155 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/guywithnose/runner"
+	"github.com/urfave/cli"
+)
+
+// Git backends
+const (
+	gitBackendCLI    = "cli"
+	gitBackendNative = "native"
+)
+
+func useNativeGit(backend string) (bool, error) {
+	switch backend {
+	case "", gitBackendCLI:
+		return false, nil
+	case gitBackendNative:
+		return true, nil
+	}
+
+	return false, cli.NewExitError(fmt.Sprintf("Invalid git backend: %s (must be cli or native)", backend), 1)
+}
+
+// newGitBackend returns the gitBackend that reads local clones with git or natively
+func newGitBackend(native bool, cmdWrapper runner.Builder, verboseWriter io.Writer) gitBackend {
+	var git gitBackend = cliGit{cmdWrapper: cmdWrapper}
+	if native {
+		git = nativeGit{fallback: git, verboseWriter: verboseWriter}
+	}
+
+	return git
+}
+
+// gitBackend inspects a local clone
+type gitBackend interface {
+	// remotes maps "url (fetch)" and "url (push)" to the name of the remote, like the output of 'git remote -v'
+	remotes(path string) (map[string]string, error)
+	// currentBranch returns the checked out branch or the checked out commit if HEAD is detached
+	currentBranch(path string) (string, error)
+}
+
+// cliGit inspects a local clone by running git
+type cliGit struct {
+	cmdWrapper runner.Builder
+}
+
+func (g cliGit) remotes(path string) (map[string]string, error) {
+	getRemotes := g.cmdWrapper.New(path, "git", "remote", "-v")
+	remotesOutput, err := getRemotes.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to analyze remotes in %s\n%s", path, string(remotesOutput))
+	}
+
+	return parseRemotes(strings.Split(string(remotesOutput), "\n")), nil
+}
+
+func (g cliGit) currentBranch(path string) (string, error) {
+	getCurrentBranch := g.cmdWrapper.New(path, "git", "symbolic-ref", "HEAD")
+	currentBranchOutput, err := getCurrentBranch.CombinedOutput()
+	if err != nil {
+		code := getErrorCode(err)
+		if code == 128 {
+			getCurrentBranch = g.cmdWrapper.New(path, "git", "rev-parse", "HEAD")
+			currentBranchOutput, err = getCurrentBranch.CombinedOutput()
+			if err != nil {
+				return "", fmt.Errorf("No branch checked out in %s\n%s", path, string(currentBranchOutput))
+			}
+
+			return strings.Replace(strings.Replace(string(currentBranchOutput), "refs/heads/", "", -1), "\n", "", -1), nil
+		}
+
+		return "", fmt.Errorf("%s\n%s", err, string(currentBranchOutput))
+	}
+
+	return strings.Replace(strings.Replace(string(currentBranchOutput), "refs/heads/", "", -1), "\n", "", -1), nil
+}
+
+func parseRemotes(lines []string) map[string]string {
+	remotes := make(map[string]string)
+	for _, line := range lines {
+		parts := strings.Split(line, "\t")
+		if len(parts) == 2 {
+			remotes[parts[1]] = parts[0]
+		}
+	}
+
+	return remotes
+}
+
+// errUnsupportedRepo means nativeGit can't read a clone and the fallback has to be used
+var errUnsupportedRepo = errors.New("unsupported repository layout")
+
+// nativeGit reads the checked out branch of a local clone straight from .git/HEAD
+// go-git isn't vendored, so the remotes still come from 'git remote -v' and everything that changes the clone runs git
+// Layouts it doesn't understand (worktrees, submodules, ...) are handed to the fallback
+type nativeGit struct {
+	fallback      gitBackend
+	verboseWriter io.Writer
+}
+
+func (g nativeGit) remotes(path string) (map[string]string, error) {
+	return g.fallback.remotes(path)
+}
+
+func (g nativeGit) currentBranch(path string) (string, error) {
+	branch, err := readHead(path)
+	if err == errUnsupportedRepo {
+		fmt.Fprintf(g.verboseWriter, "Unable to read HEAD in %s, falling back to git\n", path)
+		return g.fallback.currentBranch(path)
+	}
+
+	return branch, err
+}
+
+func gitDir(path string) (string, error) {
+	dir := fmt.Sprintf("%s/.git", path)
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		// .git is a file in worktrees and submodules
+		return "", errUnsupportedRepo
+	}
+
+	return dir, nil
+}
+
+func readHead(path string) (string, error) {
+	dir, err := gitDir(path)
+	if err != nil {
+		return "", err
+	}
+
+	head, err := ioutil.ReadFile(fmt.Sprintf("%s/HEAD", dir))
+	if err != nil {
+		return "", errUnsupportedRepo
+	}
+
+	ref := strings.TrimSpace(string(head))
+	if strings.HasPrefix(ref, "ref: refs/heads/") {
+		return strings.TrimPrefix(ref, "ref: refs/heads/"), nil
+	}
+
+	if strings.HasPrefix(ref, "ref: ") {
+		return "", errUnsupportedRepo
+	}
+
+	return ref, nil
+}
@@ -2,23 +2,37 @@ package command
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/url"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/google/go-github/github"
+	"github.com/google/go-querystring/query"
 	"github.com/gregjones/httpcache"
 	"github.com/gregjones/httpcache/diskcache"
 	"golang.org/x/oauth2"
 )
 
 func getGithubClient(token, apiURL *string, useCache bool) (*github.Client, error) {
+	if useCache {
+		return getCachingGithubClient(token, apiURL, diskcache.New(fmt.Sprintf("%s/prpCache", os.TempDir())))
+	}
+
+	return getCachingGithubClient(token, apiURL, nil)
+}
+
+// getCachingGithubClient returns a client that stores responses in cache and revalidates them with conditional requests
+// A nil cache disables caching
+func getCachingGithubClient(token, apiURL *string, cache httpcache.Cache) (*github.Client, error) {
 	tokenSource := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: *token})
 	tokenClient := oauth2.NewClient(context.Background(), tokenSource)
 
-	if useCache {
-		cache := diskcache.New(fmt.Sprintf("%s/prpCache", os.TempDir()))
+	if cache != nil {
 		transport := httpcache.NewTransport(cache)
 		transport.Transport = tokenClient.Transport
 		tokenClient.Transport = transport
@@ -37,16 +51,74 @@ func getGithubClient(token, apiURL *string, useCache bool) (*github.Client, erro
 	return client, nil
 }
 
-func getRepoPullRequests(client *github.Client, owner, name string) (<-chan *github.PullRequest, <-chan error) {
+// githubPullRequest adds the fields that the vendored go-github does not know about to github.PullRequest
+type githubPullRequest struct {
+	*github.PullRequest
+	Draft *bool `json:"draft,omitempty"`
+}
+
+// GetDraft returns the Draft field if it's non-nil, zero value otherwise.
+func (pr *githubPullRequest) GetDraft() bool {
+	if pr == nil || pr.Draft == nil {
+		return false
+	}
+
+	return *pr.Draft
+}
+
+func listPullRequests(client *github.Client, owner, name string, opt *github.PullRequestListOptions) ([]*githubPullRequest, *github.Response, error) {
+	values, err := query.Values(opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	u := fmt.Sprintf("repos/%s/%s/pulls", owner, name)
+	if encoded := values.Encode(); encoded != "" {
+		u = fmt.Sprintf("%s?%s", u, encoded)
+	}
+
+	req, err := client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Older Github Enterprise versions only include the draft field with the preview media type
+	req.Header.Set("Accept", "application/vnd.github.shadow-cat-preview+json")
+	var pullRequests []*githubPullRequest
+	resp, err := client.Do(context.Background(), req, &pullRequests)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return pullRequests, resp, nil
+}
+
+func getPullRequest(client *github.Client, owner, name string, number int) (*githubPullRequest, *github.Response, error) {
+	req, err := client.NewRequest("GET", fmt.Sprintf("repos/%s/%s/pulls/%d", owner, name, number), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req.Header.Set("Accept", "application/vnd.github.shadow-cat-preview+json")
+	pullRequest := &githubPullRequest{}
+	resp, err := client.Do(context.Background(), req, pullRequest)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return pullRequest, resp, nil
+}
+
+func getRepoPullRequests(client *github.Client, owner, name string) (<-chan *githubPullRequest, <-chan error) {
 	opt := &github.PullRequestListOptions{
 		ListOptions: github.ListOptions{PerPage: 100},
 	}
 
-	allPrs := make(chan *github.PullRequest, 100)
+	allPrs := make(chan *githubPullRequest, 100)
 	errors := make(chan error, 1)
 	go func() {
 		for {
-			pullRequests, resp, err := client.PullRequests.List(context.Background(), owner, name, opt)
+			pullRequests, resp, err := listPullRequests(client, owner, name, opt)
 			if err != nil {
 				errors <- err
 				close(errors)
@@ -91,7 +163,7 @@ func getAllRepos(client *github.Client, login string) []*github.Repository {
 	}
 }
 
-func getRepoPullRequestsAndReportErrors(client *github.Client, owner, name string, errWriter io.Writer) <-chan *github.PullRequest {
+func getRepoPullRequestsAndReportErrors(client *github.Client, owner, name string, errWriter io.Writer) <-chan *githubPullRequest {
 	repoPrs, errors := getRepoPullRequests(client, owner, name)
 	go func() {
 		for {
@@ -106,3 +178,243 @@ func getRepoPullRequestsAndReportErrors(client *github.Client, owner, name strin
 
 	return repoPrs
 }
+
+func updatePullRequestBranch(client *github.Client, owner, name string, number int, expectedHeadSHA string) error {
+	body := map[string]string{"expected_head_sha": expectedHeadSHA}
+	req, err := client.NewRequest("PUT", fmt.Sprintf("repos/%s/%s/pulls/%d/update-branch", owner, name, number), body)
+	if err != nil {
+		return err
+	}
+
+	// Older Github Enterprise versions only expose this endpoint through the preview media type
+	req.Header.Set("Accept", "application/vnd.github.lydian-preview+json")
+	_, err = client.Do(context.Background(), req, nil)
+	return err
+}
+
+type graphQLError struct {
+	Message string `json:"message"`
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []graphQLError  `json:"errors"`
+}
+
+func graphQLURL(client *github.Client) string {
+	// Github Enterprise serves the v3 API from /api/v3/ and GraphQL from /api/graphql
+	if strings.HasSuffix(client.BaseURL.Path, "/v3/") {
+		return "../graphql"
+	}
+
+	return "graphql"
+}
+
+func runGraphQL(client *github.Client, query string, variables map[string]interface{}, data interface{}) error {
+	body := map[string]interface{}{"query": query, "variables": variables}
+	req, err := client.NewRequest("POST", graphQLURL(client), body)
+	if err != nil {
+		return err
+	}
+
+	response := new(graphQLResponse)
+	_, err = client.Do(context.Background(), req, response)
+	if err != nil {
+		return err
+	}
+
+	if len(response.Errors) != 0 {
+		messages := make([]string, 0, len(response.Errors))
+		for _, graphQLErr := range response.Errors {
+			messages = append(messages, graphQLErr.Message)
+		}
+
+		return errors.New(strings.Join(messages, "\n"))
+	}
+
+	if data == nil {
+		return nil
+	}
+
+	return json.Unmarshal(response.Data, data)
+}
+
+func getPullRequestNodeID(client *github.Client, owner, name string, number int) (string, error) {
+	query := `query($owner: String!, $name: String!, $number: Int!) {
+  repository(owner: $owner, name: $name) { pullRequest(number: $number) { id } }
+}`
+	var data struct {
+		Repository struct {
+			PullRequest struct {
+				ID string `json:"id"`
+			} `json:"pullRequest"`
+		} `json:"repository"`
+	}
+
+	err := runGraphQL(client, query, map[string]interface{}{"owner": owner, "name": name, "number": number}, &data)
+	if err != nil {
+		return "", err
+	}
+
+	return data.Repository.PullRequest.ID, nil
+}
+
+func rebasePullRequestBranch(client *github.Client, owner, name string, number int, expectedHeadSHA string) error {
+	nodeID, err := getPullRequestNodeID(client, owner, name, number)
+	if err != nil {
+		return err
+	}
+
+	mutation := `mutation($id: ID!, $sha: GitObjectID) {
+  updatePullRequestBranch(input: {pullRequestId: $id, expectedHeadOid: $sha, updateMethod: REBASE}) { pullRequest { id } }
+}`
+	return runGraphQL(client, mutation, map[string]interface{}{"id": nodeID, "sha": expectedHeadSHA}, nil)
+}
+
+// checkRun is the part of a Github check run that rerun needs, the vendored go-github does not know about the checks API
+type checkRun struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+	App        struct {
+		Slug string `json:"slug"`
+	} `json:"app"`
+}
+
+// githubActionsApp is the app that creates the check runs of Github Actions jobs
+const githubActionsApp = "github-actions"
+
+func listCheckRuns(client *github.Client, owner, name, sha string) ([]*checkRun, error) {
+	opt := &github.ListOptions{PerPage: 100}
+	allCheckRuns := []*checkRun{}
+	for {
+		values, err := query.Values(opt)
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := client.NewRequest("GET", fmt.Sprintf("repos/%s/%s/commits/%s/check-runs?%s", owner, name, sha, values.Encode()), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		// Older Github Enterprise versions only expose the checks API through the preview media type
+		req.Header.Set("Accept", "application/vnd.github.antiope-preview+json")
+		var response struct {
+			CheckRuns []*checkRun `json:"check_runs"`
+		}
+		resp, err := client.Do(context.Background(), req, &response)
+		if err != nil {
+			return nil, err
+		}
+
+		allCheckRuns = append(allCheckRuns, response.CheckRuns...)
+		if resp.NextPage == 0 {
+			return allCheckRuns, nil
+		}
+
+		opt.Page = resp.NextPage
+	}
+}
+
+// rerunCheckRun asks for run to be run again
+// Github Actions jobs are rerun directly, other apps are asked to rerequest the check run
+func rerunCheckRun(client *github.Client, owner, name string, run *checkRun) error {
+	u := fmt.Sprintf("repos/%s/%s/check-runs/%d/rerequest", owner, name, run.ID)
+	if run.App.Slug == githubActionsApp {
+		// The check run of a Github Actions job shares its id with the job
+		u = fmt.Sprintf("repos/%s/%s/actions/jobs/%d/rerun", owner, name, run.ID)
+	}
+
+	req, err := client.NewRequest("POST", u, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/vnd.github.antiope-preview+json")
+	_, err = client.Do(context.Background(), req, nil)
+	return err
+}
+
+// timelineEvent is the part of a pull request's timeline event that stale needs
+// The vendored go-github does not know about the dates of commits and reviews in the timeline
+type timelineEvent struct {
+	Event       string       `json:"event"`
+	CreatedAt   *time.Time   `json:"created_at,omitempty"`
+	SubmittedAt *time.Time   `json:"submitted_at,omitempty"`
+	Body        string       `json:"body,omitempty"`
+	User        *github.User `json:"user,omitempty"`
+	Committer   *struct {
+		Date *time.Time `json:"date,omitempty"`
+	} `json:"committer,omitempty"`
+}
+
+// Time returns when the event happened or the zero time if the timeline doesn't say
+func (event timelineEvent) Time() time.Time {
+	switch {
+	case event.CreatedAt != nil:
+		return *event.CreatedAt
+	case event.SubmittedAt != nil:
+		return *event.SubmittedAt
+	case event.Committer != nil && event.Committer.Date != nil:
+		return *event.Committer.Date
+	}
+
+	return time.Time{}
+}
+
+func listTimeline(client *github.Client, owner, name string, number int) ([]*timelineEvent, error) {
+	opt := &github.ListOptions{PerPage: 100}
+	allEvents := []*timelineEvent{}
+	for {
+		values, err := query.Values(opt)
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := client.NewRequest("GET", fmt.Sprintf("repos/%s/%s/issues/%d/timeline?%s", owner, name, number, values.Encode()), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		// Older Github Enterprise versions only expose the timeline through the preview media type
+		req.Header.Set("Accept", "application/vnd.github.mockingbird-preview+json")
+		var events []*timelineEvent
+		resp, err := client.Do(context.Background(), req, &events)
+		if err != nil {
+			return nil, err
+		}
+
+		allEvents = append(allEvents, events...)
+		if resp.NextPage == 0 {
+			return allEvents, nil
+		}
+
+		opt.Page = resp.NextPage
+	}
+}
+
+func markPullRequestReady(client *github.Client, owner, name string, number int) error {
+	nodeID, err := getPullRequestNodeID(client, owner, name, number)
+	if err != nil {
+		return err
+	}
+
+	mutation := `mutation($id: ID!) {
+  markPullRequestReadyForReview(input: {pullRequestId: $id}) { pullRequest { id } }
+}`
+	return runGraphQL(client, mutation, map[string]interface{}{"id": nodeID}, nil)
+}
+
+func convertPullRequestToDraft(client *github.Client, owner, name string, number int) error {
+	nodeID, err := getPullRequestNodeID(client, owner, name, number)
+	if err != nil {
+		return err
+	}
+
+	mutation := `mutation($id: ID!) {
+  convertPullRequestToDraft(input: {pullRequestId: $id}) { pullRequest { id } }
+}`
+	return runGraphQL(client, mutation, map[string]interface{}{"id": nodeID}, nil)
+}
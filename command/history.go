@@ -0,0 +1,129 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+// journalOutcomes lists every outcome auto-rebase records in the journal
+var journalOutcomes = []string{outcomePushed, outcomeUpdated, outcomeFailed, outcomeUndone}
+
+// CmdHistory shows what auto-rebase has done
+func CmdHistory(c *cli.Context) error {
+	_, err := loadConfig(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() != 0 {
+		return cli.NewExitError("Usage: \"prp history\"", 1)
+	}
+
+	outcomes := c.StringSlice("outcome")
+	for _, outcome := range outcomes {
+		if !stringSliceContains(outcome, journalOutcomes) {
+			return cli.NewExitError(fmt.Sprintf("Invalid outcome: %s (must be one of %s)", outcome, strings.Join(journalOutcomes, ", ")), 1)
+		}
+	}
+
+	journal := newJournal(journalFile(c))
+	entries, err := journal.entries()
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Unable to read journal %s\n%v", journal.path, err), 1)
+	}
+
+	var since time.Time
+	if c.Duration("since") > 0 {
+		since = time.Now().Add(-c.Duration("since"))
+	}
+
+	entries = filterJournalEntries(entries, c.StringSlice("repo"), c.Int("pull-request-number"), outcomes, since)
+	if c.Int("limit") > 0 && len(entries) > c.Int("limit") {
+		entries = entries[len(entries)-c.Int("limit"):]
+	}
+
+	if c.Bool("json") {
+		entriesJSON, _ := json.MarshalIndent(entries, "", "  ")
+		fmt.Fprintln(c.App.Writer, string(entriesJSON))
+		return nil
+	}
+
+	printHistory(entries, c.App.Writer)
+	return nil
+}
+
+func filterJournalEntries(entries []journalEntry, repos []string, pullRequestNumber int, outcomes []string, since time.Time) []journalEntry {
+	filtered := []journalEntry{}
+	for _, entry := range entries {
+		if len(repos) != 0 && !stringSliceContains(entry.Repo, repos) {
+			continue
+		}
+
+		if pullRequestNumber != 0 && entry.PullRequestID != pullRequestNumber {
+			continue
+		}
+
+		if len(outcomes) != 0 && !stringSliceContains(entry.Outcome, outcomes) {
+			continue
+		}
+
+		if entry.Time.Before(since) {
+			continue
+		}
+
+		filtered = append(filtered, entry)
+	}
+
+	return filtered
+}
+
+func printHistory(entries []journalEntry, w io.Writer) {
+	buffer := &bytes.Buffer{}
+	tabW := tabwriter.NewWriter(buffer, 0, 0, 0, ' ', tabwriter.Debug)
+	fmt.Fprintln(tabW, "Time\tRepo\tID\tBranch\tOutcome\tOld\tNew\tError")
+	for _, entry := range entries {
+		fmt.Fprintf(
+			tabW,
+			"%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.Time.Format("2006-01-02 15:04:05"),
+			entry.Repo,
+			strconv.Itoa(entry.PullRequestID),
+			entry.Branch,
+			entry.Outcome,
+			entry.OldSHA,
+			entry.NewSHA,
+			strings.Split(entry.Error, "\n")[0],
+		)
+	}
+
+	_ = tabW.Flush()
+	fmt.Fprint(w, buffer.String())
+	fmt.Fprintf(w, "Total %d\n", len(entries))
+}
+
+// CompleteHistory handles bash autocompletion for the 'history' command
+func CompleteHistory(c *cli.Context) {
+	lastParam := os.Args[len(os.Args)-2]
+	switch lastParam {
+	case "--repo":
+		configData, profileName, err := loadProfile(c)
+		if err != nil {
+			return
+		}
+
+		completeRepo(c.StringSlice("repo"), configData.Profiles[*profileName], c.App.Writer)
+	case "--outcome":
+		fmt.Fprintln(c.App.Writer, strings.Join(journalOutcomes, "\n"))
+	default:
+		completeFlags(c, "history")
+	}
+}
@@ -0,0 +1,235 @@
+package command_test
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guywithnose/pull-request-parser/command"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+func TestCmdHistory(t *testing.T) {
+	configFileName := writeHistoryJournal(t)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	set := getBaseFlagSet(configFileName)
+	app, writer, _ := appWithTestWriters()
+	assert.Nil(t, command.CmdHistory(cli.NewContext(app, set, nil)))
+	assert.Equal(
+		t,
+		strings.Join(
+			[]string{
+				"Time               |Repo   |ID|Branch|Outcome|Old |New |Error",
+				"2017-01-01 00:00:00|own/rep|1 |ref1  |pushed |sha1|sha2|",
+				"2017-01-02 00:00:00|own/rep|1 |ref1  |failed |sha2|    |Unable to rebase against upstream/baseRef1, there may be a conflict",
+				"2017-01-03 00:00:00|foo/bar|2 |ref2  |updated|sha4|    |",
+				"2017-01-04 00:00:00|own/rep|1 |ref1  |failed |sha2|    |Unable to rebase against upstream/baseRef1, there may be a conflict",
+				"2017-01-05 00:00:00|own/rep|1 |ref1  |undone |sha2|sha1|",
+				"Total 5",
+				"",
+			},
+			"\n",
+		),
+		writer.String(),
+	)
+}
+
+func TestCmdHistoryFilters(t *testing.T) {
+	var testCases = []struct {
+		name     string
+		setFlags func(*flag.FlagSet)
+		dates    []string
+	}{
+		{
+			"Repo",
+			func(set *flag.FlagSet) {
+				repos := cli.StringSlice{"foo/bar"}
+				set.Var(&repos, "repo", "doc")
+			},
+			[]string{"2017-01-03"},
+		},
+		{
+			"PullRequestNumber",
+			func(set *flag.FlagSet) { set.Int("pull-request-number", 1, "doc") },
+			[]string{"2017-01-01", "2017-01-02", "2017-01-04", "2017-01-05"},
+		},
+		{
+			"Outcome",
+			func(set *flag.FlagSet) {
+				outcomes := cli.StringSlice{"failed", "undone"}
+				set.Var(&outcomes, "outcome", "doc")
+			},
+			[]string{"2017-01-02", "2017-01-04", "2017-01-05"},
+		},
+		{
+			"Limit",
+			func(set *flag.FlagSet) { set.Int("limit", 2, "doc") },
+			[]string{"2017-01-04", "2017-01-05"},
+		},
+		{
+			"Since",
+			func(set *flag.FlagSet) {
+				set.Duration("since", time.Since(time.Date(2017, 1, 3, 12, 0, 0, 0, time.UTC)), "doc")
+			},
+			[]string{"2017-01-04", "2017-01-05"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			configFileName := writeHistoryJournal(t)
+			defer removeFile(t, configFileName)
+			defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+			set := getBaseFlagSet(configFileName)
+			tc.setFlags(set)
+			app, writer, _ := appWithTestWriters()
+			assert.Nil(t, command.CmdHistory(cli.NewContext(app, set, nil)))
+			lines := strings.Split(strings.TrimSpace(writer.String()), "\n")
+			dates := []string{}
+			for _, line := range lines[1 : len(lines)-1] {
+				dates = append(dates, line[:10])
+			}
+
+			assert.Equal(t, tc.dates, dates)
+			assert.Equal(t, fmt.Sprintf("Total %d", len(tc.dates)), lines[len(lines)-1])
+		})
+	}
+}
+
+func TestCmdHistoryJSON(t *testing.T) {
+	configFileName := writeHistoryJournal(t)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	set := getBaseFlagSet(configFileName)
+	set.Bool("json", true, "doc")
+	set.Int("limit", 1, "doc")
+	app, writer, _ := appWithTestWriters()
+	assert.Nil(t, command.CmdHistory(cli.NewContext(app, set, nil)))
+	assert.Equal(
+		t,
+		`[
+  {
+    "time": "2017-01-05T00:00:00Z",
+    "repo": "own/rep",
+    "pullRequestId": 1,
+    "branch": "ref1",
+    "oldSha": "sha2",
+    "newSha": "sha1",
+    "outcome": "undone"
+  }
+]
+`,
+		writer.String(),
+	)
+}
+
+func TestCmdHistoryNoJournal(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	app, writer, _ := appWithTestWriters()
+	assert.Nil(t, command.CmdHistory(cli.NewContext(app, set, nil)))
+	assert.Equal(t, "Time|Repo|ID|Branch|Outcome|Old|New|Error\nTotal 0\n", writer.String())
+}
+
+func TestCmdHistoryInvalidJournal(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	assert.Nil(t, ioutil.WriteFile(fmt.Sprintf("%s.journal", configFileName), []byte("not json\n"), 0644))
+	set := getBaseFlagSet(configFileName)
+	app, _, _ := appWithTestWriters()
+	err := command.CmdHistory(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, fmt.Sprintf("Unable to read journal %s.journal\ninvalid character 'o' in literal null (expecting 'u')", configFileName))
+}
+
+func TestCmdHistoryInvalidOutcome(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	outcomes := cli.StringSlice{"conflicted"}
+	set.Var(&outcomes, "outcome", "doc")
+	app, _, _ := appWithTestWriters()
+	err := command.CmdHistory(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Invalid outcome: conflicted (must be one of pushed, updated, failed, undone)")
+}
+
+func TestCmdHistoryNoConfig(t *testing.T) {
+	app, _, _ := appWithTestWriters()
+	err := command.CmdHistory(cli.NewContext(app, flag.NewFlagSet("test", 0), nil))
+	assert.EqualError(t, err, "You must specify a config file")
+}
+
+func TestCmdHistoryUsage(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"foo"}))
+	app, _, _ := appWithTestWriters()
+	err := command.CmdHistory(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Usage: \"prp history\"")
+}
+
+func TestCompleteHistoryFlags(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	app, writer, _ := appWithTestWriters()
+	app.Commands = []cli.Command{
+		{
+			Name: "history",
+			Flags: []cli.Flag{
+				cli.StringSliceFlag{Name: "repo, r"},
+				cli.BoolFlag{Name: "json"},
+			},
+		},
+	}
+	os.Args = []string{"history", "--completion"}
+	command.CompleteHistory(cli.NewContext(app, set, nil))
+	assert.Equal(t, "--repo\n--json\n", writer.String())
+}
+
+func TestCompleteHistoryRepo(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	app, writer, _ := appWithTestWriters()
+	os.Args = []string{"history", "--repo", "--completion"}
+	command.CompleteHistory(cli.NewContext(app, set, nil))
+	assert.Equal(t, "foo/bar\nown/rep\n", writer.String())
+}
+
+func TestCompleteHistoryOutcome(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	app, writer, _ := appWithTestWriters()
+	os.Args = []string{"history", "--outcome", "--completion"}
+	command.CompleteHistory(cli.NewContext(app, set, nil))
+	assert.Equal(t, "pushed\nupdated\nfailed\nundone\n", writer.String())
+}
+
+func writeHistoryJournal(t *testing.T) string {
+	t.Helper()
+	_, configFileName := getConfigWithFooProfile(t)
+	conflict := "Unable to rebase against upstream/baseRef1, there may be a conflict\\nconflict"
+	journal := strings.Join(
+		[]string{
+			`{"time":"2017-01-01T00:00:00Z","repo":"own/rep","pullRequestId":1,"branch":"ref1","oldSha":"sha1","newSha":"sha2","outcome":"pushed"}`,
+			fmt.Sprintf(`{"time":"2017-01-02T00:00:00Z","repo":"own/rep","pullRequestId":1,"branch":"ref1","oldSha":"sha2","outcome":"failed","error":"%s"}`, conflict),
+			`{"time":"2017-01-03T00:00:00Z","repo":"foo/bar","pullRequestId":2,"branch":"ref2","oldSha":"sha4","outcome":"updated"}`,
+			fmt.Sprintf(`{"time":"2017-01-04T00:00:00Z","repo":"own/rep","pullRequestId":1,"branch":"ref1","oldSha":"sha2","outcome":"failed","error":"%s"}`, conflict),
+			`{"time":"2017-01-05T00:00:00Z","repo":"own/rep","pullRequestId":1,"branch":"ref1","oldSha":"sha2","newSha":"sha1","outcome":"undone"}`,
+			"",
+		},
+		"\n",
+	)
+	assert.Nil(t, ioutil.WriteFile(fmt.Sprintf("%s.journal", configFileName), []byte(journal), 0644))
+	return configFileName
+}
@@ -0,0 +1,30 @@
+package command
+
+import (
+	"fmt"
+)
+
+// runHooks runs each hook through sh in path with the pull request's details in the environment
+func (r rebaser) runHooks(hooks []string, path string, pr *pullRequest, oldSHA, newSHA string) error {
+	for _, hook := range hooks {
+		fmt.Fprintf(r.verboseWriter, "Running hook: %s\n", hook)
+		err := r.runCommand(
+			path,
+			"env",
+			fmt.Sprintf("PRP_REPO=%s/%s", pr.Repo.Owner, pr.Repo.Name),
+			fmt.Sprintf("PRP_PULL_REQUEST_NUMBER=%d", pr.PullRequestID),
+			fmt.Sprintf("PRP_BRANCH=%s", pr.Branch),
+			fmt.Sprintf("PRP_TARGET_BRANCH=%s", pr.TargetBranch),
+			fmt.Sprintf("PRP_OLD_SHA=%s", oldSHA),
+			fmt.Sprintf("PRP_NEW_SHA=%s", newSHA),
+			"sh",
+			"-c",
+			hook,
+		)
+		if err != nil {
+			return wrapExitError(err, fmt.Sprintf("Hook failed: %s", hook))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package command
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// Journal outcomes
+const (
+	outcomePushed  = "pushed"
+	outcomeUpdated = "updated"
+	outcomeFailed  = "failed"
+	outcomeUndone  = "undone"
+)
+
+// journalEntry records an attempt by auto-rebase to update a pull request branch
+type journalEntry struct {
+	Time          time.Time `json:"time"`
+	Repo          string    `json:"repo"`
+	PullRequestID int       `json:"pullRequestId"`
+	Branch        string    `json:"branch"`
+	Path          string    `json:"path,omitempty"`
+	Remote        string    `json:"remote,omitempty"`
+	OldSHA        string    `json:"oldSha,omitempty"`
+	NewSHA        string    `json:"newSha,omitempty"`
+	Outcome       string    `json:"outcome"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// journal is an append-only log of journalEntries stored as one JSON object per line
+type journal struct {
+	path string
+}
+
+func newJournal(path string) *journal {
+	return &journal{path: path}
+}
+
+func (j journal) record(entry journalEntry) error {
+	entry.Time = time.Now().UTC()
+	entryJSON, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(j.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(append(entryJSON, '\n'))
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func (j journal) entries() ([]journalEntry, error) {
+	entries := []journalEntry{}
+	file, err := os.Open(j.path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry journalEntry
+		err = json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
@@ -0,0 +1,174 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/github"
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/urfave/cli"
+)
+
+// CmdLabelAdd adds a label to every pull request matching the parse selectors
+func CmdLabelAdd(c *cli.Context) error {
+	return changeLabel(c, true)
+}
+
+// CmdLabelRemove removes a label from every pull request matching the parse selectors
+func CmdLabelRemove(c *cli.Context) error {
+	return changeLabel(c, false)
+}
+
+func changeLabel(c *cli.Context, add bool) error {
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() != 1 {
+		if add {
+			return cli.NewExitError("Usage: \"prp label add {label}\"", 1)
+		}
+
+		return cli.NewExitError("Usage: \"prp label remove {label}\"", 1)
+	}
+
+	label := c.Args().First()
+	profile := configData.Profiles[*profileName]
+	client, err := getGithubClient(&profile.Token, &profile.APIURL, c.Bool("use-cache"))
+	if err != nil {
+		return err
+	}
+
+	user, _, err := client.Users.Get(context.Background(), "")
+	if err != nil {
+		return err
+	}
+
+	parser := newParser(client, user, &profile)
+	prs := parser.parsePullRequests(parser.getBasePullRequestData(c.App.ErrWriter), c.String("owner"), c.StringSlice("repo"), c.Bool("need-rebase"))
+
+	// Pull requests that already have (or don't have) the label are left alone
+	affected := []*pullRequest{}
+	for pr := range prs {
+		if stringSliceContains(label, pr.Labels) != add {
+			affected = append(affected, pr)
+		}
+	}
+
+	if len(affected) == 0 {
+		if add {
+			fmt.Fprintf(c.App.Writer, "No pull requests are missing the label %s\n", label)
+		} else {
+			fmt.Fprintf(c.App.Writer, "No pull requests have the label %s\n", label)
+		}
+
+		return nil
+	}
+
+	if add {
+		fmt.Fprintf(c.App.Writer, "The label %s will be added to:\n", label)
+	} else {
+		fmt.Fprintf(c.App.Writer, "The label %s will be removed from:\n", label)
+	}
+
+	for _, pr := range sortPullRequests(affected) {
+		fmt.Fprintf(c.App.Writer, "  PR #%d in %s/%s: %s\n", pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, pr.Title)
+	}
+
+	if !c.Bool("yes") && !confirm(os.Stdin, c.App.Writer, "Continue?") {
+		return nil
+	}
+
+	var completeError error
+	for _, pr := range affected {
+		if add {
+			_, _, err = client.Issues.AddLabelsToIssue(context.Background(), pr.Repo.Owner, pr.Repo.Name, pr.PullRequestID, []string{label})
+		} else {
+			_, err = client.Issues.RemoveLabelForIssue(context.Background(), pr.Repo.Owner, pr.Repo.Name, pr.PullRequestID, label)
+		}
+
+		if err != nil {
+			fmt.Fprintf(c.App.ErrWriter, "Could not update the labels of PR #%d in %s/%s because: %v\n", pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, err)
+			completeError = cli.NewExitError("Unable to label all pull requests", 1)
+		}
+	}
+
+	return completeError
+}
+
+// CompleteLabelAdd handles bash autocompletion for the 'label add' command
+func CompleteLabelAdd(c *cli.Context) {
+	completeLabel(c, "add")
+}
+
+// CompleteLabelRemove handles bash autocompletion for the 'label remove' command
+func CompleteLabelRemove(c *cli.Context) {
+	completeLabel(c, "remove")
+}
+
+func completeLabel(c *cli.Context, commandName string) {
+	lastParam := os.Args[len(os.Args)-2]
+	if lastParam != "--user" && lastParam != "--repo" && c.NArg() != 0 {
+		completeFlags(c, commandName)
+		return
+	}
+
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return
+	}
+
+	profile := configData.Profiles[*profileName]
+	switch lastParam {
+	case "--user":
+		completeUser(&profile, c.App.Writer, c.App.ErrWriter)
+	case "--repo":
+		completeRepo(c.StringSlice("repo"), profile, c.App.Writer)
+	default:
+		completeRepoLabels(&profile, c.App.Writer)
+	}
+}
+
+// completeRepoLabels lists the labels defined on any of the tracked repos
+func completeRepoLabels(profile *config.Profile, writer io.Writer) {
+	client, err := getGithubClient(&profile.Token, &profile.APIURL, true)
+	if err != nil {
+		return
+	}
+
+	labelChan := make(chan string, 5)
+	go func() {
+		wg := sync.WaitGroup{}
+		for _, repo := range profile.TrackedRepos {
+			wg.Add(1)
+			go func(repo config.Repo) {
+				labels, _, err := client.Issues.ListLabels(context.Background(), repo.Owner, repo.Name, &github.ListOptions{PerPage: 100})
+				if err == nil {
+					for _, label := range labels {
+						labelChan <- label.GetName()
+					}
+				}
+
+				wg.Done()
+			}(repo)
+		}
+
+		wg.Wait()
+		close(labelChan)
+	}()
+
+	labels := []string{}
+	for label := range labelChan {
+		labels = append(labels, label)
+	}
+
+	labels = unique(labels)
+	sort.Strings(labels)
+	fmt.Fprintln(writer, strings.Join(labels, "\n"))
+}
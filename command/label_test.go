@@ -0,0 +1,236 @@
+package command_test
+
+import (
+	"flag"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/guywithnose/pull-request-parser/command"
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+func TestCmdLabelAdd(t *testing.T) {
+	requests := []string{}
+	inner := getParseTestServer("")
+	defer inner.Close()
+	ts := getRecordingOverridingTestServer(inner, &requests, map[string]string{})
+	defer ts.Close()
+	writer, _, err := runAgainstServer(t, ts, command.CmdLabelAdd, func(*config.Profile) {}, withArgs(t, []string{"label1"}, func(set *flag.FlagSet) {
+		set.Bool("yes", true, "doc")
+	}))
+	assert.Nil(t, err)
+	assert.Equal(
+		t,
+		[]string{
+			`POST /repos/foo/bar/issues/1/labels ["label1"]`,
+			`POST /repos/foo/bar/issues/2/labels ["label1"]`,
+			`POST /repos/own/rep/issues/2/labels ["label1"]`,
+		},
+		requests,
+	)
+	assert.Equal(
+		t,
+		strings.Join(
+			[]string{
+				"The label label1 will be added to:",
+				"  PR #1 in foo/bar: fooPrOne",
+				"  PR #2 in foo/bar: fooPrTwo",
+				"  PR #2 in own/rep: Really long Pull Request Title",
+				"",
+			},
+			"\n",
+		),
+		writer.String(),
+	)
+}
+
+func TestCmdLabelAddSelectors(t *testing.T) {
+	var testCases = []struct {
+		name             string
+		setFlags         func(*flag.FlagSet)
+		expectedRequests []string
+	}{
+		{
+			"Owner",
+			func(set *flag.FlagSet) { set.String("owner", "guy2", "doc") },
+			[]string{`POST /repos/own/rep/issues/2/labels ["label1"]`},
+		},
+		{
+			"Repo",
+			func(set *flag.FlagSet) {
+				repos := cli.StringSlice{"own/rep"}
+				set.Var(&repos, "repo", "doc")
+			},
+			[]string{`POST /repos/own/rep/issues/2/labels ["label1"]`},
+		},
+		{
+			"NeedRebase",
+			func(set *flag.FlagSet) { set.Bool("need-rebase", true, "doc") },
+			[]string{`POST /repos/foo/bar/issues/2/labels ["label1"]`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := []string{}
+			inner := getParseTestServer("")
+			defer inner.Close()
+			ts := getRecordingOverridingTestServer(inner, &requests, map[string]string{})
+			defer ts.Close()
+			_, _, err := runAgainstServer(t, ts, command.CmdLabelAdd, func(*config.Profile) {}, withArgs(t, []string{"label1"}, func(set *flag.FlagSet) {
+				set.Bool("yes", true, "doc")
+				tc.setFlags(set)
+			}))
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedRequests, requests)
+		})
+	}
+}
+
+func TestCmdLabelRemove(t *testing.T) {
+	requests := []string{}
+	inner := getParseTestServer("")
+	defer inner.Close()
+	ts := getRecordingOverridingTestServer(inner, &requests, map[string]string{"/repos/foo/bar/issues/1/labels/label2": ""})
+	defer ts.Close()
+	writer, _, err := runAgainstServer(t, ts, command.CmdLabelRemove, func(*config.Profile) {}, withArgs(t, []string{"label2"}, func(set *flag.FlagSet) {
+		set.Bool("yes", true, "doc")
+	}))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DELETE /repos/foo/bar/issues/1/labels/label2"}, requests)
+	assert.Equal(t, "The label label2 will be removed from:\n  PR #1 in foo/bar: fooPrOne\n", writer.String())
+}
+
+func TestCmdLabelDeclined(t *testing.T) {
+	requests := []string{}
+	inner := getParseTestServer("")
+	defer inner.Close()
+	ts := getRecordingOverridingTestServer(inner, &requests, map[string]string{"/repos/foo/bar/issues/1/labels/label2": ""})
+	defer ts.Close()
+	defer replaceStdin(t, "n\n")()
+	writer, _, err := runAgainstServer(t, ts, command.CmdLabelRemove, func(*config.Profile) {}, withArgs(t, []string{"label2"}, func(*flag.FlagSet) {}))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{}, requests)
+	assert.Equal(t, "The label label2 will be removed from:\n  PR #1 in foo/bar: fooPrOne\nContinue? [y/N] ", writer.String())
+}
+
+func TestCmdLabelNothingToChange(t *testing.T) {
+	var testCases = []struct {
+		name           string
+		cmd            func(*cli.Context) error
+		label          string
+		expectedOutput string
+	}{
+		{"Add", command.CmdLabelAdd, "label1", "No pull requests are missing the label label1\n"},
+		{"Remove", command.CmdLabelRemove, "missing", "No pull requests have the label missing\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := []string{}
+			inner := getParseTestServer("")
+			defer inner.Close()
+			ts := getRecordingOverridingTestServer(inner, &requests, map[string]string{})
+			defer ts.Close()
+			writer, _, err := runAgainstServer(t, ts, tc.cmd, func(*config.Profile) {}, withArgs(t, []string{tc.label}, func(set *flag.FlagSet) {
+				set.String("owner", "guy", "doc")
+				set.Bool("yes", true, "doc")
+			}))
+			assert.Nil(t, err)
+			assert.Equal(t, []string{}, requests)
+			assert.Equal(t, tc.expectedOutput, writer.String())
+		})
+	}
+}
+
+func TestCmdLabelFailure(t *testing.T) {
+	requests := []string{}
+	inner := getParseTestServer("/repos/foo/bar/issues/1/labels/label2")
+	defer inner.Close()
+	ts := getRecordingOverridingTestServer(inner, &requests, map[string]string{})
+	defer ts.Close()
+	_, errWriter, err := runAgainstServer(t, ts, command.CmdLabelRemove, func(*config.Profile) {}, withArgs(t, []string{"label2"}, func(set *flag.FlagSet) {
+		set.Bool("yes", true, "doc")
+	}))
+	assert.EqualError(t, err, "Unable to label all pull requests")
+	assert.Equal(t, []string{"DELETE /repos/foo/bar/issues/1/labels/label2"}, requests)
+	assert.Contains(t, errWriter.String(), "Could not update the labels of PR #1 in foo/bar because: ")
+}
+
+func TestCmdLabelNoConfig(t *testing.T) {
+	app, _, _ := appWithTestWriters()
+	err := command.CmdLabelAdd(cli.NewContext(app, flag.NewFlagSet("test", 0), nil))
+	assert.EqualError(t, err, "You must specify a config file")
+}
+
+func TestCmdLabelUsage(t *testing.T) {
+	var testCases = []struct {
+		name  string
+		cmd   func(*cli.Context) error
+		usage string
+	}{
+		{"Add", command.CmdLabelAdd, "Usage: \"prp label add {label}\""},
+		{"Remove", command.CmdLabelRemove, "Usage: \"prp label remove {label}\""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, configFileName := getConfigWithFooProfile(t)
+			defer removeFile(t, configFileName)
+			set := getBaseFlagSet(configFileName)
+			app, _, _ := appWithTestWriters()
+			assert.EqualError(t, tc.cmd(cli.NewContext(app, set, nil)), tc.usage)
+		})
+	}
+}
+
+func TestCompleteLabelAddLabels(t *testing.T) {
+	requests := []string{}
+	inner := getParseTestServer("")
+	defer inner.Close()
+	ts := getRecordingOverridingTestServer(inner, &requests, map[string]string{
+		"/repos/own/rep/labels?per_page=100": `[{"name":"label1"},{"name":"ready"}]`,
+		"/repos/foo/bar/labels?per_page=100": `[{"name":"ready"},{"name":"label2"}]`,
+	})
+	defer ts.Close()
+	_, configFileName := getConfigWithAPIURL(t, ts.URL)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	app, writer, _ := appWithTestWriters()
+	os.Args = []string{"label", "add", "--completion"}
+	command.CompleteLabelAdd(cli.NewContext(app, set, nil))
+	assert.Equal(t, "label1\nlabel2\nready\n", writer.String())
+}
+
+func TestCompleteLabelRemoveFlags(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"label1"}))
+	app, writer, _ := appWithTestWriters()
+	app.Commands = []cli.Command{
+		{
+			Name: "remove",
+			Flags: []cli.Flag{
+				cli.StringFlag{Name: "owner"},
+				cli.BoolFlag{Name: "yes, y"},
+			},
+		},
+	}
+	os.Args = []string{"label", "remove", "label1", "--completion"}
+	command.CompleteLabelRemove(cli.NewContext(app, set, nil))
+	assert.Equal(t, "--owner\n--yes\n", writer.String())
+}
+
+func TestCompleteLabelRepo(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	app, writer, _ := appWithTestWriters()
+	os.Args = []string{"label", "add", "--repo", "--completion"}
+	command.CompleteLabelAdd(cli.NewContext(app, set, nil))
+	assert.Equal(t, "foo/bar\nown/rep\n", writer.String())
+}
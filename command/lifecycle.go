@@ -0,0 +1,314 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/google/go-github/github"
+	"github.com/urfave/cli"
+)
+
+// lifecycleChange moves pull requests between draft, ready for review and closed
+type lifecycleChange struct {
+	name    string
+	applies func(*pullRequest) bool
+	// needsSelector keeps the change from applying to every pull request when no selector is given
+	needsSelector bool
+	nothingToDo   string
+	preview       string
+	failure       string
+	exitError     string
+}
+
+var readyChange = lifecycleChange{
+	name:        "ready",
+	applies:     func(pr *pullRequest) bool { return pr.Draft },
+	nothingToDo: "No pull requests are drafts",
+	preview:     "These pull requests will be marked ready for review:",
+	failure:     "Could not mark PR #%d in %s/%s ready for review because: %v\n",
+	exitError:   "Unable to mark all pull requests ready for review",
+}
+
+var draftChange = lifecycleChange{
+	name:          "draft",
+	applies:       func(pr *pullRequest) bool { return !pr.Draft },
+	needsSelector: true,
+	nothingToDo:   "No pull requests are ready for review",
+	preview:       "These pull requests will be converted to drafts:",
+	failure:       "Could not convert PR #%d in %s/%s to a draft because: %v\n",
+	exitError:     "Unable to convert all pull requests to drafts",
+}
+
+var closeChange = lifecycleChange{
+	name:          "close",
+	applies:       func(*pullRequest) bool { return true },
+	needsSelector: true,
+	nothingToDo:   "No pull requests match",
+	preview:       "These pull requests will be closed:",
+	failure:       "Could not close PR #%d in %s/%s because: %v\n",
+	exitError:     "Unable to close all pull requests",
+}
+
+// CmdReady marks draft pull requests ready for review
+func CmdReady(c *cli.Context) error {
+	return changeLifecycle(c, readyChange, func(c *cli.Context, user *github.User, pr *pullRequest) error {
+		return markPullRequestReady(pr.client, pr.Repo.Owner, pr.Repo.Name, pr.PullRequestID)
+	})
+}
+
+// CmdDraft converts pull requests to drafts
+func CmdDraft(c *cli.Context) error {
+	return changeLifecycle(c, draftChange, func(c *cli.Context, user *github.User, pr *pullRequest) error {
+		return convertPullRequestToDraft(pr.client, pr.Repo.Owner, pr.Repo.Name, pr.PullRequestID)
+	})
+}
+
+// CmdClose closes pull requests, optionally leaving a comment and deleting their branches
+func CmdClose(c *cli.Context) error {
+	var commentTemplate *template.Template
+	if c.String("message") != "" {
+		var err error
+		commentTemplate, err = parseCommentTemplate(c.String("message"))
+		if err != nil {
+			return err
+		}
+	}
+
+	return changeLifecycle(c, closeChange, func(c *cli.Context, user *github.User, pr *pullRequest) error {
+		return pr.close(commentTemplate, c.Bool("delete-branch"), user, c.App.Writer)
+	})
+}
+
+func changeLifecycle(c *cli.Context, change lifecycleChange, apply func(*cli.Context, *github.User, *pullRequest) error) error {
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() != 0 && c.NArg() != 2 {
+		return cli.NewExitError(fmt.Sprintf("Usage: \"prp %s {repoName} {pullRequestNumber}\" or \"prp %s\" with the parse selectors", change.name, change.name), 1)
+	}
+
+	if c.NArg() == 0 && change.needsSelector && c.String("owner") == "" && len(c.StringSlice("repo")) == 0 && !c.Bool("need-rebase") {
+		return cli.NewExitError(fmt.Sprintf("Usage: \"prp %s\" needs at least one of --owner, --repo or --need-rebase when no pull request is given", change.name), 1)
+	}
+
+	profile := configData.Profiles[*profileName]
+	client, err := getGithubClient(&profile.Token, &profile.APIURL, c.Bool("use-cache"))
+	if err != nil {
+		return err
+	}
+
+	user, _, err := client.Users.Get(context.Background(), "")
+	if err != nil {
+		return err
+	}
+
+	parser := newParser(client, user, &profile)
+	var candidates []*pullRequest
+	if c.NArg() == 2 {
+		repo, number, err := loadPullRequest(&profile, c.Args().Get(0), c.Args().Get(1))
+		if err != nil {
+			return err
+		}
+
+		pr, err := parser.getPullRequest(repo, number)
+		if err != nil {
+			return err
+		}
+
+		candidates = []*pullRequest{pr}
+	} else {
+		prs := parser.parsePullRequests(parser.getBasePullRequestData(c.App.ErrWriter), c.String("owner"), c.StringSlice("repo"), c.Bool("need-rebase"))
+		candidates = sortPullRequests(selectPullRequests(prs, 0))
+	}
+
+	affected := []*pullRequest{}
+	for _, pr := range candidates {
+		if change.applies(pr) {
+			affected = append(affected, pr)
+		}
+	}
+
+	if len(affected) == 0 {
+		fmt.Fprintln(c.App.Writer, change.nothingToDo)
+		return nil
+	}
+
+	fmt.Fprintln(c.App.Writer, change.preview)
+	for _, pr := range affected {
+		fmt.Fprintf(c.App.Writer, "  PR #%d in %s/%s: %s\n", pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, pr.Title)
+	}
+
+	if !c.Bool("yes") && !confirm(os.Stdin, c.App.Writer, "Continue?") {
+		return nil
+	}
+
+	var completeError error
+	for _, pr := range affected {
+		err = apply(c, user, pr)
+		if err != nil {
+			fmt.Fprintf(c.App.ErrWriter, change.failure, pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, err)
+			completeError = cli.NewExitError(change.exitError, 1)
+		}
+	}
+
+	return completeError
+}
+
+// close leaves the comment rendered from commentTemplate on pr unless it is nil, closes pr and deletes its branch if asked to
+func (pr *pullRequest) close(commentTemplate *template.Template, deleteBranch bool, user *github.User, writer io.Writer) error {
+	if commentTemplate != nil {
+		body := &bytes.Buffer{}
+		err := commentTemplate.Execute(body, pr.commentData())
+		if err != nil {
+			return err
+		}
+
+		comment := strings.TrimSpace(body.String())
+		_, _, err = pr.client.Issues.CreateComment(context.Background(), pr.Repo.Owner, pr.Repo.Name, pr.PullRequestID, &github.IssueComment{Body: &comment})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, _, err := pr.client.PullRequests.Edit(context.Background(), pr.Repo.Owner, pr.Repo.Name, pr.PullRequestID, &github.PullRequest{State: github.String("closed")})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(writer, "Closed PR #%d in %s/%s\n", pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name)
+	if !deleteBranch {
+		return nil
+	}
+
+	reason, err := pr.keepBranchReason(user)
+	if err != nil {
+		return err
+	}
+
+	if reason != "" {
+		branch := pr.Branch
+		if pr.HeadRepoFullName != "" {
+			branch = fmt.Sprintf("%s of %s", pr.Branch, pr.HeadRepoFullName)
+		}
+
+		fmt.Fprintf(writer, "Kept %s because %s\n", branch, reason)
+		return nil
+	}
+
+	err = pr.deleteHeadBranch()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(writer, "Deleted %s of %s\n", pr.Branch, pr.HeadRepoFullName)
+	return nil
+}
+
+// keepBranchReason explains why pr's head branch must not be deleted, or returns "" if it can be
+// Only branches in user's repositories or of user's pull requests are deleted
+func (pr pullRequest) keepBranchReason(user *github.User) (string, error) {
+	repoNameParts := strings.Split(pr.HeadRepoFullName, "/")
+	if len(repoNameParts) != 2 {
+		return "its repository no longer exists", nil
+	}
+
+	if repoNameParts[0] != user.GetLogin() && pr.Owner != user.GetLogin() {
+		return "neither its repository nor the pull request is yours", nil
+	}
+
+	headRepo, _, err := pr.client.Repositories.Get(context.Background(), repoNameParts[0], repoNameParts[1])
+	if err != nil {
+		return "", err
+	}
+
+	if headRepo.Permissions == nil || !(*headRepo.Permissions)["push"] {
+		return "you can't push to it", nil
+	}
+
+	if pr.Branch == headRepo.GetDefaultBranch() {
+		return "it is the default branch", nil
+	}
+
+	if pr.HeadRepoFullName != fmt.Sprintf("%s/%s", pr.Repo.Owner, pr.Repo.Name) {
+		return "", nil
+	}
+
+	// Pull requests stacked on the branch would be closed along with it
+	dependents, _, err := listPullRequests(pr.client, pr.Repo.Owner, pr.Repo.Name, &github.PullRequestListOptions{Base: pr.Branch})
+	if err != nil {
+		return "", err
+	}
+
+	if len(dependents) != 0 {
+		numbers := make([]int, 0, len(dependents))
+		for _, dependent := range dependents {
+			numbers = append(numbers, dependent.GetNumber())
+		}
+
+		sort.Ints(numbers)
+		dependentNames := make([]string, 0, len(numbers))
+		for _, number := range numbers {
+			dependentNames = append(dependentNames, fmt.Sprintf("PR #%d", number))
+		}
+
+		return fmt.Sprintf("%s targets it", strings.Join(dependentNames, ", ")), nil
+	}
+
+	return "", nil
+}
+
+// CompleteReady handles bash autocompletion for the 'ready' command
+func CompleteReady(c *cli.Context) {
+	completeLifecycle(c, "ready")
+}
+
+// CompleteDraft handles bash autocompletion for the 'draft' command
+func CompleteDraft(c *cli.Context) {
+	completeLifecycle(c, "draft")
+}
+
+// CompleteClose handles bash autocompletion for the 'close' command
+func CompleteClose(c *cli.Context) {
+	completeLifecycle(c, "close")
+}
+
+func completeLifecycle(c *cli.Context, commandName string) {
+	lastParam := os.Args[len(os.Args)-2]
+	if lastParam != "--user" && lastParam != "--repo" && c.NArg() >= 2 {
+		completeFlags(c, commandName)
+		return
+	}
+
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return
+	}
+
+	profile := configData.Profiles[*profileName]
+	switch lastParam {
+	case "--user":
+		completeUser(&profile, c.App.Writer, c.App.ErrWriter)
+		return
+	case "--repo":
+		completeRepo(c.StringSlice("repo"), profile, c.App.Writer)
+		return
+	}
+
+	prs, err := getPullRequestsByOwner(&profile, allOwners, nil, true, c.App.ErrWriter)
+	if err != nil {
+		return
+	}
+
+	completions := completeRepoValues(profile, prs, c.Args(), c.NArg() == 1)
+	completions = unique(completions)
+	sort.Strings(completions)
+	fmt.Fprintln(c.App.Writer, strings.Join(completions, "\n"))
+}
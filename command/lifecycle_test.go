@@ -0,0 +1,403 @@
+package command_test
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/guywithnose/pull-request-parser/command"
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+func TestCmdReady(t *testing.T) {
+	requests := []string{}
+	inner := getParseTestServer("")
+	defer inner.Close()
+	ts := getCloseTestServer(inner, &requests, map[string]string{"/repos/own/rep/pulls?per_page=100": getMergePullRequestsJSON("guy/rep", true)})
+	defer ts.Close()
+	writer, errWriter, err := runAgainstServer(t, ts, command.CmdReady, func(*config.Profile) {}, func(set *flag.FlagSet) {
+		repos := cli.StringSlice{"own/rep"}
+		set.Var(&repos, "repo", "doc")
+		set.Bool("yes", true, "doc")
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(requests))
+	assert.Contains(t, requests[1], "markPullRequestReadyForReview")
+	assert.Equal(t, "These pull requests will be marked ready for review:\n  PR #1 in own/rep: prOne\n", writer.String())
+	assert.Equal(t, "", errWriter.String())
+}
+
+func TestCmdReadyNoDrafts(t *testing.T) {
+	requests := []string{}
+	inner := getParseTestServer("")
+	defer inner.Close()
+	ts := getCloseTestServer(inner, &requests, map[string]string{})
+	defer ts.Close()
+	writer, _, err := runAgainstServer(t, ts, command.CmdReady, func(*config.Profile) {}, func(set *flag.FlagSet) {
+		set.String("owner", "guy", "doc")
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{}, requests)
+	assert.Equal(t, "No pull requests are drafts\n", writer.String())
+}
+
+func TestCmdDraft(t *testing.T) {
+	requests := []string{}
+	inner := getParseTestServer("")
+	defer inner.Close()
+	ts := getCloseTestServer(inner, &requests, map[string]string{})
+	defer ts.Close()
+	writer, errWriter, err := runAgainstServer(t, ts, command.CmdDraft, func(*config.Profile) {}, func(set *flag.FlagSet) {
+		set.String("owner", "guy", "doc")
+		set.Bool("yes", true, "doc")
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(requests))
+	assert.Contains(t, requests[1], "convertPullRequestToDraft")
+	assert.Equal(t, "These pull requests will be converted to drafts:\n  PR #1 in own/rep: prOne\n", writer.String())
+	assert.Equal(t, "", errWriter.String())
+}
+
+func TestCmdDraftFailure(t *testing.T) {
+	requests := []string{}
+	inner := getParseTestServer("")
+	defer inner.Close()
+	ts := getCloseTestServer(inner, &requests, map[string]string{"/graphql": "not json"})
+	defer ts.Close()
+	_, errWriter, err := runAgainstServer(t, ts, command.CmdDraft, func(*config.Profile) {}, func(set *flag.FlagSet) {
+		set.String("owner", "guy", "doc")
+		set.Bool("yes", true, "doc")
+	})
+	assert.EqualError(t, err, "Unable to convert all pull requests to drafts")
+	assert.Contains(t, errWriter.String(), "Could not convert PR #1 in own/rep to a draft because: ")
+}
+
+func TestCmdClose(t *testing.T) {
+	var testCases = []struct {
+		name             string
+		responses        map[string]string
+		setFlags         func(*flag.FlagSet)
+		expectedRequests []string
+		expectedOutput   []string
+	}{
+		{
+			"Comment",
+			map[string]string{},
+			func(set *flag.FlagSet) { set.String("message", "Superseded by {{.Branch}}2", "doc") },
+			[]string{
+				`POST /repos/own/rep/issues/1/comments {"body":"Superseded by ref12"}`,
+				`PATCH /repos/own/rep/pulls/1 {"state":"closed"}`,
+			},
+			[]string{"Closed PR #1 in own/rep"},
+		},
+		{
+			"CommentWithPullRequestData",
+			map[string]string{},
+			func(set *flag.FlagSet) { set.String("message", "Closing {{join .Labels \", \"}}", "doc") },
+			[]string{
+				`POST /repos/own/rep/issues/1/comments {"body":"Closing label1"}`,
+				`PATCH /repos/own/rep/pulls/1 {"state":"closed"}`,
+			},
+			[]string{"Closed PR #1 in own/rep"},
+		},
+		{
+			"DeleteBranch",
+			map[string]string{},
+			func(set *flag.FlagSet) { set.Bool("delete-branch", true, "doc") },
+			[]string{
+				`PATCH /repos/own/rep/pulls/1 {"state":"closed"}`,
+				"DELETE /repos/guy/rep/git/refs/heads/ref1",
+			},
+			[]string{"Closed PR #1 in own/rep", "Deleted ref1 of guy/rep"},
+		},
+		{
+			"NoPushAccess",
+			map[string]string{"/repos/guy/rep": `{"default_branch":"master","permissions":{"push":false}}`},
+			func(set *flag.FlagSet) { set.Bool("delete-branch", true, "doc") },
+			[]string{`PATCH /repos/own/rep/pulls/1 {"state":"closed"}`},
+			[]string{"Closed PR #1 in own/rep", "Kept ref1 of guy/rep because you can't push to it"},
+		},
+		{
+			"DefaultBranch",
+			map[string]string{"/repos/guy/rep": `{"default_branch":"ref1","permissions":{"push":true}}`},
+			func(set *flag.FlagSet) { set.Bool("delete-branch", true, "doc") },
+			[]string{`PATCH /repos/own/rep/pulls/1 {"state":"closed"}`},
+			[]string{"Closed PR #1 in own/rep", "Kept ref1 of guy/rep because it is the default branch"},
+		},
+		{
+			"DeletedRepository",
+			map[string]string{"/repos/own/rep/pulls?per_page=100": getMergePullRequestsJSON("", false)},
+			func(set *flag.FlagSet) { set.Bool("delete-branch", true, "doc") },
+			[]string{`PATCH /repos/own/rep/pulls/1 {"state":"closed"}`},
+			[]string{"Closed PR #1 in own/rep", "Kept ref1 because its repository no longer exists"},
+		},
+		{
+			"SomeoneElsesBranch",
+			map[string]string{"/repos/own/rep/pulls?per_page=100": getOthersPullRequestsJSON("alice", "alice/rep")},
+			func(set *flag.FlagSet) { set.Bool("delete-branch", true, "doc") },
+			[]string{`PATCH /repos/own/rep/pulls/1 {"state":"closed"}`},
+			[]string{"Closed PR #1 in own/rep", "Kept ref1 of alice/rep because neither its repository nor the pull request is yours"},
+		},
+		{
+			"SomeoneElsesPullRequestFromMyRepository",
+			map[string]string{"/repos/own/rep/pulls?per_page=100": getOthersPullRequestsJSON("alice", "guy/rep")},
+			func(set *flag.FlagSet) { set.Bool("delete-branch", true, "doc") },
+			[]string{
+				`PATCH /repos/own/rep/pulls/1 {"state":"closed"}`,
+				"DELETE /repos/guy/rep/git/refs/heads/ref1",
+			},
+			[]string{"Closed PR #1 in own/rep", "Deleted ref1 of guy/rep"},
+		},
+		{
+			"StackedPullRequests",
+			map[string]string{
+				"/repos/own/rep/pulls?per_page=100": getMergePullRequestsJSON("own/rep", false),
+				"/repos/own/rep":                    `{"default_branch":"master","permissions":{"push":true}}`,
+				"/repos/own/rep/pulls?base=ref1":    `[{"number":7},{"number":3}]`,
+			},
+			func(set *flag.FlagSet) { set.Bool("delete-branch", true, "doc") },
+			[]string{`PATCH /repos/own/rep/pulls/1 {"state":"closed"}`},
+			[]string{"Closed PR #1 in own/rep", "Kept ref1 of own/rep because PR #3, PR #7 targets it"},
+		},
+		{
+			"SameRepository",
+			map[string]string{
+				"/repos/own/rep/pulls?per_page=100":  getMergePullRequestsJSON("own/rep", false),
+				"/repos/own/rep":                     `{"default_branch":"master","permissions":{"push":true}}`,
+				"/repos/own/rep/pulls?base=ref1":     `[]`,
+				"/repos/own/rep/git/refs/heads/ref1": "",
+			},
+			func(set *flag.FlagSet) { set.Bool("delete-branch", true, "doc") },
+			[]string{
+				`PATCH /repos/own/rep/pulls/1 {"state":"closed"}`,
+				"DELETE /repos/own/rep/git/refs/heads/ref1",
+			},
+			[]string{"Closed PR #1 in own/rep", "Deleted ref1 of own/rep"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := []string{}
+			inner := getParseTestServer("")
+			defer inner.Close()
+			ts := getCloseTestServer(inner, &requests, tc.responses)
+			defer ts.Close()
+			writer, errWriter, err := runAgainstServer(t, ts, command.CmdClose, func(*config.Profile) {}, func(set *flag.FlagSet) {
+				set.Bool("yes", true, "doc")
+				tc.setFlags(set)
+				assert.Nil(t, set.Parse([]string{"own/rep", "1"}))
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedRequests, requests)
+			expectedOutput := append([]string{"These pull requests will be closed:", "  PR #1 in own/rep: prOne"}, tc.expectedOutput...)
+			assert.Equal(t, strings.Join(append(expectedOutput, ""), "\n"), writer.String())
+			assert.Equal(t, "", errWriter.String())
+		})
+	}
+}
+
+func TestCmdCloseDeclined(t *testing.T) {
+	requests := []string{}
+	inner := getParseTestServer("")
+	defer inner.Close()
+	ts := getCloseTestServer(inner, &requests, map[string]string{})
+	defer ts.Close()
+	defer replaceStdin(t, "n\n")()
+	writer, _, err := runAgainstServer(t, ts, command.CmdClose, func(*config.Profile) {}, func(set *flag.FlagSet) {
+		set.String("owner", "guy", "doc")
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{}, requests)
+	assert.Equal(t, "These pull requests will be closed:\n  PR #1 in own/rep: prOne\nContinue? [y/N] ", writer.String())
+}
+
+func TestCmdCloseFailure(t *testing.T) {
+	requests := []string{}
+	inner := getParseTestServer("")
+	defer inner.Close()
+	ts := getCloseTestServer(inner, &requests, map[string]string{"/repos/own/rep/pulls/1": "not json"})
+	defer ts.Close()
+	writer, errWriter, err := runAgainstServer(t, ts, command.CmdClose, func(*config.Profile) {}, func(set *flag.FlagSet) {
+		set.String("owner", "guy", "doc")
+		set.Bool("yes", true, "doc")
+		set.Bool("delete-branch", true, "doc")
+	})
+	assert.EqualError(t, err, "Unable to close all pull requests")
+	assert.Equal(t, []string{`PATCH /repos/own/rep/pulls/1 {"state":"closed"}`}, requests)
+	assert.Equal(t, "These pull requests will be closed:\n  PR #1 in own/rep: prOne\n", writer.String())
+	assert.Contains(t, errWriter.String(), "Could not close PR #1 in own/rep because: ")
+}
+
+func TestCmdCloseErrors(t *testing.T) {
+	var testCases = []struct {
+		name          string
+		setFlags      func(*flag.FlagSet)
+		expectedError string
+	}{
+		{
+			"Usage",
+			func(set *flag.FlagSet) { assert.Nil(t, set.Parse([]string{"own/rep"})) },
+			"Usage: \"prp close {repoName} {pullRequestNumber}\" or \"prp close\" with the parse selectors",
+		},
+		{
+			"NoSelector",
+			func(*flag.FlagSet) {},
+			"Usage: \"prp close\" needs at least one of --owner, --repo or --need-rebase when no pull request is given",
+		},
+		{
+			"InvalidRepo",
+			func(set *flag.FlagSet) { assert.Nil(t, set.Parse([]string{"own/missing", "1"})) },
+			"Not a valid Repo: own/missing",
+		},
+		{
+			"InvalidMessage",
+			func(set *flag.FlagSet) { set.String("message", "{{", "doc") },
+			"Invalid comment template: template: comment:1: unclosed action",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := []string{}
+			inner := getParseTestServer("")
+			defer inner.Close()
+			ts := getCloseTestServer(inner, &requests, map[string]string{})
+			defer ts.Close()
+			_, _, err := runAgainstServer(t, ts, command.CmdClose, func(*config.Profile) {}, tc.setFlags)
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestCmdCloseMissingPullRequest(t *testing.T) {
+	var testCases = []struct {
+		name          string
+		args          []string
+		expectedError string
+	}{
+		{"NotFound", []string{"own/rep", "5"}, "PR #5 in own/rep was not found"},
+		{"AlreadyClosed", []string{"own/rep", "1"}, "PR #1 in own/rep is already closed"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := []string{}
+			inner := getParseTestServer("")
+			defer inner.Close()
+			closeServer := getCloseTestServer(inner, &requests, map[string]string{"/repos/own/rep/pulls/1": `{"number":1,"state":"closed"}`})
+			defer closeServer.Close()
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.String() == "/repos/own/rep/pulls/5" {
+					w.WriteHeader(http.StatusNotFound)
+					fmt.Fprint(w, `{"message": "Not Found"}`)
+					return
+				}
+
+				closeServer.Config.Handler.ServeHTTP(w, r)
+			}))
+			defer ts.Close()
+			writer, _, err := runAgainstServer(t, ts, command.CmdClose, func(*config.Profile) {}, withArgs(t, tc.args, func(set *flag.FlagSet) {
+				set.Bool("yes", true, "doc")
+			}))
+			assert.EqualError(t, err, tc.expectedError)
+			assert.Equal(t, []string{}, requests)
+			assert.Equal(t, "", writer.String())
+		})
+	}
+}
+
+func TestCmdReadyNoConfig(t *testing.T) {
+	app, _, _ := appWithTestWriters()
+	err := command.CmdReady(cli.NewContext(app, flag.NewFlagSet("test", 0), nil))
+	assert.EqualError(t, err, "You must specify a config file")
+}
+
+func TestCompleteCloseFlags(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"foo/bar", "1"}))
+	app, writer, _ := appWithTestWriters()
+	app.Commands = []cli.Command{
+		{
+			Name: "close",
+			Flags: []cli.Flag{
+				cli.StringFlag{Name: "message, m"},
+				cli.BoolFlag{Name: "delete-branch, db"},
+			},
+		},
+	}
+	os.Args = []string{"close", "foo/bar", "1", "--completion"}
+	command.CompleteClose(cli.NewContext(app, set, nil))
+	assert.Equal(t, "--message\n--delete-branch\n", writer.String())
+}
+
+func TestCompleteDraftRepo(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	app, writer, _ := appWithTestWriters()
+	os.Args = []string{"draft", "--repo", "--completion"}
+	command.CompleteDraft(cli.NewContext(app, set, nil))
+	assert.Equal(t, "foo/bar\nown/rep\n", writer.String())
+}
+
+func TestCompleteReadyRepoName(t *testing.T) {
+	inner := getParseTestServer("")
+	defer inner.Close()
+	_, configFileName := getConfigWithAPIURL(t, inner.URL)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	app, writer, _ := appWithTestWriters()
+	os.Args = []string{"ready", "--completion"}
+	command.CompleteReady(cli.NewContext(app, set, nil))
+	assert.Equal(t, "foo/bar\nown/rep\n", writer.String())
+}
+
+// getCloseTestServer serves PR #1 in own/rep opened by guy from guy/rep, which guy can push to, to guy
+// It records every request that isn't a GET
+func getCloseTestServer(inner *httptest.Server, requests *[]string, responses map[string]string) *httptest.Server {
+	withGraphQL := &httptest.Server{Config: &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if response := handleGraphQLRequests(r, w, inner); response != nil {
+			fmt.Fprint(w, *response)
+			return
+		}
+
+		inner.Config.Handler.ServeHTTP(w, r)
+	})}}
+
+	defaultResponses := map[string]string{
+		"/user":                              `{"login":"guy"}`,
+		"/repos/own/rep/pulls?per_page=100":  getMergePullRequestsJSON("guy/rep", false),
+		"/repos/own/rep/issues/1/comments":   "{}",
+		"/repos/guy/rep":                     `{"default_branch":"master","permissions":{"push":true}}`,
+		"/repos/guy/rep/git/refs/heads/ref1": "",
+	}
+
+	merged := mergeResponses(defaultResponses, responses)
+	if _, ok := merged["/repos/own/rep/pulls/1"]; !ok {
+		// PR #1 is loaded on its own when it is given as an argument
+		var listed []json.RawMessage
+		_ = json.Unmarshal([]byte(merged["/repos/own/rep/pulls?per_page=100"]), &listed)
+		merged["/repos/own/rep/pulls/1"] = string(listed[0])
+	}
+
+	return getRecordingOverridingTestServer(withGraphQL, requests, merged)
+}
+
+// getOthersPullRequestsJSON lists PR #1 in own/rep opened by owner from headRepoFullName
+func getOthersPullRequestsJSON(owner, headRepoFullName string) string {
+	pr := newPullRequest(1, "prOne", owner, "label", "ref1", "sha1", "baseLabel1", "baseRef1")
+	pr.Head.Repo.FullName = &headRepoFullName
+	bytes, _ := json.Marshal([]*github.PullRequest{pr})
+	return string(bytes)
+}
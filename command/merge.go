@@ -0,0 +1,267 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/urfave/cli"
+)
+
+// merger merges pull requests once they are ready
+type merger struct {
+	writer        io.Writer
+	errorWriter   io.Writer
+	verboseWriter io.Writer
+	policy        config.MergePolicy
+	dryRun        bool
+}
+
+// mergeSummary lists what happened to each pull request during mergeSelectedPullRequests
+type mergeSummary struct {
+	merged   []*pullRequest
+	notReady []*pullRequest
+	failed   []*pullRequest
+}
+
+// CmdMerge merges your pull requests once they are ready
+func CmdMerge(c *cli.Context) error {
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() != 0 {
+		return cli.NewExitError("Usage: \"prp merge\"", 1)
+	}
+
+	policy, err := mergePolicyFromFlags(c)
+	if err != nil {
+		return err
+	}
+
+	profile := configData.Profiles[*profileName]
+	verboseWriter := ioutil.Discard
+	if c.Bool("verbose") {
+		verboseWriter = c.App.ErrWriter
+	}
+
+	merger := &merger{
+		writer:        c.App.Writer,
+		errorWriter:   c.App.ErrWriter,
+		verboseWriter: verboseWriter,
+		policy:        policy,
+		dryRun:        c.Bool("dry-run"),
+	}
+
+	if c.Bool("watch") {
+		return merger.watch(&profile, c.StringSlice("repo"), c.Int("pull-request-number"), watchOptionsFromFlags(c))
+	}
+
+	pullRequests, err := getPullRequestsByOwner(&profile, "", c.StringSlice("repo"), c.Bool("use-cache"), c.App.ErrWriter)
+	if err != nil {
+		return err
+	}
+
+	_, err = merger.mergeSelectedPullRequests(selectPullRequests(pullRequests, c.Int("pull-request-number")))
+	return err
+}
+
+func (m merger) watch(profile *config.Profile, repos []string, pullRequestNumber int, options watchOptions) error {
+	err := options.validate()
+	if err != nil {
+		return err
+	}
+
+	client, cache, err := getWatchClient(profile, options)
+	if err != nil {
+		return err
+	}
+
+	return repeatCycles(options, m.writer, func() (string, bool, error) {
+		cache.nextCycle()
+		pullRequests, err := getPullRequestsWithClient(client, profile, "", repos, m.errorWriter)
+		if err != nil {
+			fmt.Fprintf(m.errorWriter, "Unable to list pull requests\n%v\n", err)
+			return "Unable to list pull requests", true, cli.NewExitError("Unable to list pull requests", 1)
+		}
+
+		summary, err := m.mergeSelectedPullRequests(selectPullRequests(pullRequests, pullRequestNumber))
+		description := fmt.Sprintf("%d merged, %d not ready, %d failed", len(summary.merged), len(summary.notReady), len(summary.failed))
+		return description, len(summary.failed) != 0, err
+	})
+}
+
+func (m merger) mergeSelectedPullRequests(selectedPullRequests []*pullRequest) (mergeSummary, error) {
+	var completeError error
+	summary := mergeSummary{}
+	for _, pr := range sortPullRequests(selectedPullRequests) {
+		policy := m.policy.Merge(pr.Repo.MergePolicy)
+		blocker := pr.mergeBlocker(policy)
+		if blocker != "" {
+			writer := m.verboseWriter
+			if m.dryRun {
+				writer = m.writer
+			}
+
+			fmt.Fprintf(writer, "PR #%d in %s/%s is not ready to be merged because %s\n", pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, blocker)
+			summary.notReady = append(summary.notReady, pr)
+			continue
+		}
+
+		method := policy.Method
+		if method == "" {
+			method = config.MergeMethodMerge
+		}
+
+		if m.dryRun {
+			fmt.Fprintf(m.writer, "PR #%d in %s/%s would be merged with %s\n", pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, method)
+			summary.merged = append(summary.merged, pr)
+			continue
+		}
+
+		err := pr.merge(method)
+		if err != nil {
+			fmt.Fprintf(m.errorWriter, "Could not merge PR #%d in %s/%s because: %v\n", pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, err)
+			summary.failed = append(summary.failed, pr)
+			completeError = cli.NewExitError("Unable to merge all pull requests", 1)
+			continue
+		}
+
+		fmt.Fprintf(m.writer, "Merged PR #%d in %s/%s with %s\n", pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, method)
+		summary.merged = append(summary.merged, pr)
+		if !policy.DeleteBranch {
+			continue
+		}
+
+		err = pr.deleteHeadBranch()
+		if err != nil {
+			fmt.Fprintf(m.errorWriter, "Could not delete %s after merging PR #%d in %s/%s because: %v\n", pr.Branch, pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, err)
+			completeError = cli.NewExitError("Unable to merge all pull requests", 1)
+			continue
+		}
+
+		fmt.Fprintf(m.verboseWriter, "Deleted %s in %s\n", pr.Branch, pr.HeadRepoFullName)
+	}
+
+	return summary, completeError
+}
+
+// mergeBlocker returns the reason pr may not be merged under policy or "" if it may
+// Drafts, pull requests that aren't rebased, failing builds and requested changes always block merging
+func (pr *pullRequest) mergeBlocker(policy config.MergePolicy) string {
+	if !pr.Rebased {
+		return fmt.Sprintf("it is not up to date with %s", pr.TargetBranch)
+	}
+
+	violation := pr.rebasePolicyViolation(config.RebasePolicy{
+		MinApprovals:         policy.MinApprovals,
+		RequiredLabels:       policy.RequiredLabels,
+		SkipDrafts:           true,
+		RequirePassingBuilds: true,
+	})
+	if violation != "" {
+		return violation
+	}
+
+	requestingUsers := pr.getUsersRequestingChanges()
+	if len(requestingUsers) != 0 {
+		return fmt.Sprintf("%s requested changes", strings.Join(requestingUsers, ", "))
+	}
+
+	return ""
+}
+
+// getUsersRequestingChanges returns the users whose latest review of pr requests changes
+func (pr pullRequest) getUsersRequestingChanges() []string {
+	latestReviews := make(map[string]string)
+	for review := range pr.getReviews() {
+		switch review.GetState() {
+		case "APPROVED", "CHANGES_REQUESTED", "DISMISSED":
+			latestReviews[review.User.GetLogin()] = review.GetState()
+		}
+	}
+
+	requestingUsers := []string{}
+	for user, state := range latestReviews {
+		if state == "CHANGES_REQUESTED" {
+			requestingUsers = append(requestingUsers, user)
+		}
+	}
+
+	sort.Strings(requestingUsers)
+	return requestingUsers
+}
+
+// merge merges pr as long as its head hasn't moved since it was checked
+func (pr *pullRequest) merge(method string) error {
+	result, _, err := pr.client.PullRequests.Merge(
+		context.Background(),
+		pr.Repo.Owner,
+		pr.Repo.Name,
+		pr.PullRequestID,
+		"",
+		&github.PullRequestOptions{MergeMethod: method, SHA: pr.SHA},
+	)
+	if err != nil {
+		return err
+	}
+
+	if !result.GetMerged() {
+		return errors.New(result.GetMessage())
+	}
+
+	return nil
+}
+
+func (pr *pullRequest) deleteHeadBranch() error {
+	repoNameParts := strings.Split(pr.HeadRepoFullName, "/")
+	if len(repoNameParts) != 2 {
+		return errors.New("its repository no longer exists")
+	}
+
+	_, err := pr.client.Git.DeleteRef(context.Background(), repoNameParts[0], repoNameParts[1], fmt.Sprintf("heads/%s", pr.Branch))
+	return err
+}
+
+func mergePolicyFromFlags(c *cli.Context) (config.MergePolicy, error) {
+	method := c.String("method")
+	if method != "" && !stringSliceContains(method, config.MergeMethods) {
+		return config.MergePolicy{}, cli.NewExitError(fmt.Sprintf("Invalid merge method: %s (must be one of %s)", method, strings.Join(config.MergeMethods, ", ")), 1)
+	}
+
+	return config.MergePolicy{
+		MinApprovals:   c.Int("min-approvals"),
+		RequiredLabels: c.StringSlice("require-label"),
+		Method:         method,
+		DeleteBranch:   c.Bool("delete-branch"),
+	}, nil
+}
+
+// CompleteMerge handles bash autocompletion for the 'merge' command
+func CompleteMerge(c *cli.Context) {
+	lastParam := os.Args[len(os.Args)-2]
+	if lastParam == "--method" {
+		fmt.Fprintln(c.App.Writer, strings.Join(config.MergeMethods, "\n"))
+		return
+	}
+
+	if lastParam != "--repo" {
+		completeFlags(c, "merge")
+		return
+	}
+
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return
+	}
+
+	completeRepo(c.StringSlice("repo"), configData.Profiles[*profileName], c.App.Writer)
+}
@@ -0,0 +1,378 @@
+package command_test
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/guywithnose/pull-request-parser/command"
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+func TestCmdMerge(t *testing.T) {
+	var testCases = []struct {
+		name             string
+		modifyRepo       func(*config.Repo)
+		setFlags         func(*flag.FlagSet)
+		expectedRequests []string
+		expectedOutput   string
+	}{
+		{
+			"Default",
+			func(*config.Repo) {},
+			func(*flag.FlagSet) {},
+			[]string{`PUT /repos/own/rep/pulls/1/merge {"commit_message":"","merge_method":"merge","sha":"sha1"}`},
+			"Merged PR #1 in own/rep with merge\n",
+		},
+		{
+			"MethodAndDeleteBranch",
+			func(*config.Repo) {},
+			func(set *flag.FlagSet) {
+				set.String("method", "squash", "doc")
+				set.Bool("delete-branch", true, "doc")
+			},
+			[]string{
+				`PUT /repos/own/rep/pulls/1/merge {"commit_message":"","merge_method":"squash","sha":"sha1"}`,
+				"DELETE /repos/guy/rep/git/refs/heads/ref1",
+			},
+			"Merged PR #1 in own/rep with squash\n",
+		},
+		{
+			"RepoPolicy",
+			func(repo *config.Repo) {
+				repo.MergePolicy = &config.MergePolicy{Method: config.MergeMethodRebase, DeleteBranch: true}
+			},
+			func(*flag.FlagSet) {},
+			[]string{
+				`PUT /repos/own/rep/pulls/1/merge {"commit_message":"","merge_method":"rebase","sha":"sha1"}`,
+				"DELETE /repos/guy/rep/git/refs/heads/ref1",
+			},
+			"Merged PR #1 in own/rep with rebase\n",
+		},
+		{
+			"MethodFlagOverridesRepo",
+			func(repo *config.Repo) {
+				repo.MergePolicy = &config.MergePolicy{Method: config.MergeMethodRebase}
+			},
+			func(set *flag.FlagSet) { set.String("method", "squash", "doc") },
+			[]string{`PUT /repos/own/rep/pulls/1/merge {"commit_message":"","merge_method":"squash","sha":"sha1"}`},
+			"Merged PR #1 in own/rep with squash\n",
+		},
+		{
+			"RequirementsMet",
+			func(*config.Repo) {},
+			func(set *flag.FlagSet) {
+				set.Int("min-approvals", 2, "doc")
+				labels := cli.StringSlice{"label1"}
+				set.Var(&labels, "require-label", "doc")
+			},
+			[]string{`PUT /repos/own/rep/pulls/1/merge {"commit_message":"","merge_method":"merge","sha":"sha1"}`},
+			"Merged PR #1 in own/rep with merge\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := []string{}
+			inner := getAutoRebaseTestServer("")
+			defer inner.Close()
+			ts := getMergeTestServer(inner, &requests, map[string]string{})
+			defer ts.Close()
+			writer, _, err := runAgainstServer(t, ts, command.CmdMerge, withRepo(tc.modifyRepo), tc.setFlags)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedRequests, requests)
+			assert.Equal(t, tc.expectedOutput, writer.String())
+		})
+	}
+}
+
+func TestCmdMergeNotReady(t *testing.T) {
+	var testCases = []struct {
+		name      string
+		responses map[string]string
+		setFlags  func(*flag.FlagSet)
+		reason    string
+	}{
+		{
+			"NotRebased",
+			map[string]string{"/repos/own/rep/compare/label...baseLabel1": `{"ahead_by":1}`},
+			func(*flag.FlagSet) {},
+			"it is not up to date with baseRef1",
+		},
+		{
+			"Draft",
+			map[string]string{"/repos/own/rep/pulls?per_page=100": getMergePullRequestsJSON("guy/rep", true)},
+			func(*flag.FlagSet) {},
+			"it is a draft",
+		},
+		{
+			"Approvals",
+			map[string]string{},
+			func(set *flag.FlagSet) { set.Int("min-approvals", 3, "doc") },
+			"it has 2 of 3 required approvals",
+		},
+		{
+			"Label",
+			map[string]string{},
+			func(set *flag.FlagSet) {
+				labels := cli.StringSlice{"ready"}
+				set.Var(&labels, "require-label", "doc")
+			},
+			"it does not have the label ready",
+		},
+		{
+			"FailingBuild",
+			map[string]string{"/repos/own/rep/commits/sha1/statuses?per_page=100": `[{"context":"build1","state":"failure"}]`},
+			func(*flag.FlagSet) {},
+			"the build1 build is not passing",
+		},
+		{
+			"FailingCheckRun",
+			map[string]string{
+				"/repos/own/rep/commits/sha1/check-runs?per_page=100": `{"check_runs":[{"name":"test","status":"completed","conclusion":"failure"}]}`,
+			},
+			func(*flag.FlagSet) {},
+			"the test build is not passing",
+		},
+		{
+			"RunningCheckRun",
+			map[string]string{
+				"/repos/own/rep/commits/sha1/check-runs?per_page=100": `{"check_runs":[{"name":"test","status":"queued"}]}`,
+			},
+			func(*flag.FlagSet) {},
+			"the test build is still running",
+		},
+		{
+			"NoBuilds",
+			map[string]string{"/repos/own/rep/commits/sha1/statuses?per_page=100": `[]`},
+			func(*flag.FlagSet) {},
+			"it has no builds",
+		},
+		{
+			"ChangesRequested",
+			map[string]string{
+				"/repos/own/rep/pulls/1/reviews?per_page=100": `[` +
+					`{"user":{"login":"guy2"},"state":"CHANGES_REQUESTED"},` +
+					`{"user":{"login":"fooGuy"},"state":"CHANGES_REQUESTED"},` +
+					`{"user":{"login":"guy3"},"state":"CHANGES_REQUESTED"},` +
+					`{"user":{"login":"fooGuy"},"state":"COMMENTED"},` +
+					`{"user":{"login":"guy3"},"state":"APPROVED"}` +
+					`]`,
+			},
+			func(*flag.FlagSet) {},
+			"fooGuy, guy2 requested changes",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := []string{}
+			inner := getAutoRebaseTestServer("")
+			defer inner.Close()
+			ts := getMergeTestServer(inner, &requests, tc.responses)
+			defer ts.Close()
+			writer, errWriter, err := runAgainstServer(t, ts, command.CmdMerge, func(*config.Profile) {}, func(set *flag.FlagSet) {
+				set.Bool("verbose", true, "doc")
+				tc.setFlags(set)
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, []string{}, requests)
+			assert.Equal(t, "", writer.String())
+			assert.Equal(t, fmt.Sprintf("PR #1 in own/rep is not ready to be merged because %s\n", tc.reason), errWriter.String())
+		})
+	}
+}
+
+func TestCmdMergeDryRun(t *testing.T) {
+	requests := []string{}
+	inner := getAutoRebaseTestServer("")
+	defer inner.Close()
+	ts := getMergeTestServer(inner, &requests, map[string]string{})
+	defer ts.Close()
+	writer, _, err := runAgainstServer(t, ts, command.CmdMerge, func(*config.Profile) {}, func(set *flag.FlagSet) {
+		set.Bool("dry-run", true, "doc")
+		set.String("method", "squash", "doc")
+		set.Bool("delete-branch", true, "doc")
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{}, requests)
+	assert.Equal(t, "PR #1 in own/rep would be merged with squash\n", writer.String())
+}
+
+func TestCmdMergeDryRunNotReady(t *testing.T) {
+	requests := []string{}
+	inner := getAutoRebaseTestServer("")
+	defer inner.Close()
+	ts := getMergeTestServer(inner, &requests, map[string]string{})
+	defer ts.Close()
+	writer, _, err := runAgainstServer(t, ts, command.CmdMerge, func(*config.Profile) {}, func(set *flag.FlagSet) {
+		set.Bool("dry-run", true, "doc")
+		set.Int("min-approvals", 3, "doc")
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{}, requests)
+	assert.Equal(t, "PR #1 in own/rep is not ready to be merged because it has 2 of 3 required approvals\n", writer.String())
+}
+
+func TestCmdMergeFailure(t *testing.T) {
+	requests := []string{}
+	inner := getAutoRebaseTestServer("")
+	defer inner.Close()
+	ts := getMergeTestServer(inner, &requests, map[string]string{
+		"/repos/own/rep/pulls/1/merge": `{"merged":false,"message":"Head branch was modified"}`,
+	})
+	defer ts.Close()
+	writer, errWriter, err := runAgainstServer(t, ts, command.CmdMerge, func(*config.Profile) {}, func(set *flag.FlagSet) {
+		set.Bool("delete-branch", true, "doc")
+	})
+	assert.EqualError(t, err, "Unable to merge all pull requests")
+	assert.Equal(t, []string{`PUT /repos/own/rep/pulls/1/merge {"commit_message":"","merge_method":"merge","sha":"sha1"}`}, requests)
+	assert.Equal(t, "", writer.String())
+	assert.Equal(t, "Could not merge PR #1 in own/rep because: Head branch was modified\n", errWriter.String())
+}
+
+func TestCmdMergeDeleteBranchFailure(t *testing.T) {
+	requests := []string{}
+	inner := getAutoRebaseTestServer("")
+	defer inner.Close()
+	ts := getMergeTestServer(inner, &requests, map[string]string{
+		"/repos/own/rep/pulls?per_page=100": getMergePullRequestsJSON("", false),
+	})
+	defer ts.Close()
+	writer, errWriter, err := runAgainstServer(t, ts, command.CmdMerge, func(*config.Profile) {}, func(set *flag.FlagSet) {
+		set.Bool("delete-branch", true, "doc")
+	})
+	assert.EqualError(t, err, "Unable to merge all pull requests")
+	assert.Equal(t, "Merged PR #1 in own/rep with merge\n", writer.String())
+	assert.Equal(t, "Could not delete ref1 after merging PR #1 in own/rep because: its repository no longer exists\n", errWriter.String())
+}
+
+func TestCmdMergeWatch(t *testing.T) {
+	requests := []string{}
+	inner := getAutoRebaseTestServer("")
+	defer inner.Close()
+	ts := getMergeTestServer(inner, &requests, map[string]string{})
+	defer ts.Close()
+	writer, _, err := runAgainstServer(t, ts, command.CmdMerge, func(*config.Profile) {}, func(set *flag.FlagSet) {
+		set.Bool("watch", true, "doc")
+		set.Duration("interval", time.Millisecond, "doc")
+		set.Int("max-cycles", 2, "doc")
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(requests))
+	lines := strings.Split(writer.String(), "\n")
+	assert.Equal(t, 5, len(lines))
+	assert.Equal(t, "Merged PR #1 in own/rep with merge", lines[0])
+	assert.Contains(t, lines[1], "cycle 1: 1 merged, 0 not ready, 0 failed, next cycle in 1ms")
+	assert.Contains(t, lines[3], "cycle 2: 1 merged, 0 not ready, 0 failed, next cycle in 1ms")
+}
+
+func TestCmdMergeWatchInvalidInterval(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	set.Bool("watch", true, "doc")
+	app, _, _ := appWithTestWriters()
+	err := command.CmdMerge(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Invalid interval: 0s (must be positive)")
+}
+
+func TestCmdMergeInvalidMethod(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	set.String("method", "octopus", "doc")
+	app, _, _ := appWithTestWriters()
+	err := command.CmdMerge(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Invalid merge method: octopus (must be one of merge, squash, rebase)")
+}
+
+func TestCmdMergeNoConfig(t *testing.T) {
+	app, _, _ := appWithTestWriters()
+	err := command.CmdMerge(cli.NewContext(app, flag.NewFlagSet("test", 0), nil))
+	assert.EqualError(t, err, "You must specify a config file")
+}
+
+func TestCmdMergeUsage(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"foo"}))
+	app, _, _ := appWithTestWriters()
+	err := command.CmdMerge(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Usage: \"prp merge\"")
+}
+
+func TestCompleteMergeFlags(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	app, writer, _ := appWithTestWriters()
+	app.Commands = []cli.Command{
+		{
+			Name: "merge",
+			Flags: []cli.Flag{
+				cli.StringSliceFlag{Name: "repo, r"},
+				cli.BoolFlag{Name: "dry-run, dr"},
+				cli.StringFlag{Name: "method, me"},
+			},
+		},
+	}
+	os.Args = []string{"merge", "--completion"}
+	command.CompleteMerge(cli.NewContext(app, set, nil))
+	assert.Equal(t, "--repo\n--dry-run\n--method\n", writer.String())
+}
+
+func TestCompleteMergeRepo(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	app, writer, _ := appWithTestWriters()
+	os.Args = []string{"merge", "--repo", "--completion"}
+	command.CompleteMerge(cli.NewContext(app, set, nil))
+	assert.Equal(t, "foo/bar\nown/rep\n", writer.String())
+}
+
+func TestCompleteMergeMethod(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	app, writer, _ := appWithTestWriters()
+	os.Args = []string{"merge", "--method", "--completion"}
+	command.CompleteMerge(cli.NewContext(app, set, nil))
+	assert.Equal(t, "merge\nsquash\nrebase\n", writer.String())
+}
+
+// getMergeTestServer serves PR #1 in own/rep ready to be merged and records every request that isn't a GET
+func getMergeTestServer(inner *httptest.Server, requests *[]string, responses map[string]string) *httptest.Server {
+	return getRecordingOverridingTestServer(inner, requests, mergeResponses(map[string]string{
+		"/repos/own/rep/pulls?per_page=100":                   getMergePullRequestsJSON("guy/rep", false),
+		"/repos/own/rep/compare/label...baseLabel1":           `{"ahead_by":0}`,
+		"/repos/own/rep/commits/sha1/check-runs?per_page=100": `{"check_runs":[]}`,
+		"/repos/own/rep/pulls/1/merge":                        `{"merged":true}`,
+		"/repos/guy/rep/git/refs/heads/ref1":                  "",
+	}, responses))
+}
+
+func getMergePullRequestsJSON(headRepoFullName string, draft bool) string {
+	pr := newPullRequest(1, "prOne", "guy", "label", "ref1", "sha1", "baseLabel1", "baseRef1")
+	if headRepoFullName != "" {
+		pr.Head.Repo.FullName = &headRepoFullName
+	}
+
+	bytes, _ := json.Marshal([]interface{}{
+		struct {
+			*github.PullRequest
+			Draft bool `json:"draft"`
+		}{pr, draft},
+	})
+	return string(bytes)
+}
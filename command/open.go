@@ -0,0 +1,382 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/google/go-github/github"
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/guywithnose/runner"
+	"github.com/urfave/cli"
+)
+
+// pullRequestDraft is what open knows about the pull request it is about to open
+// It is also the data a pull request template is rendered with
+type pullRequestDraft struct {
+	Title   string
+	Body    string
+	Branch  string
+	Base    string
+	Commits []string
+}
+
+// opener pushes a local branch and opens a pull request for it
+type opener struct {
+	cmdWrapper runner.Builder
+	git        gitBackend
+	client     *github.Client
+	user       *github.User
+	repo       *config.Repo
+}
+
+// CmdOpen pushes the current branch of a tracked clone and opens a pull request for it
+func CmdOpen(cmdWrapper runner.Builder) func(*cli.Context) error {
+	return func(c *cli.Context) error {
+		return cmdOpenHelper(c, cmdWrapper)
+	}
+}
+
+func cmdOpenHelper(c *cli.Context, cmdWrapper runner.Builder) error {
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() > 1 {
+		return cli.NewExitError("Usage: \"prp open [repoName]\"", 1)
+	}
+
+	profile := configData.Profiles[*profileName]
+	repo, err := loadOpenRepo(c, &profile)
+	if err != nil {
+		return err
+	}
+
+	client, err := getGithubClient(&profile.Token, &profile.APIURL, false)
+	if err != nil {
+		return err
+	}
+
+	user, _, err := client.Users.Get(context.Background(), "")
+	if err != nil {
+		return err
+	}
+
+	nativeGit, err := useNativeGit(c.String("git-backend"))
+	if err != nil {
+		return err
+	}
+
+	o := &opener{
+		cmdWrapper: cmdWrapper,
+		git:        newGitBackend(nativeGit, cmdWrapper, ioutil.Discard),
+		client:     client,
+		user:       user,
+		repo:       repo,
+	}
+
+	return o.open(c)
+}
+
+// loadOpenRepo finds the tracked repo named by the argument or the one whose clone contains --path or the working directory
+func loadOpenRepo(c *cli.Context, profile *config.Profile) (*config.Repo, error) {
+	if c.NArg() == 1 {
+		repo, _, err := loadRepo(profile, c.Args().First())
+		if err != nil {
+			return nil, err
+		}
+
+		if repo.LocalPath == "" {
+			return nil, cli.NewExitError(fmt.Sprintf("Path was not set for repo: %s/%s", repo.Owner, repo.Name), 1)
+		}
+
+		return repo, nil
+	}
+
+	path := c.String("path")
+	if path == "" {
+		workingDirectory, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+
+		path = workingDirectory
+	}
+
+	return loadRepoByPath(profile, path)
+}
+
+func (o opener) open(c *cli.Context) error {
+	upstream, _, err := o.client.Repositories.Get(context.Background(), o.repo.Owner, o.repo.Name)
+	if err != nil {
+		return err
+	}
+
+	owned := upstream
+	if o.repo.Owner != o.user.GetLogin() {
+		owned, _, err = o.client.Repositories.Get(context.Background(), o.user.GetLogin(), upstream.GetName())
+		if err != nil {
+			return fmt.Errorf("Unable to find your fork of %s/%s\n%v", o.repo.Owner, o.repo.Name, err)
+		}
+	}
+
+	ownedRemote, upstreamRemote, err := o.getRemotes(owned.GetSSHURL(), upstream.GetSSHURL())
+	if err != nil {
+		return err
+	}
+
+	draft, err := o.draft(c, upstreamRemote, upstream.GetDefaultBranch())
+	if err != nil {
+		return err
+	}
+
+	reviewers, labels := o.defaults(c)
+	if c.Bool("dry-run") {
+		fmt.Fprintf(c.App.Writer, "Would open a pull request from %s onto %s in %s/%s\n", draft.Branch, draft.Base, o.repo.Owner, o.repo.Name)
+		fmt.Fprintf(c.App.Writer, "Title: %s\n", draft.Title)
+		fmt.Fprintf(c.App.Writer, "Reviewers: %s\n", strings.Join(reviewers, ", "))
+		fmt.Fprintf(c.App.Writer, "Labels: %s\n", strings.Join(labels, ", "))
+		fmt.Fprintf(c.App.Writer, "\n%s\n", draft.Body)
+		return nil
+	}
+
+	err = o.runCommand("git", "push", "--set-upstream", ownedRemote, draft.Branch)
+	if err != nil {
+		return wrapExitError(err, fmt.Sprintf("Unable to push %s to %s", draft.Branch, ownedRemote))
+	}
+
+	head := draft.Branch
+	if owned.GetFullName() != upstream.GetFullName() {
+		head = fmt.Sprintf("%s:%s", o.user.GetLogin(), draft.Branch)
+	}
+
+	maintainerCanModify := true
+	created, _, err := o.client.PullRequests.Create(context.Background(), o.repo.Owner, o.repo.Name, &github.NewPullRequest{
+		Title:               &draft.Title,
+		Head:                &head,
+		Base:                &draft.Base,
+		Body:                &draft.Body,
+		MaintainerCanModify: &maintainerCanModify,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.App.Writer, "Opened PR #%d in %s/%s: %s\n%s\n", created.GetNumber(), o.repo.Owner, o.repo.Name, draft.Title, created.GetHTMLURL())
+	return o.addReviewersAndLabels(created.GetNumber(), reviewers, labels, c)
+}
+
+func (o opener) getRemotes(ownedSSHURL, upstreamSSHURL string) (string, string, error) {
+	remotes, err := o.git.remotes(o.repo.LocalPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	ownedRemote, ok := remotes[fmt.Sprintf("%s (push)", ownedSSHURL)]
+	if !ok {
+		return "", "", fmt.Errorf("No remote exists in %s that points to %s", o.repo.LocalPath, ownedSSHURL)
+	}
+
+	upstreamRemote, ok := remotes[fmt.Sprintf("%s (fetch)", upstreamSSHURL)]
+	if !ok {
+		return "", "", fmt.Errorf("No remote exists in %s that points to %s", o.repo.LocalPath, upstreamSSHURL)
+	}
+
+	return ownedRemote, upstreamRemote, nil
+}
+
+// draft works out the title and body of the pull request from the commits that aren't in the base branch yet
+func (o opener) draft(c *cli.Context, upstreamRemote, defaultBranch string) (*pullRequestDraft, error) {
+	branch, err := o.git.currentBranch(o.repo.LocalPath)
+	if err != nil {
+		return nil, err
+	}
+
+	base := c.String("base")
+	if base == "" {
+		base = defaultBranch
+	}
+
+	if branch == base {
+		return nil, cli.NewExitError(fmt.Sprintf("Check out the branch you want to open a pull request for, %s is the base branch", base), 1)
+	}
+
+	err = o.runCommand("git", "fetch", upstreamRemote)
+	if err != nil {
+		return nil, wrapExitError(err, fmt.Sprintf("Unable to fetch %s", upstreamRemote))
+	}
+
+	commits, err := o.output("git", "log", "--reverse", "--format=%s", fmt.Sprintf("%s/%s..HEAD", upstreamRemote, base))
+	if err != nil {
+		return nil, wrapExitError(err, fmt.Sprintf("Unable to list the commits of %s", branch))
+	}
+
+	draft := &pullRequestDraft{Branch: branch, Base: base, Commits: nonEmptyLines(commits)}
+	switch len(draft.Commits) {
+	case 0:
+		return nil, cli.NewExitError(fmt.Sprintf("%s has no commits that aren't in %s/%s", branch, upstreamRemote, base), 1)
+	case 1:
+		draft.Title = draft.Commits[0]
+		body, err := o.output("git", "log", "-1", "--format=%b", "HEAD")
+		if err != nil {
+			return nil, wrapExitError(err, fmt.Sprintf("Unable to read the last commit of %s", branch))
+		}
+
+		draft.Body = strings.TrimSpace(body)
+	default:
+		draft.Title = titleFromBranch(branch)
+		draft.Body = fmt.Sprintf("- %s", strings.Join(draft.Commits, "\n- "))
+	}
+
+	if c.String("title") != "" {
+		draft.Title = c.String("title")
+	}
+
+	if c.String("body") != "" {
+		draft.Body = c.String("body")
+		return draft, nil
+	}
+
+	if o.repo.PullRequestDefaults != nil && o.repo.PullRequestDefaults.Template != "" {
+		draft.Body, err = o.renderTemplate(o.repo.PullRequestDefaults.Template, draft)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return draft, nil
+}
+
+func (o opener) renderTemplate(templatePath string, draft *pullRequestDraft) (string, error) {
+	if !filepath.IsAbs(templatePath) {
+		templatePath = filepath.Join(o.repo.LocalPath, templatePath)
+	}
+
+	templateText, err := ioutil.ReadFile(templatePath)
+	if err != nil {
+		return "", err
+	}
+
+	bodyTemplate, err := template.New(filepath.Base(templatePath)).Parse(string(templateText))
+	if err != nil {
+		return "", err
+	}
+
+	body := &bytes.Buffer{}
+	err = bodyTemplate.Execute(body, draft)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(body.String()), nil
+}
+
+// defaults combines the repo's default reviewers and labels with the ones passed as flags
+func (o opener) defaults(c *cli.Context) ([]string, []string) {
+	reviewers := c.StringSlice("reviewer")
+	labels := c.StringSlice("label")
+	if o.repo.PullRequestDefaults != nil {
+		reviewers = append(append([]string{}, o.repo.PullRequestDefaults.Reviewers...), reviewers...)
+		labels = append(append([]string{}, o.repo.PullRequestDefaults.Labels...), labels...)
+	}
+
+	// You can't request a review from yourself
+	filteredReviewers := []string{}
+	for _, reviewer := range unique(reviewers) {
+		if reviewer != o.user.GetLogin() {
+			filteredReviewers = append(filteredReviewers, reviewer)
+		}
+	}
+
+	labels = unique(labels)
+	sort.Strings(filteredReviewers)
+	sort.Strings(labels)
+	return filteredReviewers, labels
+}
+
+func (o opener) addReviewersAndLabels(number int, reviewers, labels []string, c *cli.Context) error {
+	var completeError error
+	if len(reviewers) != 0 {
+		_, _, err := o.client.PullRequests.RequestReviewers(context.Background(), o.repo.Owner, o.repo.Name, number, github.ReviewersRequest{Reviewers: reviewers})
+		if err != nil {
+			fmt.Fprintf(c.App.ErrWriter, "Could not request reviews from %s because: %v\n", strings.Join(reviewers, ", "), err)
+			completeError = cli.NewExitError(fmt.Sprintf("Unable to finish setting up PR #%d in %s/%s", number, o.repo.Owner, o.repo.Name), 1)
+		}
+	}
+
+	if len(labels) != 0 {
+		_, _, err := o.client.Issues.AddLabelsToIssue(context.Background(), o.repo.Owner, o.repo.Name, number, labels)
+		if err != nil {
+			fmt.Fprintf(c.App.ErrWriter, "Could not add the labels %s because: %v\n", strings.Join(labels, ", "), err)
+			completeError = cli.NewExitError(fmt.Sprintf("Unable to finish setting up PR #%d in %s/%s", number, o.repo.Owner, o.repo.Name), 1)
+		}
+	}
+
+	return completeError
+}
+
+func (o opener) runCommand(command ...string) error {
+	_, err := o.output(command...)
+	return err
+}
+
+func (o opener) output(command ...string) (string, error) {
+	cmd := o.cmdWrapper.New(o.repo.LocalPath, command...)
+	output, err := cmd.Output()
+	return string(output), err
+}
+
+// titleFromBranch turns a branch name like fix-the-thing into a title like Fix the thing
+func titleFromBranch(branch string) string {
+	title := strings.NewReplacer("-", " ", "_", " ").Replace(branch[strings.LastIndex(branch, "/")+1:])
+	if title == "" {
+		return branch
+	}
+
+	runes := []rune(title)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+func nonEmptyLines(output string) []string {
+	lines := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+// CompleteOpen handles bash autocompletion for the 'open' command
+func CompleteOpen(c *cli.Context) {
+	if c.NArg() != 0 {
+		completeFlags(c, "open")
+		return
+	}
+
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return
+	}
+
+	profile := configData.Profiles[*profileName]
+	repoNames := []string{}
+	for _, repo := range profile.TrackedRepos {
+		if repo.LocalPath != "" {
+			repoNames = append(repoNames, fmt.Sprintf("%s/%s", repo.Owner, repo.Name))
+		}
+	}
+
+	sort.Strings(repoNames)
+	fmt.Fprintln(c.App.Writer, strings.Join(repoNames, "\n"))
+}
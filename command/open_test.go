@@ -0,0 +1,385 @@
+package command_test
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/guywithnose/pull-request-parser/command"
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/guywithnose/runner"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+func TestCmdOpen(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	var testCases = []struct {
+		name             string
+		modifyRepo       func(*config.Repo)
+		setFlags         func(*flag.FlagSet)
+		expectedCommands []*runner.ExpectedCommand
+		expectedRequests []string
+		expectedOutput   string
+	}{
+		{
+			"SingleCommit",
+			func(*config.Repo) {},
+			func(*flag.FlagSet) {},
+			getOpenExpectedCommands(repoDir, "Fix the thing", true),
+			[]string{
+				`POST /repos/own/rep/pulls {"title":"Fix the thing","head":"guy:fix-thing","base":"master","body":"It was broken","maintainer_can_modify":true}`,
+			},
+			"Opened PR #9 in own/rep: Fix the thing\nhttps://github.com/own/rep/pull/9\n",
+		},
+		{
+			"MultipleCommits",
+			func(*config.Repo) {},
+			func(*flag.FlagSet) {},
+			getOpenExpectedCommands(repoDir, "Fix the thing\nTest the thing", true),
+			[]string{
+				`POST /repos/own/rep/pulls {"title":"Fix thing","head":"guy:fix-thing","base":"master","body":"- Fix the thing\n- Test the thing","maintainer_can_modify":true}`,
+			},
+			"Opened PR #9 in own/rep: Fix thing\nhttps://github.com/own/rep/pull/9\n",
+		},
+		{
+			"Defaults",
+			func(repo *config.Repo) {
+				repo.PullRequestDefaults = &config.PullRequestDefaults{Reviewers: []string{"guy", "reviewer1"}, Labels: []string{"label1"}}
+			},
+			func(set *flag.FlagSet) {
+				reviewers := cli.StringSlice{"reviewer2"}
+				set.Var(&reviewers, "reviewer", "doc")
+				labels := cli.StringSlice{"label1", "label2"}
+				set.Var(&labels, "label", "doc")
+			},
+			getOpenExpectedCommands(repoDir, "Fix the thing", true),
+			[]string{
+				`POST /repos/own/rep/pulls {"title":"Fix the thing","head":"guy:fix-thing","base":"master","body":"It was broken","maintainer_can_modify":true}`,
+				`POST /repos/own/rep/pulls/9/requested_reviewers {"reviewers":["reviewer1","reviewer2"]}`,
+				`POST /repos/own/rep/issues/9/labels ["label1","label2"]`,
+			},
+			"Opened PR #9 in own/rep: Fix the thing\nhttps://github.com/own/rep/pull/9\n",
+		},
+		{
+			"Template",
+			func(repo *config.Repo) {
+				repo.PullRequestDefaults = &config.PullRequestDefaults{Template: "template.md"}
+			},
+			func(*flag.FlagSet) {},
+			getOpenExpectedCommands(repoDir, "Fix the thing\nTest the thing", true),
+			[]string{
+				`POST /repos/own/rep/pulls {"title":"Fix thing","head":"guy:fix-thing","base":"master","body":"Merges fix-thing into master\n\n* Fix the thing\n* Test the thing","maintainer_can_modify":true}`,
+			},
+			"Opened PR #9 in own/rep: Fix thing\nhttps://github.com/own/rep/pull/9\n",
+		},
+		{
+			"Flags",
+			func(*config.Repo) {},
+			func(set *flag.FlagSet) {
+				set.String("base", "develop", "doc")
+				set.String("title", "A title", "doc")
+				set.String("body", "A body", "doc")
+			},
+			[]*runner.ExpectedCommand{
+				runner.NewExpectedCommand(repoDir, "git remote -v", "origin\tlabelSSHURL (push)\nupstream\tbaseLabel1SSHURL (fetch)", 0),
+				runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "refs/heads/fix-thing", 0),
+				runner.NewExpectedCommand(repoDir, "git fetch upstream", "", 0),
+				runner.NewExpectedCommand(repoDir, "git log --reverse --format=%s upstream/develop..HEAD", "Fix the thing\nTest the thing", 0),
+				runner.NewExpectedCommand(repoDir, "git push --set-upstream origin fix-thing", "", 0),
+			},
+			[]string{
+				`POST /repos/own/rep/pulls {"title":"A title","head":"guy:fix-thing","base":"develop","body":"A body","maintainer_can_modify":true}`,
+			},
+			"Opened PR #9 in own/rep: A title\nhttps://github.com/own/rep/pull/9\n",
+		},
+		{
+			"DryRun",
+			func(repo *config.Repo) {
+				repo.PullRequestDefaults = &config.PullRequestDefaults{Reviewers: []string{"reviewer1"}, Labels: []string{"label1"}}
+			},
+			func(set *flag.FlagSet) { set.Bool("dry-run", true, "doc") },
+			getOpenExpectedCommands(repoDir, "Fix the thing", false),
+			[]string{},
+			"Would open a pull request from fix-thing onto master in own/rep\nTitle: Fix the thing\nReviewers: reviewer1\nLabels: label1\n\nIt was broken\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/.git", repoDir), 0777))
+			defer removeFile(t, repoDir)
+			assert.Nil(t, ioutil.WriteFile(fmt.Sprintf("%s/template.md", repoDir), []byte("Merges {{.Branch}} into {{.Base}}\n\n{{range .Commits}}* {{.}}\n{{end}}"), 0644))
+			requests := []string{}
+			cb := &runner.Test{ExpectedCommands: tc.expectedCommands}
+			writer, errWriter, err := runOpenAgainstServer(t, repoDir, cb, &requests, "", tc.modifyRepo, tc.setFlags)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedRequests, requests)
+			assert.Equal(t, tc.expectedOutput, writer.String())
+			assert.Equal(t, "", errWriter.String())
+		})
+	}
+}
+
+func TestCmdOpenErrors(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	var testCases = []struct {
+		name             string
+		setFlags         func(*flag.FlagSet)
+		expectedCommands []*runner.ExpectedCommand
+		expectedError    string
+	}{
+		{
+			"BaseBranch",
+			func(*flag.FlagSet) {},
+			[]*runner.ExpectedCommand{
+				runner.NewExpectedCommand(repoDir, "git remote -v", "origin\tlabelSSHURL (push)\nupstream\tbaseLabel1SSHURL (fetch)", 0),
+				runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "refs/heads/master", 0),
+			},
+			"Check out the branch you want to open a pull request for, master is the base branch",
+		},
+		{
+			"NoCommits",
+			func(*flag.FlagSet) {},
+			[]*runner.ExpectedCommand{
+				runner.NewExpectedCommand(repoDir, "git remote -v", "origin\tlabelSSHURL (push)\nupstream\tbaseLabel1SSHURL (fetch)", 0),
+				runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "refs/heads/fix-thing", 0),
+				runner.NewExpectedCommand(repoDir, "git fetch upstream", "", 0),
+				runner.NewExpectedCommand(repoDir, "git log --reverse --format=%s upstream/master..HEAD", "", 0),
+			},
+			"fix-thing has no commits that aren't in upstream/master",
+		},
+		{
+			"NoRemote",
+			func(*flag.FlagSet) {},
+			[]*runner.ExpectedCommand{
+				runner.NewExpectedCommand(repoDir, "git remote -v", "upstream\tbaseLabel1SSHURL (fetch)", 0),
+			},
+			fmt.Sprintf("No remote exists in %s that points to labelSSHURL", repoDir),
+		},
+		{
+			"PushFailure",
+			func(*flag.FlagSet) {},
+			append(
+				getOpenExpectedCommands(repoDir, "Fix the thing", false),
+				runner.NewExpectedCommand(repoDir, "git push --set-upstream origin fix-thing", "rejected", 1),
+			),
+			"Unable to push fix-thing to origin",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/.git", repoDir), 0777))
+			defer removeFile(t, repoDir)
+			requests := []string{}
+			cb := &runner.Test{ExpectedCommands: tc.expectedCommands}
+			_, _, err := runOpenAgainstServer(t, repoDir, cb, &requests, "", func(*config.Repo) {}, tc.setFlags)
+			assert.NotNil(t, err)
+			assert.Contains(t, err.Error(), tc.expectedError)
+			assert.Equal(t, []string{}, requests)
+		})
+	}
+}
+
+func TestCmdOpenSetupFailure(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/.git", repoDir), 0777))
+	defer removeFile(t, repoDir)
+	requests := []string{}
+	cb := &runner.Test{ExpectedCommands: getOpenExpectedCommands(repoDir, "Fix the thing", true)}
+	writer, errWriter, err := runOpenAgainstServer(
+		t,
+		repoDir,
+		cb,
+		&requests,
+		"/repos/own/rep/pulls/9/requested_reviewers",
+		func(*config.Repo) {},
+		func(set *flag.FlagSet) {
+			reviewers := cli.StringSlice{"reviewer1"}
+			set.Var(&reviewers, "reviewer", "doc")
+		},
+	)
+	assert.EqualError(t, err, "Unable to finish setting up PR #9 in own/rep")
+	assert.Equal(t, "Opened PR #9 in own/rep: Fix the thing\nhttps://github.com/own/rep/pull/9\n", writer.String())
+	assert.Contains(t, errWriter.String(), "Could not request reviews from reviewer1 because: ")
+}
+
+func TestCmdOpenPath(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/.git", repoDir), 0777))
+	defer removeFile(t, repoDir)
+	requests := []string{}
+	cb := &runner.Test{ExpectedCommands: getOpenExpectedCommands(repoDir, "Fix the thing", false)}
+	writer, _, err := runOpenAgainstServer(t, repoDir, cb, &requests, "", func(*config.Repo) {}, func(set *flag.FlagSet) {
+		set.String("path", fmt.Sprintf("%s/sub/dir", repoDir), "doc")
+		set.Bool("dry-run", true, "doc")
+	})
+	assert.Nil(t, err)
+	assert.Contains(t, writer.String(), "Would open a pull request from fix-thing onto master in own/rep\n")
+}
+
+func TestCmdOpenSymlinkedPath(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/.git", repoDir), 0777))
+	assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/sub", repoDir), 0777))
+	defer removeFile(t, repoDir)
+	link := fmt.Sprintf("%s/repo-link", os.TempDir())
+	assert.Nil(t, os.Symlink(repoDir, link))
+	defer removeFile(t, link)
+	requests := []string{}
+	cb := &runner.Test{ExpectedCommands: getOpenExpectedCommands(repoDir, "Fix the thing", false)}
+	writer, _, err := runOpenAgainstServer(t, repoDir, cb, &requests, "", func(*config.Repo) {}, func(set *flag.FlagSet) {
+		set.String("path", fmt.Sprintf("%s/sub/..//sub", link), "doc")
+		set.Bool("dry-run", true, "doc")
+	})
+	assert.Nil(t, err)
+	assert.Contains(t, writer.String(), "Would open a pull request from fix-thing onto master in own/rep\n")
+}
+
+func TestCmdOpenNativeGit(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/.git", repoDir), 0777))
+	assert.Nil(t, ioutil.WriteFile(fmt.Sprintf("%s/.git/HEAD", repoDir), []byte("ref: refs/heads/fix-thing\n"), 0644))
+	defer removeFile(t, repoDir)
+	requests := []string{}
+	expectedCommands := getOpenExpectedCommands(repoDir, "Fix the thing", false)
+	// HEAD is read from .git/HEAD instead of running git symbolic-ref
+	expectedCommands = append(expectedCommands[:1], expectedCommands[2:]...)
+	cb := &runner.Test{ExpectedCommands: expectedCommands}
+	writer, _, err := runOpenAgainstServer(t, repoDir, cb, &requests, "", func(*config.Repo) {}, func(set *flag.FlagSet) {
+		set.String("git-backend", "native", "doc")
+		set.Bool("dry-run", true, "doc")
+	})
+	assert.Nil(t, err)
+	assert.Contains(t, writer.String(), "Would open a pull request from fix-thing onto master in own/rep\n")
+}
+
+func TestCmdOpenUntrackedPath(t *testing.T) {
+	ts := getAutoRebaseTestServer("")
+	defer ts.Close()
+	_, configFileName := getConfigWithAPIURLAndPath(t, ts.URL, fmt.Sprintf("%s/repo", os.TempDir()))
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	set.String("path", "/somewhere/else", "doc")
+	app, _, _ := appWithTestWriters()
+	err := command.CmdOpen(&runner.Test{})(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "/somewhere/else is not inside the local path of a tracked repo")
+}
+
+func TestCmdOpenRepoWithoutPath(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep"}))
+	app, _, _ := appWithTestWriters()
+	err := command.CmdOpen(&runner.Test{})(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Path was not set for repo: own/rep")
+}
+
+func TestCmdOpenNoConfig(t *testing.T) {
+	app, _, _ := appWithTestWriters()
+	err := command.CmdOpen(&runner.Test{})(cli.NewContext(app, flag.NewFlagSet("test", 0), nil))
+	assert.EqualError(t, err, "You must specify a config file")
+}
+
+func TestCmdOpenUsage(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep", "extra"}))
+	app, _, _ := appWithTestWriters()
+	err := command.CmdOpen(&runner.Test{})(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Usage: \"prp open [repoName]\"")
+}
+
+func TestCompleteOpen(t *testing.T) {
+	_, configFileName := getConfigWithAPIURLAndPath(t, "", "/tmp/repo")
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	app, writer, _ := appWithTestWriters()
+	os.Args = []string{"open", "--completion"}
+	command.CompleteOpen(cli.NewContext(app, set, nil))
+	assert.Equal(t, "own/rep\n", writer.String())
+}
+
+func TestCompleteOpenFlags(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep"}))
+	app, writer, _ := appWithTestWriters()
+	app.Commands = []cli.Command{
+		{
+			Name: "open",
+			Flags: []cli.Flag{
+				cli.StringFlag{Name: "base, b"},
+				cli.BoolFlag{Name: "dry-run"},
+			},
+		},
+	}
+	os.Args = []string{"open", "own/rep", "--completion"}
+	command.CompleteOpen(cli.NewContext(app, set, nil))
+	assert.Equal(t, "--base\n--dry-run\n", writer.String())
+}
+
+func runOpenAgainstServer(
+	t *testing.T,
+	repoDir string,
+	cb *runner.Test,
+	requests *[]string,
+	failureURL string,
+	modifyRepo func(*config.Repo),
+	setFlags func(*flag.FlagSet),
+) (*bytes.Buffer, *bytes.Buffer, error) {
+	t.Helper()
+	inner := getAutoRebaseTestServer(failureURL)
+	defer inner.Close()
+	responses := map[string]string{
+		"/repos/own/rep":                             `{"name":"rep","full_name":"own/rep","ssh_url":"baseLabel1SSHURL","default_branch":"master"}`,
+		"/repos/guy/rep":                             `{"name":"rep","full_name":"guy/rep","ssh_url":"labelSSHURL","default_branch":"master"}`,
+		"/repos/own/rep/pulls":                       `{"number":9,"html_url":"https://github.com/own/rep/pull/9"}`,
+		"/repos/own/rep/pulls/9/requested_reviewers": "{}",
+		"/repos/own/rep/issues/9/labels":             "[]",
+	}
+	delete(responses, failureURL)
+	ts := getRecordingOverridingTestServer(inner, requests, responses)
+	defer ts.Close()
+	conf, configFileName := getConfigWithAPIURLAndPath(t, ts.URL, repoDir)
+	defer removeFile(t, configFileName)
+	modifyRepo(&conf.Profiles["foo"].TrackedRepos[1])
+	assert.Nil(t, conf.Write(configFileName))
+	set := getBaseFlagSet(configFileName)
+	setFlags(set)
+	if set.Lookup("path") == nil {
+		set.String("path", repoDir, "doc")
+	}
+	app, writer, errWriter := appWithTestWriters()
+	err := command.CmdOpen(cb)(cli.NewContext(app, set, nil))
+	assert.Equal(t, []*runner.ExpectedCommand{}, cb.ExpectedCommands)
+	assert.Equal(t, []error(nil), cb.Errors)
+	return writer, errWriter, err
+}
+
+// getOpenExpectedCommands are the commands open runs for the fix-thing branch whose commits have the given subjects
+func getOpenExpectedCommands(repoDir, subjects string, push bool) []*runner.ExpectedCommand {
+	commands := []*runner.ExpectedCommand{
+		runner.NewExpectedCommand(repoDir, "git remote -v", "origin\tlabelSSHURL (push)\nupstream\tbaseLabel1SSHURL (fetch)", 0),
+		runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "refs/heads/fix-thing", 0),
+		runner.NewExpectedCommand(repoDir, "git fetch upstream", "", 0),
+		runner.NewExpectedCommand(repoDir, "git log --reverse --format=%s upstream/master..HEAD", subjects, 0),
+	}
+	if !strings.Contains(subjects, "\n") {
+		commands = append(commands, runner.NewExpectedCommand(repoDir, "git log -1 --format=%b HEAD", "It was broken\n", 0))
+	}
+
+	if push {
+		commands = append(commands, runner.NewExpectedCommand(repoDir, "git push --set-upstream origin fix-thing", "", 0))
+	}
+
+	return commands
+}
@@ -48,7 +48,7 @@ func CmdParse(c *cli.Context) error {
 func CompleteParse(c *cli.Context) {
 	lastParam := os.Args[len(os.Args)-2]
 	if lastParam != "--user" && lastParam != "--repo" {
-		completeFlags(c)
+		completeFlags(c, "parse")
 		return
 	}
 
@@ -77,8 +77,8 @@ func completeRepo(selectedRepos []string, profile config.Profile, writer io.Writ
 	}
 }
 
-func completeFlags(c *cli.Context) {
-	for _, flag := range c.App.Command("parse").Flags {
+func completeFlags(c *cli.Context, commandName string) {
+	for _, flag := range c.App.Command(commandName).Flags {
 		name := strings.Split(flag.GetName(), ",")[0]
 		if !c.IsSet(name) || name == "repo" {
 			fmt.Fprintf(c.App.Writer, "--%s\n", name)
@@ -1,11 +1,14 @@
 package command
 
 import (
+	"fmt"
 	"io"
+	"net/http"
 	"sync"
 
 	"github.com/google/go-github/github"
 	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/urfave/cli"
 )
 
 type prParser struct {
@@ -44,24 +47,56 @@ func (parser prParser) getRepositoryPullRequests(repo config.Repo, prs chan<- *p
 	repoPrs := getRepoPullRequestsAndReportErrors(parser.client, repo.Owner, repo.Name, errorWriter)
 
 	for pr := range repoPrs {
-		prs <- &pullRequest{
-			client:          parser.client,
-			Repo:            &repo,
-			PullRequestID:   pr.GetNumber(),
-			Title:           pr.GetTitle(),
-			Owner:           pr.Head.User.GetLogin(),
-			Branch:          pr.Head.GetRef(),
-			TargetBranch:    pr.Base.GetRef(),
-			HeadLabel:       pr.Head.GetLabel(),
-			BaseLabel:       pr.Base.GetLabel(),
-			SHA:             pr.Head.GetSHA(),
-			BaseSSHURL:      pr.Base.Repo.GetSSHURL(),
-			HeadSSHURL:      pr.Head.Repo.GetSSHURL(),
-			BuildInfo:       map[string]bool{},
-			NeedsMyApproval: parser.user.GetLogin() != pr.Head.User.GetLogin(),
-			IgnoredBuilds:   repo.IgnoredBuilds,
-		}
+		prs <- parser.newPullRequest(&repo, pr)
+	}
+}
+
+func (parser prParser) newPullRequest(repo *config.Repo, pr *githubPullRequest) *pullRequest {
+	return &pullRequest{
+		client:              parser.client,
+		Repo:                repo,
+		PullRequestID:       pr.GetNumber(),
+		Title:               pr.GetTitle(),
+		Owner:               pr.Head.User.GetLogin(),
+		Branch:              pr.Head.GetRef(),
+		TargetBranch:        pr.Base.GetRef(),
+		HeadLabel:           pr.Head.GetLabel(),
+		BaseLabel:           pr.Base.GetLabel(),
+		SHA:                 pr.Head.GetSHA(),
+		BaseSHA:             pr.Base.GetSHA(),
+		BaseSSHURL:          pr.Base.Repo.GetSSHURL(),
+		HeadSSHURL:          pr.Head.Repo.GetSSHURL(),
+		HeadRepoFullName:    pr.Head.Repo.GetFullName(),
+		BaseDefaultBranch:   pr.Base.Repo.GetDefaultBranch(),
+		CreatedAt:           pr.GetCreatedAt(),
+		Draft:               pr.GetDraft(),
+		MaintainerCanModify: pr.GetMaintainerCanModify(),
+		BuildInfo:           map[string]bool{},
+		NeedsMyApproval:     parser.user.GetLogin() != pr.Head.User.GetLogin(),
+		IgnoredBuilds:       repo.IgnoredBuilds,
+		mine:                parser.user.GetLogin() == pr.Head.User.GetLogin(),
+	}
+}
+
+// getPullRequest loads a single open pull request with everything parsePullRequests adds to it
+func (parser prParser) getPullRequest(repo *config.Repo, number int) (*pullRequest, error) {
+	githubPR, response, err := getPullRequest(parser.client, repo.Owner, repo.Name, number)
+	if response != nil && response.StatusCode == http.StatusNotFound {
+		return nil, cli.NewExitError(fmt.Sprintf("PR #%d in %s/%s was not found", number, repo.Owner, repo.Name), 1)
+	}
+
+	if err != nil {
+		return nil, err
 	}
+
+	if githubPR.GetState() != "open" {
+		return nil, cli.NewExitError(fmt.Sprintf("PR #%d in %s/%s is already closed", number, repo.Owner, repo.Name), 1)
+	}
+
+	pr := parser.newPullRequest(repo, githubPR)
+	pr.getAdditionalData(parser.user)
+	pr.setColor(parser.user)
+	return pr, nil
 }
 
 func (parser prParser) parsePullRequests(prs <-chan *pullRequest, owner string, repos []string, needsRebase bool) <-chan *pullRequest {
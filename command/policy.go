@@ -0,0 +1,83 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/urfave/cli"
+)
+
+// rebasePolicyViolation returns the reason pr may not be rebased under policy or "" if it may
+// The approvals, labels and statuses are only requested when the policy needs them
+func (pr *pullRequest) rebasePolicyViolation(policy config.RebasePolicy) string {
+	if !pr.mine && pr.HeadSSHURL != pr.BaseSSHURL && !pr.MaintainerCanModify {
+		return "its fork does not allow edits from maintainers"
+	}
+
+	if policy.SkipDrafts && pr.Draft {
+		return "it is a draft"
+	}
+
+	if policy.MinApprovals > 0 {
+		pr.getApprovals(nil)
+		if pr.Approvals < policy.MinApprovals {
+			return fmt.Sprintf("it has %d of %d required approvals", pr.Approvals, policy.MinApprovals)
+		}
+	}
+
+	if len(policy.RequiredLabels) > 0 {
+		pr.getLabels()
+		for _, label := range policy.RequiredLabels {
+			if !stringSliceContains(label, pr.Labels) {
+				return fmt.Sprintf("it does not have the label %s", label)
+			}
+		}
+	}
+
+	if policy.RequirePassingBuilds {
+		return pr.buildBlocker()
+	}
+
+	return ""
+}
+
+// buildBlocker explains why the commit statuses and check runs of pr's head keep it from being merged
+// A head without any builds is never merged because nothing checked it
+func (pr *pullRequest) buildBlocker() string {
+	contexts, err := pr.getLatestStatuses(pr.SHA)
+	if err != nil {
+		return fmt.Sprintf("its builds could not be checked: %v", err)
+	}
+
+	if len(contexts) == 0 {
+		return "it has no builds"
+	}
+
+	names := make([]string, 0, len(contexts))
+	for context := range contexts {
+		names = append(names, context)
+	}
+
+	sort.Strings(names)
+	for _, context := range names {
+		if contexts[context] == "pending" {
+			return fmt.Sprintf("the %s build is still running", context)
+		}
+
+		if !statePassed(contexts[context]) {
+			return fmt.Sprintf("the %s build is not passing", context)
+		}
+	}
+
+	return ""
+}
+
+func rebasePolicyFromFlags(c *cli.Context) config.RebasePolicy {
+	return config.RebasePolicy{
+		MinApprovals:         c.Int("min-approvals"),
+		RequiredLabels:       c.StringSlice("require-label"),
+		SkipDrafts:           c.Bool("skip-drafts"),
+		RequirePassingBuilds: c.Bool("require-passing-builds"),
+	}
+}
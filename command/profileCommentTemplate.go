@@ -0,0 +1,75 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli"
+)
+
+// CmdProfileAddCommentTemplate saves a comment template in the profile
+func CmdProfileAddCommentTemplate(c *cli.Context) error {
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() != 2 {
+		return cli.NewExitError("Usage: \"prp profile add-comment-template {templateName} {template}\"", 1)
+	}
+
+	_, err = parseCommentTemplate(c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	profile := configData.Profiles[*profileName]
+	if profile.CommentTemplates == nil {
+		profile.CommentTemplates = make(map[string]string)
+	}
+
+	profile.CommentTemplates[c.Args().Get(0)] = c.Args().Get(1)
+	configData.Profiles[*profileName] = profile
+
+	return configData.Write(c.GlobalString("config"))
+}
+
+// CmdProfileRemoveCommentTemplate removes a comment template from the profile
+func CmdProfileRemoveCommentTemplate(c *cli.Context) error {
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() != 1 {
+		return cli.NewExitError("Usage: \"prp profile remove-comment-template {templateName}\"", 1)
+	}
+
+	profile := configData.Profiles[*profileName]
+	if _, ok := profile.CommentTemplates[c.Args().Get(0)]; !ok {
+		return cli.NewExitError(fmt.Sprintf("Profile %s has no comment template named %s", *profileName, c.Args().Get(0)), 1)
+	}
+
+	delete(profile.CommentTemplates, c.Args().Get(0))
+	if len(profile.CommentTemplates) == 0 {
+		profile.CommentTemplates = nil
+	}
+
+	configData.Profiles[*profileName] = profile
+
+	return configData.Write(c.GlobalString("config"))
+}
+
+// CompleteProfileRemoveCommentTemplate handles bash autocompletion for the 'profile remove-comment-template' command
+func CompleteProfileRemoveCommentTemplate(c *cli.Context) {
+	if c.NArg() >= 1 {
+		return
+	}
+
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return
+	}
+
+	profile := configData.Profiles[*profileName]
+	completeCommentTemplates(&profile, c)
+}
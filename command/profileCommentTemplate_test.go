@@ -0,0 +1,112 @@
+package command_test
+
+import (
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/guywithnose/pull-request-parser/command"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+func TestCmdProfileAddCommentTemplate(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"rebase", "@{{.Owner}} please rebase {{.Branch}}"}))
+	assert.Nil(t, command.CmdProfileAddCommentTemplate(cli.NewContext(nil, set, nil)))
+
+	expectedConfigFile, disposableConfigFile := getConfigWithFooProfile(t)
+	removeFile(t, disposableConfigFile)
+	profile := expectedConfigFile.Profiles["foo"]
+	profile.CommentTemplates = map[string]string{"rebase": "@{{.Owner}} please rebase {{.Branch}}"}
+	expectedConfigFile.Profiles["foo"] = profile
+	assertConfigFile(t, expectedConfigFile, configFileName)
+}
+
+func TestCmdProfileAddCommentTemplateInvalid(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"rebase", "{{.Owner"}))
+	err := command.CmdProfileAddCommentTemplate(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "Invalid comment template: template: comment:1: unclosed action")
+}
+
+func TestCmdProfileAddCommentTemplateUsage(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"rebase"}))
+	err := command.CmdProfileAddCommentTemplate(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "Usage: \"prp profile add-comment-template {templateName} {template}\"")
+}
+
+func TestCmdProfileAddCommentTemplateNoConfig(t *testing.T) {
+	err := command.CmdProfileAddCommentTemplate(cli.NewContext(nil, flag.NewFlagSet("test", 0), nil))
+	assert.EqualError(t, err, "You must specify a config file")
+}
+
+func TestCmdProfileRemoveCommentTemplate(t *testing.T) {
+	configData, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	profile := configData.Profiles["foo"]
+	profile.CommentTemplates = map[string]string{"rebase": "Please rebase"}
+	configData.Profiles["foo"] = profile
+	assert.Nil(t, configData.Write(configFileName))
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"rebase"}))
+	assert.Nil(t, command.CmdProfileRemoveCommentTemplate(cli.NewContext(nil, set, nil)))
+
+	expectedConfigFile, disposableConfigFile := getConfigWithFooProfile(t)
+	removeFile(t, disposableConfigFile)
+	assertConfigFile(t, expectedConfigFile, configFileName)
+}
+
+func TestCmdProfileRemoveCommentTemplateMissing(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"rebase"}))
+	err := command.CmdProfileRemoveCommentTemplate(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "Profile foo has no comment template named rebase")
+}
+
+func TestCmdProfileRemoveCommentTemplateUsage(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	err := command.CmdProfileRemoveCommentTemplate(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "Usage: \"prp profile remove-comment-template {templateName}\"")
+}
+
+func TestCmdProfileRemoveCommentTemplateNoConfig(t *testing.T) {
+	err := command.CmdProfileRemoveCommentTemplate(cli.NewContext(nil, flag.NewFlagSet("test", 0), nil))
+	assert.EqualError(t, err, "You must specify a config file")
+}
+
+func TestCompleteProfileRemoveCommentTemplate(t *testing.T) {
+	configData, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	profile := configData.Profiles["foo"]
+	profile.CommentTemplates = map[string]string{"rebase": "Please rebase", "builds": "Please fix the builds"}
+	configData.Profiles["foo"] = profile
+	assert.Nil(t, configData.Write(configFileName))
+	set := getBaseFlagSet(configFileName)
+	os.Args = []string{"profile", "remove-comment-template", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteProfileRemoveCommentTemplate(cli.NewContext(app, set, nil))
+	assert.Equal(t, "builds\nrebase\n", writer.String())
+}
+
+func TestCompleteProfileRemoveCommentTemplateDone(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"rebase"}))
+	os.Args = []string{"profile", "remove-comment-template", "rebase", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteProfileRemoveCommentTemplate(cli.NewContext(app, set, nil))
+	assert.Equal(t, "", writer.String())
+}
@@ -5,31 +5,44 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/go-github/github"
 	"github.com/guywithnose/pull-request-parser/config"
 )
 
 type pullRequest struct {
-	client          *github.Client
-	Repo            *config.Repo
-	PullRequestID   int
-	Title           string
-	Owner           string
-	Branch          string
-	TargetBranch    string
-	HeadLabel       string
-	BaseLabel       string
-	SHA             string
-	BaseSSHURL      string
-	HeadSSHURL      string
-	Approvals       int
-	Rebased         bool
-	NeedsMyApproval bool
-	BuildInfo       map[string]bool
-	Labels          []string
-	IgnoredBuilds   []string
-	Color           string
+	client              *github.Client
+	Repo                *config.Repo
+	PullRequestID       int
+	Title               string
+	Owner               string
+	Branch              string
+	TargetBranch        string
+	HeadLabel           string
+	BaseLabel           string
+	SHA                 string
+	BaseSHA             string
+	BaseSSHURL          string
+	HeadSSHURL          string
+	HeadRepoFullName    string
+	BaseDefaultBranch   string
+	CreatedAt           time.Time
+	Draft               bool
+	MaintainerCanModify bool
+	Approvals           int
+	Rebased             bool
+	NeedsMyApproval     bool
+	BuildInfo           map[string]bool
+	Labels              []string
+	IgnoredBuilds       []string
+	Color               string
+	mine                bool
+	parent              *pullRequest
+	previousBaseSHA     string
+	pushedSHA           string
+	// latestBuildStates holds the state of the newest status of each context
+	latestBuildStates map[string]string
 }
 
 func (pr *pullRequest) getApprovals(user *github.User) {
@@ -149,11 +162,20 @@ func (pr *pullRequest) getStatuses() {
 }
 
 func (pr *pullRequest) parseStatuses(statuses []*github.RepoStatus) {
+	if pr.latestBuildStates == nil {
+		pr.latestBuildStates = make(map[string]string)
+	}
+
 	for _, status := range statuses {
 		if pr.buildIsIgnored(status) {
 			continue
 		}
 
+		// Statuses are listed newest first
+		if _, ok := pr.latestBuildStates[status.GetContext()]; !ok {
+			pr.latestBuildStates[status.GetContext()] = status.GetState()
+		}
+
 		if _, ok := pr.BuildInfo[status.GetContext()]; !ok {
 			pr.BuildInfo[status.GetContext()] = false
 		}
@@ -165,8 +187,12 @@ func (pr *pullRequest) parseStatuses(statuses []*github.RepoStatus) {
 }
 
 func (pr pullRequest) buildIsIgnored(status *github.RepoStatus) bool {
+	return pr.contextIsIgnored(status.GetContext())
+}
+
+func (pr pullRequest) contextIsIgnored(context string) bool {
 	for _, ignoredBuild := range pr.IgnoredBuilds {
-		if ignoredBuild == status.GetContext() {
+		if ignoredBuild == context {
 			return true
 		}
 	}
@@ -1,49 +1,204 @@
 package command
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os/exec"
+	"sort"
 	"strings"
 	"syscall"
 
+	"github.com/google/go-github/github"
+	"github.com/guywithnose/pull-request-parser/config"
 	"github.com/guywithnose/runner"
 	"github.com/urfave/cli"
 )
 
+type rebaseOptions struct {
+	leaveConflicts bool
+	backupRef      bool
+	comment        bool
+	autosquash     bool
+	nativeGit      bool
+	policy         config.RebasePolicy
+}
+
 type rebaser struct {
 	errorWriter   io.Writer
 	verboseWriter io.Writer
 	cmdWrapper    runner.Builder
+	git           gitBackend
+	journal       *journal
+	stacks        *stackRecord
+	options       rebaseOptions
 }
 
-func newRebaser(errorWriter, verboseWriter io.Writer, cmdWrapper runner.Builder) *rebaser {
+func newRebaser(errorWriter, verboseWriter io.Writer, cmdWrapper runner.Builder, journal *journal, stacks *stackRecord, options rebaseOptions) *rebaser {
 	return &rebaser{
 		errorWriter:   errorWriter,
 		verboseWriter: verboseWriter,
 		cmdWrapper:    cmdWrapper,
+		git:           newGitBackend(options.nativeGit, cmdWrapper, verboseWriter),
+		journal:       journal,
+		stacks:        stacks,
+		options:       options,
 	}
 }
 
-func (r rebaser) rebasePullRequests(pullRequests <-chan *pullRequest, pullRequestNumber int) error {
-	var completeError error
+func selectPullRequests(pullRequests <-chan *pullRequest, pullRequestNumber int) []*pullRequest {
+	selectedPullRequests := []*pullRequest{}
 	for pullRequest := range pullRequests {
-		if pullRequestNumber != 0 && pullRequest.PullRequestID != pullRequestNumber {
-			continue
+		if pullRequestNumber == 0 || pullRequest.PullRequestID == pullRequestNumber {
+			selectedPullRequests = append(selectedPullRequests, pullRequest)
+		}
+	}
+
+	return selectedPullRequests
+}
+
+// sortPullRequests orders prs by repo and then by number so they are reported in a stable order
+func sortPullRequests(prs []*pullRequest) []*pullRequest {
+	sort.SliceStable(prs, func(i, j int) bool {
+		first, second := prs[i], prs[j]
+		if first.Repo.Owner != second.Repo.Owner || first.Repo.Name != second.Repo.Name {
+			return fmt.Sprintf("%s/%s", first.Repo.Owner, first.Repo.Name) < fmt.Sprintf("%s/%s", second.Repo.Owner, second.Repo.Name)
+		}
+
+		return first.PullRequestID < second.PullRequestID
+	})
+
+	return prs
+}
+
+// rebaseSummary lists what happened to each pull request during rebaseSelectedPullRequests
+type rebaseSummary struct {
+	rebased  []*pullRequest
+	upToDate []*pullRequest
+	skipped  []*pullRequest
+	failed   []*pullRequest
+}
+
+func (r rebaser) rebaseSelectedPullRequests(selectedPullRequests []*pullRequest) (rebaseSummary, error) {
+	var completeError error
+	summary := rebaseSummary{}
+	moved := make(map[*pullRequest]bool)
+	failed := make(map[*pullRequest]bool)
+	targets := make(goneTargets)
+	orderedPullRequests := orderStacks(sortPullRequests(selectedPullRequests))
+	r.stacks.remember(orderedPullRequests)
+	defer r.saveStacks()
+	for _, pullRequest := range orderedPullRequests {
+		err := r.prepareStackedPullRequest(pullRequest, moved, failed, targets)
+		if err == nil {
+			if pullRequest.Rebased {
+				summary.upToDate = append(summary.upToDate, pullRequest)
+				continue
+			}
+
+			violation := pullRequest.rebasePolicyViolation(r.options.policy.Merge(pullRequest.Repo.RebasePolicy))
+			if violation != "" {
+				fmt.Fprintf(r.verboseWriter, "Skipping PR #%d in %s/%s because %s\n", pullRequest.PullRequestID, pullRequest.Repo.Owner, pullRequest.Repo.Name, violation)
+				summary.skipped = append(summary.skipped, pullRequest)
+				continue
+			}
+
+			err = r.rebasePullRequest(pullRequest)
 		}
 
-		err := r.rebasePullRequest(pullRequest)
 		if err != nil {
+			failed[pullRequest] = true
+			summary.failed = append(summary.failed, pullRequest)
+			r.record(journalEntry{
+				Repo:          fmt.Sprintf("%s/%s", pullRequest.Repo.Owner, pullRequest.Repo.Name),
+				PullRequestID: pullRequest.PullRequestID,
+				Branch:        pullRequest.Branch,
+				OldSHA:        pullRequest.SHA,
+				Outcome:       outcomeFailed,
+				Error:         err.Error(),
+			})
 			fmt.Fprintf(r.errorWriter, "Could not rebase PR #%d in %s/%s because: %v\n", pullRequest.PullRequestID, pullRequest.Repo.Owner, pullRequest.Repo.Name, err)
 			completeError = cli.NewExitError("Unable to rebase all pull requests", 1)
+			continue
 		}
+
+		moved[pullRequest] = true
+		summary.rebased = append(summary.rebased, pullRequest)
+		if r.options.comment && !pullRequest.mine {
+			r.commentOnUpdate(pullRequest)
+		}
+	}
+
+	return summary, completeError
+}
+
+func (r rebaser) prepareStackedPullRequest(pr *pullRequest, moved, failed map[*pullRequest]bool, targets goneTargets) error {
+	if pr.parent != nil {
+		if failed[pr.parent] {
+			return fmt.Errorf("Its parent PR #%d could not be rebased", pr.parent.PullRequestID)
+		}
+
+		if moved[pr.parent] {
+			fmt.Fprintf(r.verboseWriter, "Parent PR #%d was rebased, replaying PR #%d onto it\n", pr.parent.PullRequestID, pr.PullRequestID)
+			pr.previousBaseSHA = pr.parent.SHA
+			pr.Rebased = false
+		}
+
+		return nil
+	}
+
+	target, err := targets.find(pr)
+	if err != nil {
+		return fmt.Errorf("Unable to check whether %s was merged or deleted\n%v", pr.TargetBranch, err)
+	}
+
+	if target.deleted {
+		return fmt.Errorf("Its target branch %s was deleted, run 'prp retarget' to move it onto %s", pr.TargetBranch, pr.BaseDefaultBranch)
+	}
+
+	mergedParent := target.mergedParent
+	if mergedParent == nil {
+		return nil
+	}
+
+	// Only pull requests that were seen stacked follow their parent automatically, 'prp retarget' asks first for the others
+	if !r.stacks.wasStackedOn(pr) {
+		return fmt.Errorf("Its target branch %s was merged in PR #%d, run 'prp retarget' to move it onto %s", pr.TargetBranch, mergedParent.GetNumber(), mergedParent.Base.GetRef())
+	}
+
+	newTargetBranch := mergedParent.Base.GetRef()
+	if usesAPI(pr) {
+		return fmt.Errorf("Its parent PR #%d was merged, moving only its own commits onto %s needs a local path", mergedParent.GetNumber(), newTargetBranch)
+	}
+
+	fmt.Fprintf(r.verboseWriter, "Parent PR #%d was merged, retargeting PR #%d to %s\n", mergedParent.GetNumber(), pr.PullRequestID, newTargetBranch)
+	err = pr.retarget(newTargetBranch, mergedParent.Head.GetSHA())
+	if err != nil {
+		return fmt.Errorf("Unable to retarget to %s\n%v", newTargetBranch, err)
 	}
 
-	return completeError
+	r.stacks.forget(pr)
+	return nil
+}
+
+func (r rebaser) saveStacks() {
+	err := r.stacks.save()
+	if err != nil {
+		fmt.Fprintf(r.errorWriter, "Warning: Could not save stacked pull requests to %s: %v\n", r.stacks.path, err)
+	}
+}
+
+// usesAPI returns whether pr is updated through the API instead of in a local clone
+func usesAPI(pr *pullRequest) bool {
+	return pr.Repo.UpdateStrategy == config.UpdateStrategyAPI || pr.Repo.LocalPath == ""
 }
 
 func (r rebaser) rebasePullRequest(pr *pullRequest) error {
+	if usesAPI(pr) {
+		return r.updateThroughAPI(pr)
+	}
+
 	path, ownedRemote, upstreamRemote, localChanges, err := r.getRepoData(pr)
 	if err != nil {
 		return err
@@ -69,6 +224,45 @@ func (r rebaser) rebasePullRequest(pr *pullRequest) error {
 	return r.doRebase(path, ownedRemote, upstreamRemote, tempBranch, pr)
 }
 
+func (r rebaser) commentOnUpdate(pr *pullRequest) {
+	body := fmt.Sprintf("Updated this branch with the latest changes from %s using prp auto-rebase.", pr.TargetBranch)
+	_, _, err := pr.client.Issues.CreateComment(context.Background(), pr.Repo.Owner, pr.Repo.Name, pr.PullRequestID, &github.IssueComment{Body: &body})
+	if err != nil {
+		fmt.Fprintf(r.errorWriter, "Warning: Could not comment on PR #%d in %s/%s: %v\n", pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, err)
+	}
+}
+
+func (r rebaser) updateThroughAPI(pr *pullRequest) error {
+	// The API would replay the commits pr was based on too
+	if pr.previousBaseSHA != "" {
+		return fmt.Errorf("Only its commits after %s should be moved onto %s, which needs a local path", pr.previousBaseSHA, pr.TargetBranch)
+	}
+
+	if pr.Repo.UpdateStrategy == "" || pr.Repo.UpdateStrategy == config.UpdateStrategyRebase {
+		fmt.Fprintln(r.verboseWriter, "Requesting a branch rebase from the API")
+		err := rebasePullRequestBranch(pr.client, pr.Repo.Owner, pr.Repo.Name, pr.PullRequestID, pr.SHA)
+		if err != nil {
+			return fmt.Errorf("Unable to rebase branch through the API\n%v", err)
+		}
+	} else {
+		fmt.Fprintln(r.verboseWriter, "Requesting a branch update from the API")
+		err := updatePullRequestBranch(pr.client, pr.Repo.Owner, pr.Repo.Name, pr.PullRequestID, pr.SHA)
+		if err != nil {
+			return fmt.Errorf("Unable to update branch through the API\n%v", err)
+		}
+	}
+
+	// The new head isn't known until Github finishes the update
+	r.record(journalEntry{
+		Repo:          fmt.Sprintf("%s/%s", pr.Repo.Owner, pr.Repo.Name),
+		PullRequestID: pr.PullRequestID,
+		Branch:        pr.Branch,
+		OldSHA:        pr.SHA,
+		Outcome:       outcomeUpdated,
+	})
+	return nil
+}
+
 func (r rebaser) doRebase(path, ownedRemote, upstreamRemote, tempBranch string, pr *pullRequest) error {
 	myRemoteBranch := fmt.Sprintf("%s/%s", ownedRemote, pr.Branch)
 	fmt.Fprintf(r.verboseWriter, "Resetting code to %s\n", myRemoteBranch)
@@ -78,26 +272,192 @@ func (r rebaser) doRebase(path, ownedRemote, upstreamRemote, tempBranch string,
 	}
 
 	upstreamBranch := fmt.Sprintf("%s/%s", upstreamRemote, pr.TargetBranch)
-	fmt.Fprintf(r.verboseWriter, "Rebasing against %s\n", upstreamBranch)
-	err = r.runCommand(path, "git", "rebase", upstreamBranch)
+	pushCommand := []string{"git", "push", ownedRemote, fmt.Sprintf("%s:%s", tempBranch, pr.Branch)}
+	if pr.Repo.UpdateStrategy == config.UpdateStrategyMerge {
+		err = r.merge(path, ownedRemote, upstreamBranch, pr)
+	} else {
+		err = r.rebase(path, ownedRemote, upstreamBranch, pr)
+	}
+
 	if err != nil {
-		abortErr := r.runCommand(path, "git", "rebase", "--abort")
-		if abortErr != nil {
-			fmt.Fprintf(r.errorWriter, "Could not abort rebase PR #%d in %s/%s because: %v\n", pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, abortErr)
+		return err
+	}
+
+	oldSHA, newSHA, err := r.getHeads(path, myRemoteBranch)
+	if err != nil {
+		return err
+	}
+
+	// Commits pushed to the branch since it was fetched must not be overwritten
+	if pr.Repo.UpdateStrategy != config.UpdateStrategyMerge {
+		pushCommand = append(pushCommand, fmt.Sprintf("--force-with-lease=%s:%s", pr.Branch, oldSHA))
+	}
+
+	if r.options.backupRef {
+		err = r.writeBackupRef(path, pr.Branch, oldSHA)
+		if err != nil {
+			return err
 		}
+	}
 
-		return wrapExitError(err, fmt.Sprintf("Unable to rebase against %s, there may be a conflict", upstreamBranch))
+	if len(pr.Repo.PrePushHooks) != 0 {
+		err = r.runHooks(pr.Repo.PrePushHooks, path, pr, oldSHA, newSHA)
+		if err != nil {
+			return err
+		}
+
+		// Changes the hooks didn't commit would be lost with the temp branch
+		localChanges, err := r.detectLocalChanges(path)
+		if err != nil {
+			return wrapExitError(err, fmt.Sprintf("Unable to detect local changes in %s", path))
+		}
+
+		if localChanges {
+			return fmt.Errorf("Pre-push hooks left uncommitted changes in %s, hooks must commit the changes they make", path)
+		}
+
+		// The hooks may have committed generated files
+		_, newSHA, err = r.getHeads(path, myRemoteBranch)
+		if err != nil {
+			return err
+		}
+	}
+
+	if pr.Repo.SignCommits {
+		err = r.verifySignatures(path, fmt.Sprintf("%s..HEAD", upstreamBranch))
+		if err != nil {
+			return err
+		}
 	}
 
 	fmt.Fprintf(r.verboseWriter, "Pushing to %s\n", myRemoteBranch)
-	err = r.runCommand(path, "git", "push", ownedRemote, fmt.Sprintf("%s:%s", tempBranch, pr.Branch), "--force")
+	err = r.runCommand(path, pushCommand...)
 	if err != nil {
 		return wrapExitError(err, fmt.Sprintf("Unable to push to %s", myRemoteBranch))
 	}
 
+	pr.pushedSHA = newSHA
+	r.record(journalEntry{
+		Repo:          fmt.Sprintf("%s/%s", pr.Repo.Owner, pr.Repo.Name),
+		PullRequestID: pr.PullRequestID,
+		Branch:        pr.Branch,
+		Path:          path,
+		Remote:        ownedRemote,
+		OldSHA:        oldSHA,
+		NewSHA:        newSHA,
+		Outcome:       outcomePushed,
+	})
+
+	err = r.runHooks(pr.Repo.PostPushHooks, path, pr, oldSHA, newSHA)
+	if err != nil {
+		fmt.Fprintf(r.errorWriter, "%v\nWarning: Post-push hooks failed for PR #%d in %s/%s\n", err, pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name)
+	}
+
+	return nil
+}
+
+func (r rebaser) getHeads(path, myRemoteBranch string) (string, string, error) {
+	heads, err := r.cmdWrapper.New(path, "git", "rev-parse", myRemoteBranch, "HEAD").Output()
+	if err != nil {
+		return "", "", wrapExitError(err, fmt.Sprintf("Unable to resolve the heads of %s and HEAD", myRemoteBranch))
+	}
+
+	shas := strings.Fields(string(heads))
+	if len(shas) != 2 {
+		return "", "", fmt.Errorf("Unable to resolve the heads of %s and HEAD", myRemoteBranch)
+	}
+
+	return shas[0], shas[1], nil
+}
+
+func (r rebaser) writeBackupRef(path, branch, sha string) error {
+	backupRef := fmt.Sprintf("refs/prp/backup/%s", branch)
+	fmt.Fprintf(r.verboseWriter, "Saving %s as %s\n", sha, backupRef)
+	err := r.runCommand(path, "git", "update-ref", backupRef, sha)
+	if err != nil {
+		return wrapExitError(err, fmt.Sprintf("Unable to save backup ref %s", backupRef))
+	}
+
 	return nil
 }
 
+func (r rebaser) record(entry journalEntry) {
+	if r.journal == nil {
+		return
+	}
+
+	err := r.journal.record(entry)
+	if err != nil {
+		fmt.Fprintf(r.errorWriter, "Warning: Could not record PR #%d in journal %s: %v\n", entry.PullRequestID, r.journal.path, err)
+	}
+}
+
+func (r rebaser) rebase(path, ownedRemote, upstreamBranch string, pr *pullRequest) error {
+	rebaseCommand := []string{"git", "rebase"}
+	if pr.Repo.SignCommits {
+		rebaseCommand = append(rebaseCommand, "--gpg-sign")
+	}
+
+	if pr.previousBaseSHA != "" {
+		fmt.Fprintf(r.verboseWriter, "Rebasing commits after %s onto %s\n", pr.previousBaseSHA, upstreamBranch)
+		rebaseCommand = append(rebaseCommand, "--onto", upstreamBranch, pr.previousBaseSHA)
+	} else {
+		fmt.Fprintf(r.verboseWriter, "Rebasing against %s\n", upstreamBranch)
+		rebaseCommand = append(rebaseCommand, upstreamBranch)
+	}
+
+	err := r.runCommand(path, rebaseCommand...)
+	if err != nil {
+		report := r.conflictReport(path)
+		r.abort(path, "rebase", pr)
+		rebaseErr := wrapExitError(err, fmt.Sprintf("Unable to rebase against %s, there may be a conflict", upstreamBranch))
+		if report != "" {
+			rebaseErr = fmt.Errorf("%v\n%s", rebaseErr, report)
+		}
+
+		if r.options.leaveConflicts {
+			rebaseErr = fmt.Errorf("%v\n%v", rebaseErr, r.leaveConflicts(path, ownedRemote, upstreamBranch, rebaseCommand, pr))
+		}
+
+		return rebaseErr
+	}
+
+	if r.options.autosquash || pr.Repo.Autosquash {
+		return r.autosquash(path, ownedRemote, rebaseCommand, pr)
+	}
+
+	return nil
+}
+
+func (r rebaser) merge(path, ownedRemote, upstreamBranch string, pr *pullRequest) error {
+	fmt.Fprintf(r.verboseWriter, "Merging %s\n", upstreamBranch)
+	mergeCommand := []string{"git", "merge", "--no-edit"}
+	if pr.Repo.SignCommits {
+		mergeCommand = append(mergeCommand, "--gpg-sign")
+	}
+
+	mergeCommand = append(mergeCommand, upstreamBranch)
+	err := r.runCommand(path, mergeCommand...)
+	if err != nil {
+		r.abort(path, "merge", pr)
+		mergeErr := wrapExitError(err, fmt.Sprintf("Unable to merge %s, there may be a conflict", upstreamBranch))
+		if r.options.leaveConflicts {
+			mergeErr = fmt.Errorf("%v\n%v", mergeErr, r.leaveConflicts(path, ownedRemote, upstreamBranch, mergeCommand, pr))
+		}
+
+		return mergeErr
+	}
+
+	return nil
+}
+
+func (r rebaser) abort(path, operation string, pr *pullRequest) {
+	abortErr := r.runCommand(path, "git", operation, "--abort")
+	if abortErr != nil {
+		fmt.Fprintf(r.errorWriter, "Could not abort %s PR #%d in %s/%s because: %v\n", operation, pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, abortErr)
+	}
+}
+
 func (r rebaser) detectLocalChanges(path string) (bool, error) {
 	localChangesCommand := r.cmdWrapper.New(path, "git", "diff-index", "--quiet", "HEAD")
 	_, err := localChangesCommand.Output()
@@ -179,7 +539,7 @@ func wrapExitError(err error, extra string) error {
 
 func (r rebaser) checkoutTempBranch(path, branch string) (string, string, error) {
 	fmt.Fprintln(r.verboseWriter, "Saving current branch name")
-	currentBranchName, err := r.getCurrentBranch(path)
+	currentBranchName, err := r.git.currentBranch(path)
 	if err != nil {
 		return "", "", fmt.Errorf("Unable to get current branch name in %s\n%v", path, err)
 	}
@@ -201,27 +561,6 @@ func (r rebaser) checkoutTempBranch(path, branch string) (string, string, error)
 	return currentBranchName, tempBranch, nil
 }
 
-func (r rebaser) getCurrentBranch(path string) (string, error) {
-	getCurrentBranch := r.cmdWrapper.New(path, "git", "symbolic-ref", "HEAD")
-	currentBranchOutput, err := getCurrentBranch.CombinedOutput()
-	if err != nil {
-		code := getErrorCode(err)
-		if code == 128 {
-			getCurrentBranch = r.cmdWrapper.New(path, "git", "rev-parse", "HEAD")
-			currentBranchOutput, err = getCurrentBranch.CombinedOutput()
-			if err != nil {
-				return "", fmt.Errorf("No branch checked out in %s\n%s", path, string(currentBranchOutput))
-			}
-
-			return strings.Replace(strings.Replace(string(currentBranchOutput), "refs/heads/", "", -1), "\n", "", -1), nil
-		}
-
-		return "", fmt.Errorf("%s\n%s", err, string(currentBranchOutput))
-	}
-
-	return strings.Replace(strings.Replace(string(currentBranchOutput), "refs/heads/", "", -1), "\n", "", -1), nil
-}
-
 func (r rebaser) fetchRemotes(path, ownedRemote, upstreamRemote string) error {
 	err := r.fetchRemote(path, ownedRemote)
 	if err != nil {
@@ -242,19 +581,23 @@ func (r rebaser) fetchRemote(path, remoteName string) error {
 }
 
 func (r rebaser) getRemotes(path string, pr *pullRequest) (string, string, error) {
-	getRemotes := r.cmdWrapper.New(path, "git", "remote", "-v")
-	remotesOutput, err := getRemotes.CombinedOutput()
+	remotes, err := r.git.remotes(path)
 	if err != nil {
-		return "", "", fmt.Errorf("Unable to analyze remotes in %s\n%s", path, string(remotesOutput))
+		return "", "", err
 	}
 
-	remotes := parseRemotes(strings.Split(string(remotesOutput), "\n"))
-
 	ownedRemote, ok := remotes[fmt.Sprintf("%s (push)", pr.HeadSSHURL)]
-	if !ok {
+	if !ok && pr.mine {
 		return "", "", fmt.Errorf("No remote exists in %s that points to %s", path, pr.HeadSSHURL)
 	}
 
+	if !ok {
+		ownedRemote, err = r.addContributorRemote(path, pr, remotes)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
 	upstreamRemote, ok := remotes[fmt.Sprintf("%s (fetch)", pr.BaseSSHURL)]
 	if !ok {
 		return "", "", fmt.Errorf("No remote exists in %s that points to %s", path, pr.BaseSSHURL)
@@ -263,16 +606,30 @@ func (r rebaser) getRemotes(path string, pr *pullRequest) (string, string, error
 	return ownedRemote, upstreamRemote, nil
 }
 
-func parseRemotes(lines []string) map[string]string {
-	remotes := make(map[string]string)
-	for _, line := range lines {
-		parts := strings.Split(line, "\t")
-		if len(parts) == 2 {
-			remotes[parts[1]] = parts[0]
-		}
+// addContributorRemote adds a remote named prp-{owner} for the fork of pr, or prp-{owner}-{n} when that name is taken by another fork
+// remotes are the existing remotes from r.git.remotes
+func (r rebaser) addContributorRemote(path string, pr *pullRequest, remotes map[string]string) (string, error) {
+	names := make(map[string]bool, len(remotes))
+	for _, name := range remotes {
+		names[name] = true
+	}
+
+	remoteName := fmt.Sprintf("prp-%s", pr.Owner)
+	if remotes[fmt.Sprintf("%s (fetch)", pr.HeadSSHURL)] == remoteName {
+		return remoteName, nil
+	}
+
+	for i := 2; names[remoteName]; i++ {
+		remoteName = fmt.Sprintf("prp-%s-%d", pr.Owner, i)
+	}
+
+	fmt.Fprintf(r.verboseWriter, "Adding remote %s for %s\n", remoteName, pr.HeadSSHURL)
+	err := r.runCommand(path, "git", "remote", "add", remoteName, pr.HeadSSHURL)
+	if err != nil {
+		return "", wrapExitError(err, fmt.Sprintf("Unable to add remote %s for %s", remoteName, pr.HeadSSHURL))
 	}
 
-	return remotes
+	return remoteName, nil
 }
 
 func getErrorCode(err error) int {
@@ -0,0 +1,75 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/urfave/cli"
+)
+
+// CmdRepoAddHook adds a command that auto-rebase runs before or after pushing a repo's pull requests
+func CmdRepoAddHook(c *cli.Context) error {
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() != 3 {
+		return cli.NewExitError("Usage: \"prp profile repo add-hook {repoName} {hookType} {command}\"", 1)
+	}
+
+	repoName := c.Args().Get(0)
+	hookType := c.Args().Get(1)
+	hook := c.Args().Get(2)
+
+	profile := configData.Profiles[*profileName]
+	repo, repoIndex, err := loadRepo(&profile, repoName)
+	if err != nil {
+		return err
+	}
+
+	hooks, err := loadHooks(repo, hookType)
+	if err != nil {
+		return err
+	}
+
+	if stringSliceContains(hook, *hooks) {
+		return cli.NewExitError(fmt.Sprintf("%s already has the %s hook %s", repoName, hookType, hook), 1)
+	}
+
+	*hooks = append(*hooks, hook)
+	profile.TrackedRepos[repoIndex] = *repo
+	configData.Profiles[*profileName] = profile
+
+	return configData.Write(c.GlobalString("config"))
+}
+
+func loadHooks(repo *config.Repo, hookType string) (*[]string, error) {
+	hooks := repo.Hooks(hookType)
+	if hooks == nil {
+		return nil, cli.NewExitError(fmt.Sprintf("Invalid hook type: %s (must be one of %s)", hookType, strings.Join(config.HookTypes, ", ")), 1)
+	}
+
+	return hooks, nil
+}
+
+// CompleteRepoAddHook handles bash autocompletion for the 'profile repo add-hook' command
+func CompleteRepoAddHook(c *cli.Context) {
+	if c.NArg() >= 2 {
+		return
+	}
+
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return
+	}
+
+	profile := configData.Profiles[*profileName]
+
+	if c.NArg() == 0 {
+		fmt.Fprintln(c.App.Writer, strings.Join(sortRepoNames(&profile), "\n"))
+	} else {
+		fmt.Fprintln(c.App.Writer, strings.Join(config.HookTypes, "\n"))
+	}
+}
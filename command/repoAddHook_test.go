@@ -0,0 +1,97 @@
+package command_test
+
+import (
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/guywithnose/pull-request-parser/command"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+func TestCmdRepoAddHook(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep", "pre-push", "make lock"}))
+	assert.Nil(t, command.CmdRepoAddHook(cli.NewContext(nil, set, nil)))
+
+	expectedConfigFile, disposableConfigFile := getConfigWithTwoRepos(t)
+	removeFile(t, disposableConfigFile)
+	profile := expectedConfigFile.Profiles["foo"]
+	profile.TrackedRepos[1].PrePushHooks = []string{"make lock"}
+	expectedConfigFile.Profiles["foo"] = profile
+	assertConfigFile(t, expectedConfigFile, configFileName)
+}
+
+func TestCmdRepoAddHookAlreadyExists(t *testing.T) {
+	configData, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	configData.Profiles["foo"].TrackedRepos[1].PostPushHooks = []string{"notify"}
+	assert.Nil(t, configData.Write(configFileName))
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep", "post-push", "notify"}))
+	err := command.CmdRepoAddHook(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "own/rep already has the post-push hook notify")
+}
+
+func TestCmdRepoAddHookInvalidType(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep", "pre-commit", "make lock"}))
+	err := command.CmdRepoAddHook(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "Invalid hook type: pre-commit (must be one of pre-push, post-push)")
+}
+
+func TestCmdRepoAddHookNoConfig(t *testing.T) {
+	err := command.CmdRepoAddHook(cli.NewContext(nil, flag.NewFlagSet("test", 0), nil))
+	assert.EqualError(t, err, "You must specify a config file")
+}
+
+func TestCmdRepoAddHookInvalidRepo(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep", "pre-push", "make lock"}))
+	err := command.CmdRepoAddHook(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "Not a valid Repo: own/rep")
+}
+
+func TestCmdRepoAddHookUsage(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	err := command.CmdRepoAddHook(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "Usage: \"prp profile repo add-hook {repoName} {hookType} {command}\"")
+}
+
+func TestCompleteRepoAddHookRepos(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	os.Args = []string{"repo", "add-hook", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteRepoAddHook(cli.NewContext(app, set, nil))
+	assert.Equal(t, "foo/bar\nown/rep\n", writer.String())
+}
+
+func TestCompleteRepoAddHookTypes(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep"}))
+	os.Args = []string{"repo", "add-hook", "own/rep", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteRepoAddHook(cli.NewContext(app, set, nil))
+	assert.Equal(t, "pre-push\npost-push\n", writer.String())
+}
+
+func TestCompleteRepoAddHookNoConfig(t *testing.T) {
+	set := flag.NewFlagSet("test", 0)
+	os.Args = []string{"repo", "add-hook", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteRepoAddHook(cli.NewContext(app, set, nil))
+	assert.Equal(t, "", writer.String())
+}
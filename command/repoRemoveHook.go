@@ -0,0 +1,93 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/urfave/cli"
+)
+
+// CmdRepoRemoveHook removes a hook command from a repo
+func CmdRepoRemoveHook(c *cli.Context) error {
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() != 3 {
+		return cli.NewExitError("Usage: \"prp profile repo remove-hook {repoName} {hookType} {command}\"", 1)
+	}
+
+	repoName := c.Args().Get(0)
+	hookType := c.Args().Get(1)
+	hook := c.Args().Get(2)
+
+	profile := configData.Profiles[*profileName]
+	repo, repoIndex, err := loadRepo(&profile, repoName)
+	if err != nil {
+		return err
+	}
+
+	hooks, err := loadHooks(repo, hookType)
+	if err != nil {
+		return err
+	}
+
+	remainingHooks := []string{}
+	for _, existingHook := range *hooks {
+		if existingHook != hook {
+			remainingHooks = append(remainingHooks, existingHook)
+		}
+	}
+
+	if len(remainingHooks) == len(*hooks) {
+		return cli.NewExitError(fmt.Sprintf("%s does not have the %s hook %s", repoName, hookType, hook), 1)
+	}
+
+	*hooks = remainingHooks
+	if len(remainingHooks) == 0 {
+		*hooks = nil
+	}
+
+	profile.TrackedRepos[repoIndex] = *repo
+	configData.Profiles[*profileName] = profile
+
+	return configData.Write(c.GlobalString("config"))
+}
+
+// CompleteRepoRemoveHook handles bash autocompletion for the 'profile repo remove-hook' command
+func CompleteRepoRemoveHook(c *cli.Context) {
+	if c.NArg() >= 3 {
+		return
+	}
+
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return
+	}
+
+	profile := configData.Profiles[*profileName]
+
+	switch c.NArg() {
+	case 0:
+		fmt.Fprintln(c.App.Writer, strings.Join(sortRepoNames(&profile), "\n"))
+	case 1:
+		fmt.Fprintln(c.App.Writer, strings.Join(config.HookTypes, "\n"))
+	default:
+		repo, _, err := loadRepo(&profile, c.Args().Get(0))
+		if err != nil {
+			return
+		}
+
+		hooks := repo.Hooks(c.Args().Get(1))
+		if hooks == nil {
+			return
+		}
+
+		hookCommands := append([]string{}, *hooks...)
+		sort.Strings(hookCommands)
+		fmt.Fprintln(c.App.Writer, strings.Join(hookCommands, "\n"))
+	}
+}
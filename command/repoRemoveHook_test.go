@@ -0,0 +1,105 @@
+package command_test
+
+import (
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/guywithnose/pull-request-parser/command"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+func TestCmdRepoRemoveHook(t *testing.T) {
+	configData, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	configData.Profiles["foo"].TrackedRepos[1].PrePushHooks = []string{"make lock", "go build"}
+	assert.Nil(t, configData.Write(configFileName))
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep", "pre-push", "make lock"}))
+	assert.Nil(t, command.CmdRepoRemoveHook(cli.NewContext(nil, set, nil)))
+
+	expectedConfigFile, disposableConfigFile := getConfigWithTwoRepos(t)
+	removeFile(t, disposableConfigFile)
+	profile := expectedConfigFile.Profiles["foo"]
+	profile.TrackedRepos[1].PrePushHooks = []string{"go build"}
+	expectedConfigFile.Profiles["foo"] = profile
+	assertConfigFile(t, expectedConfigFile, configFileName)
+}
+
+func TestCmdRepoRemoveHookLast(t *testing.T) {
+	configData, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	configData.Profiles["foo"].TrackedRepos[1].PostPushHooks = []string{"notify"}
+	assert.Nil(t, configData.Write(configFileName))
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep", "post-push", "notify"}))
+	assert.Nil(t, command.CmdRepoRemoveHook(cli.NewContext(nil, set, nil)))
+
+	expectedConfigFile, disposableConfigFile := getConfigWithTwoRepos(t)
+	removeFile(t, disposableConfigFile)
+	assertConfigFile(t, expectedConfigFile, configFileName)
+}
+
+func TestCmdRepoRemoveHookNotFound(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep", "post-push", "notify"}))
+	err := command.CmdRepoRemoveHook(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "own/rep does not have the post-push hook notify")
+}
+
+func TestCmdRepoRemoveHookInvalidType(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep", "pre-commit", "notify"}))
+	err := command.CmdRepoRemoveHook(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "Invalid hook type: pre-commit (must be one of pre-push, post-push)")
+}
+
+func TestCmdRepoRemoveHookNoConfig(t *testing.T) {
+	err := command.CmdRepoRemoveHook(cli.NewContext(nil, flag.NewFlagSet("test", 0), nil))
+	assert.EqualError(t, err, "You must specify a config file")
+}
+
+func TestCmdRepoRemoveHookUsage(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	err := command.CmdRepoRemoveHook(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "Usage: \"prp profile repo remove-hook {repoName} {hookType} {command}\"")
+}
+
+func TestCompleteRepoRemoveHookCommands(t *testing.T) {
+	configData, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	configData.Profiles["foo"].TrackedRepos[1].PrePushHooks = []string{"make lock", "go build"}
+	assert.Nil(t, configData.Write(configFileName))
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep", "pre-push"}))
+	os.Args = []string{"repo", "remove-hook", "own/rep", "pre-push", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteRepoRemoveHook(cli.NewContext(app, set, nil))
+	assert.Equal(t, "go build\nmake lock\n", writer.String())
+}
+
+func TestCompleteRepoRemoveHookTypes(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep"}))
+	os.Args = []string{"repo", "remove-hook", "own/rep", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteRepoRemoveHook(cli.NewContext(app, set, nil))
+	assert.Equal(t, "pre-push\npost-push\n", writer.String())
+}
+
+func TestCompleteRepoRemoveHookNoConfig(t *testing.T) {
+	set := flag.NewFlagSet("test", 0)
+	os.Args = []string{"repo", "remove-hook", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteRepoRemoveHook(cli.NewContext(app, set, nil))
+	assert.Equal(t, "", writer.String())
+}
@@ -0,0 +1,58 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// CmdRepoSetAutosquash sets whether auto-rebase squashes fixup commits in a repo's pull requests
+func CmdRepoSetAutosquash(c *cli.Context) error {
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() != 2 {
+		return cli.NewExitError("Usage: \"prp profile repo set-autosquash {repoName} {true|false}\"", 1)
+	}
+
+	profile := configData.Profiles[*profileName]
+	repo, repoIndex, err := loadRepo(&profile, c.Args().Get(0))
+	if err != nil {
+		return err
+	}
+
+	autosquash, err := strconv.ParseBool(c.Args().Get(1))
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Invalid value: %s (must be true or false)", c.Args().Get(1)), 1)
+	}
+
+	repo.Autosquash = autosquash
+	profile.TrackedRepos[repoIndex] = *repo
+	configData.Profiles[*profileName] = profile
+
+	return configData.Write(c.GlobalString("config"))
+}
+
+// CompleteRepoSetAutosquash handles bash autocompletion for the 'profile repo set-autosquash' command
+func CompleteRepoSetAutosquash(c *cli.Context) {
+	if c.NArg() >= 2 {
+		return
+	}
+
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return
+	}
+
+	profile := configData.Profiles[*profileName]
+
+	if c.NArg() == 0 {
+		fmt.Fprintln(c.App.Writer, strings.Join(sortRepoNames(&profile), "\n"))
+	} else {
+		fmt.Fprintln(c.App.Writer, "true\nfalse")
+	}
+}
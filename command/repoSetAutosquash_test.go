@@ -0,0 +1,87 @@
+package command_test
+
+import (
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/guywithnose/pull-request-parser/command"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+func TestCmdRepoSetAutosquash(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep", "true"}))
+	assert.Nil(t, command.CmdRepoSetAutosquash(cli.NewContext(nil, set, nil)))
+
+	expectedConfigFile, disposableConfigFile := getConfigWithTwoRepos(t)
+	removeFile(t, disposableConfigFile)
+	profile := expectedConfigFile.Profiles["foo"]
+	profile.TrackedRepos[1].Autosquash = true
+	expectedConfigFile.Profiles["foo"] = profile
+	assertConfigFile(t, expectedConfigFile, configFileName)
+}
+
+func TestCmdRepoSetAutosquashInvalidValue(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep", "sometimes"}))
+	err := command.CmdRepoSetAutosquash(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "Invalid value: sometimes (must be true or false)")
+}
+
+func TestCmdRepoSetAutosquashNoConfig(t *testing.T) {
+	err := command.CmdRepoSetAutosquash(cli.NewContext(nil, flag.NewFlagSet("test", 0), nil))
+	assert.EqualError(t, err, "You must specify a config file")
+}
+
+func TestCmdRepoSetAutosquashInvalidRepo(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep", "true"}))
+
+	err := command.CmdRepoSetAutosquash(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "Not a valid Repo: own/rep")
+}
+
+func TestCmdRepoSetAutosquashUsage(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	err := command.CmdRepoSetAutosquash(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "Usage: \"prp profile repo set-autosquash {repoName} {true|false}\"")
+}
+
+func TestCompleteRepoSetAutosquashRepos(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	os.Args = []string{"repo", "set-autosquash", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteRepoSetAutosquash(cli.NewContext(app, set, nil))
+	assert.Equal(t, "foo/bar\nown/rep\n", writer.String())
+}
+
+func TestCompleteRepoSetAutosquashValues(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep"}))
+	os.Args = []string{"repo", "set-autosquash", "own/rep", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteRepoSetAutosquash(cli.NewContext(app, set, nil))
+	assert.Equal(t, "true\nfalse\n", writer.String())
+}
+
+func TestCompleteRepoSetAutosquashNoConfig(t *testing.T) {
+	set := flag.NewFlagSet("test", 0)
+	os.Args = []string{"repo", "set-autosquash", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteRepoSetAutosquash(cli.NewContext(app, set, nil))
+	assert.Equal(t, "", writer.String())
+}
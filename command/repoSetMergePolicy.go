@@ -0,0 +1,56 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// CmdRepoSetMergePolicy sets the conditions a repo's pull requests must meet before merge merges them
+func CmdRepoSetMergePolicy(c *cli.Context) error {
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() != 1 {
+		return cli.NewExitError("Usage: \"prp profile repo set-merge-policy {repoName}\"", 1)
+	}
+
+	profile := configData.Profiles[*profileName]
+	repo, repoIndex, err := loadRepo(&profile, c.Args().Get(0))
+	if err != nil {
+		return err
+	}
+
+	policy, err := mergePolicyFromFlags(c)
+	if err != nil {
+		return err
+	}
+
+	repo.MergePolicy = &policy
+	if policy.MinApprovals == 0 && len(policy.RequiredLabels) == 0 && policy.Method == "" && !policy.DeleteBranch {
+		repo.MergePolicy = nil
+	}
+
+	profile.TrackedRepos[repoIndex] = *repo
+	configData.Profiles[*profileName] = profile
+
+	return configData.Write(c.GlobalString("config"))
+}
+
+// CompleteRepoSetMergePolicy handles bash autocompletion for the 'profile repo set-merge-policy' command
+func CompleteRepoSetMergePolicy(c *cli.Context) {
+	if c.NArg() >= 1 {
+		return
+	}
+
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return
+	}
+
+	profile := configData.Profiles[*profileName]
+	fmt.Fprintln(c.App.Writer, strings.Join(sortRepoNames(&profile), "\n"))
+}
@@ -0,0 +1,108 @@
+package command_test
+
+import (
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/guywithnose/pull-request-parser/command"
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+func TestCmdRepoSetMergePolicy(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	set.Int("min-approvals", 2, "doc")
+	labels := cli.StringSlice{"ready"}
+	set.Var(&labels, "require-label", "doc")
+	set.String("method", "squash", "doc")
+	set.Bool("delete-branch", true, "doc")
+	assert.Nil(t, set.Parse([]string{"own/rep"}))
+	assert.Nil(t, command.CmdRepoSetMergePolicy(cli.NewContext(nil, set, nil)))
+
+	expectedConfigFile, disposableConfigFile := getConfigWithTwoRepos(t)
+	removeFile(t, disposableConfigFile)
+	profile := expectedConfigFile.Profiles["foo"]
+	profile.TrackedRepos[1].MergePolicy = &config.MergePolicy{MinApprovals: 2, RequiredLabels: []string{"ready"}, Method: "squash", DeleteBranch: true}
+	expectedConfigFile.Profiles["foo"] = profile
+	assertConfigFile(t, expectedConfigFile, configFileName)
+}
+
+func TestCmdRepoSetMergePolicyClear(t *testing.T) {
+	configData, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	configData.Profiles["foo"].TrackedRepos[1].MergePolicy = &config.MergePolicy{DeleteBranch: true}
+	assert.Nil(t, configData.Write(configFileName))
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep"}))
+	assert.Nil(t, command.CmdRepoSetMergePolicy(cli.NewContext(nil, set, nil)))
+
+	expectedConfigFile, disposableConfigFile := getConfigWithTwoRepos(t)
+	removeFile(t, disposableConfigFile)
+	assertConfigFile(t, expectedConfigFile, configFileName)
+}
+
+func TestCmdRepoSetMergePolicyInvalidMethod(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	set.String("method", "octopus", "doc")
+	assert.Nil(t, set.Parse([]string{"own/rep"}))
+	err := command.CmdRepoSetMergePolicy(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "Invalid merge method: octopus (must be one of merge, squash, rebase)")
+}
+
+func TestCmdRepoSetMergePolicyNoConfig(t *testing.T) {
+	err := command.CmdRepoSetMergePolicy(cli.NewContext(nil, flag.NewFlagSet("test", 0), nil))
+	assert.EqualError(t, err, "You must specify a config file")
+}
+
+func TestCmdRepoSetMergePolicyInvalidRepo(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep"}))
+
+	err := command.CmdRepoSetMergePolicy(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "Not a valid Repo: own/rep")
+}
+
+func TestCmdRepoSetMergePolicyUsage(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	err := command.CmdRepoSetMergePolicy(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "Usage: \"prp profile repo set-merge-policy {repoName}\"")
+}
+
+func TestCompleteRepoSetMergePolicyRepos(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	os.Args = []string{"repo", "set-merge-policy", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteRepoSetMergePolicy(cli.NewContext(app, set, nil))
+	assert.Equal(t, "foo/bar\nown/rep\n", writer.String())
+}
+
+func TestCompleteRepoSetMergePolicyDone(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep"}))
+	os.Args = []string{"repo", "set-merge-policy", "own/rep", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteRepoSetMergePolicy(cli.NewContext(app, set, nil))
+	assert.Equal(t, "", writer.String())
+}
+
+func TestCompleteRepoSetMergePolicyNoConfig(t *testing.T) {
+	set := flag.NewFlagSet("test", 0)
+	os.Args = []string{"repo", "set-merge-policy", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteRepoSetMergePolicy(cli.NewContext(app, set, nil))
+	assert.Equal(t, "", writer.String())
+}
@@ -0,0 +1,56 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/urfave/cli"
+)
+
+// CmdRepoSetPullRequestDefaults sets the template, reviewers and labels open uses for a repo's new pull requests
+func CmdRepoSetPullRequestDefaults(c *cli.Context) error {
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() != 1 {
+		return cli.NewExitError("Usage: \"prp profile repo set-pull-request-defaults {repoName}\"", 1)
+	}
+
+	profile := configData.Profiles[*profileName]
+	repo, repoIndex, err := loadRepo(&profile, c.Args().Get(0))
+	if err != nil {
+		return err
+	}
+
+	repo.PullRequestDefaults = &config.PullRequestDefaults{
+		Template:  c.String("template"),
+		Reviewers: c.StringSlice("reviewer"),
+		Labels:    c.StringSlice("label"),
+	}
+	if repo.PullRequestDefaults.Template == "" && len(repo.PullRequestDefaults.Reviewers) == 0 && len(repo.PullRequestDefaults.Labels) == 0 {
+		repo.PullRequestDefaults = nil
+	}
+
+	profile.TrackedRepos[repoIndex] = *repo
+	configData.Profiles[*profileName] = profile
+
+	return configData.Write(c.GlobalString("config"))
+}
+
+// CompleteRepoSetPullRequestDefaults handles bash autocompletion for the 'profile repo set-pull-request-defaults' command
+func CompleteRepoSetPullRequestDefaults(c *cli.Context) {
+	if c.NArg() >= 1 {
+		return
+	}
+
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return
+	}
+
+	profile := configData.Profiles[*profileName]
+	fmt.Fprintln(c.App.Writer, strings.Join(sortRepoNames(&profile), "\n"))
+}
@@ -0,0 +1,102 @@
+package command_test
+
+import (
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/guywithnose/pull-request-parser/command"
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+func TestCmdRepoSetPullRequestDefaults(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	set.String("template", ".github/pull_request_template.md", "doc")
+	reviewers := cli.StringSlice{"reviewer1", "reviewer2"}
+	set.Var(&reviewers, "reviewer", "doc")
+	labels := cli.StringSlice{"label1"}
+	set.Var(&labels, "label", "doc")
+	assert.Nil(t, set.Parse([]string{"own/rep"}))
+	assert.Nil(t, command.CmdRepoSetPullRequestDefaults(cli.NewContext(nil, set, nil)))
+
+	expectedConfigFile, disposableConfigFile := getConfigWithTwoRepos(t)
+	removeFile(t, disposableConfigFile)
+	profile := expectedConfigFile.Profiles["foo"]
+	profile.TrackedRepos[1].PullRequestDefaults = &config.PullRequestDefaults{
+		Template:  ".github/pull_request_template.md",
+		Reviewers: []string{"reviewer1", "reviewer2"},
+		Labels:    []string{"label1"},
+	}
+	expectedConfigFile.Profiles["foo"] = profile
+	assertConfigFile(t, expectedConfigFile, configFileName)
+}
+
+func TestCmdRepoSetPullRequestDefaultsClear(t *testing.T) {
+	configData, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	configData.Profiles["foo"].TrackedRepos[1].PullRequestDefaults = &config.PullRequestDefaults{Labels: []string{"label1"}}
+	assert.Nil(t, configData.Write(configFileName))
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep"}))
+	assert.Nil(t, command.CmdRepoSetPullRequestDefaults(cli.NewContext(nil, set, nil)))
+
+	expectedConfigFile, disposableConfigFile := getConfigWithTwoRepos(t)
+	removeFile(t, disposableConfigFile)
+	assertConfigFile(t, expectedConfigFile, configFileName)
+}
+
+func TestCmdRepoSetPullRequestDefaultsNoConfig(t *testing.T) {
+	err := command.CmdRepoSetPullRequestDefaults(cli.NewContext(nil, flag.NewFlagSet("test", 0), nil))
+	assert.EqualError(t, err, "You must specify a config file")
+}
+
+func TestCmdRepoSetPullRequestDefaultsInvalidRepo(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep"}))
+
+	err := command.CmdRepoSetPullRequestDefaults(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "Not a valid Repo: own/rep")
+}
+
+func TestCmdRepoSetPullRequestDefaultsUsage(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	err := command.CmdRepoSetPullRequestDefaults(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "Usage: \"prp profile repo set-pull-request-defaults {repoName}\"")
+}
+
+func TestCompleteRepoSetPullRequestDefaultsRepos(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	os.Args = []string{"repo", "set-pull-request-defaults", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteRepoSetPullRequestDefaults(cli.NewContext(app, set, nil))
+	assert.Equal(t, "foo/bar\nown/rep\n", writer.String())
+}
+
+func TestCompleteRepoSetPullRequestDefaultsDone(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep"}))
+	os.Args = []string{"repo", "set-pull-request-defaults", "own/rep", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteRepoSetPullRequestDefaults(cli.NewContext(app, set, nil))
+	assert.Equal(t, "", writer.String())
+}
+
+func TestCompleteRepoSetPullRequestDefaultsNoConfig(t *testing.T) {
+	set := flag.NewFlagSet("test", 0)
+	os.Args = []string{"repo", "set-pull-request-defaults", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteRepoSetPullRequestDefaults(cli.NewContext(app, set, nil))
+	assert.Equal(t, "", writer.String())
+}
@@ -0,0 +1,52 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli"
+)
+
+// CmdRepoSetRebasePolicy sets the conditions a repo's pull requests must meet before auto-rebase updates them
+func CmdRepoSetRebasePolicy(c *cli.Context) error {
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() != 1 {
+		return cli.NewExitError("Usage: \"prp profile repo set-rebase-policy {repoName}\"", 1)
+	}
+
+	profile := configData.Profiles[*profileName]
+	repo, repoIndex, err := loadRepo(&profile, c.Args().Get(0))
+	if err != nil {
+		return err
+	}
+
+	policy := rebasePolicyFromFlags(c)
+	repo.RebasePolicy = &policy
+	if policy.MinApprovals == 0 && len(policy.RequiredLabels) == 0 && !policy.SkipDrafts && !policy.RequirePassingBuilds {
+		repo.RebasePolicy = nil
+	}
+
+	profile.TrackedRepos[repoIndex] = *repo
+	configData.Profiles[*profileName] = profile
+
+	return configData.Write(c.GlobalString("config"))
+}
+
+// CompleteRepoSetRebasePolicy handles bash autocompletion for the 'profile repo set-rebase-policy' command
+func CompleteRepoSetRebasePolicy(c *cli.Context) {
+	if c.NArg() >= 1 {
+		return
+	}
+
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return
+	}
+
+	profile := configData.Profiles[*profileName]
+	fmt.Fprintln(c.App.Writer, strings.Join(sortRepoNames(&profile), "\n"))
+}
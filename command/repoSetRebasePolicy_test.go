@@ -0,0 +1,97 @@
+package command_test
+
+import (
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/guywithnose/pull-request-parser/command"
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+func TestCmdRepoSetRebasePolicy(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	set.Int("min-approvals", 2, "doc")
+	labels := cli.StringSlice{"ready"}
+	set.Var(&labels, "require-label", "doc")
+	set.Bool("skip-drafts", true, "doc")
+	assert.Nil(t, set.Parse([]string{"own/rep"}))
+	assert.Nil(t, command.CmdRepoSetRebasePolicy(cli.NewContext(nil, set, nil)))
+
+	expectedConfigFile, disposableConfigFile := getConfigWithTwoRepos(t)
+	removeFile(t, disposableConfigFile)
+	profile := expectedConfigFile.Profiles["foo"]
+	profile.TrackedRepos[1].RebasePolicy = &config.RebasePolicy{MinApprovals: 2, RequiredLabels: []string{"ready"}, SkipDrafts: true}
+	expectedConfigFile.Profiles["foo"] = profile
+	assertConfigFile(t, expectedConfigFile, configFileName)
+}
+
+func TestCmdRepoSetRebasePolicyClear(t *testing.T) {
+	configData, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	configData.Profiles["foo"].TrackedRepos[1].RebasePolicy = &config.RebasePolicy{SkipDrafts: true}
+	assert.Nil(t, configData.Write(configFileName))
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep"}))
+	assert.Nil(t, command.CmdRepoSetRebasePolicy(cli.NewContext(nil, set, nil)))
+
+	expectedConfigFile, disposableConfigFile := getConfigWithTwoRepos(t)
+	removeFile(t, disposableConfigFile)
+	assertConfigFile(t, expectedConfigFile, configFileName)
+}
+
+func TestCmdRepoSetRebasePolicyNoConfig(t *testing.T) {
+	err := command.CmdRepoSetRebasePolicy(cli.NewContext(nil, flag.NewFlagSet("test", 0), nil))
+	assert.EqualError(t, err, "You must specify a config file")
+}
+
+func TestCmdRepoSetRebasePolicyInvalidRepo(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep"}))
+
+	err := command.CmdRepoSetRebasePolicy(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "Not a valid Repo: own/rep")
+}
+
+func TestCmdRepoSetRebasePolicyUsage(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	err := command.CmdRepoSetRebasePolicy(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "Usage: \"prp profile repo set-rebase-policy {repoName}\"")
+}
+
+func TestCompleteRepoSetRebasePolicyRepos(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	os.Args = []string{"repo", "set-rebase-policy", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteRepoSetRebasePolicy(cli.NewContext(app, set, nil))
+	assert.Equal(t, "foo/bar\nown/rep\n", writer.String())
+}
+
+func TestCompleteRepoSetRebasePolicyDone(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep"}))
+	os.Args = []string{"repo", "set-rebase-policy", "own/rep", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteRepoSetRebasePolicy(cli.NewContext(app, set, nil))
+	assert.Equal(t, "", writer.String())
+}
+
+func TestCompleteRepoSetRebasePolicyNoConfig(t *testing.T) {
+	set := flag.NewFlagSet("test", 0)
+	os.Args = []string{"repo", "set-rebase-policy", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteRepoSetRebasePolicy(cli.NewContext(app, set, nil))
+	assert.Equal(t, "", writer.String())
+}
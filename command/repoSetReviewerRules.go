@@ -0,0 +1,66 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/urfave/cli"
+)
+
+// CmdRepoSetReviewerRules sets who assign-reviewers asks to review a repo's pull requests
+func CmdRepoSetReviewerRules(c *cli.Context) error {
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() != 1 {
+		return cli.NewExitError("Usage: \"prp profile repo set-reviewer-rules {repoName}\"", 1)
+	}
+
+	profile := configData.Profiles[*profileName]
+	repo, repoIndex, err := loadRepo(&profile, c.Args().Get(0))
+	if err != nil {
+		return err
+	}
+
+	teams := make(map[string][]string)
+	for _, team := range c.StringSlice("team") {
+		parts := strings.SplitN(team, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return cli.NewExitError(fmt.Sprintf("Invalid team: %s, expected org/team=user1,user2", team), 1)
+		}
+
+		teams[strings.TrimPrefix(parts[0], "@")] = strings.Split(parts[1], ",")
+	}
+
+	repo.ReviewerRules = &config.ReviewerRules{
+		Required: c.Int("required"),
+		Teams:    teams,
+		Exclude:  c.StringSlice("exclude"),
+	}
+	if repo.ReviewerRules.Required == 0 && len(teams) == 0 && len(repo.ReviewerRules.Exclude) == 0 {
+		repo.ReviewerRules = nil
+	}
+
+	profile.TrackedRepos[repoIndex] = *repo
+	configData.Profiles[*profileName] = profile
+
+	return configData.Write(c.GlobalString("config"))
+}
+
+// CompleteRepoSetReviewerRules handles bash autocompletion for the 'profile repo set-reviewer-rules' command
+func CompleteRepoSetReviewerRules(c *cli.Context) {
+	if c.NArg() >= 1 {
+		return
+	}
+
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return
+	}
+
+	profile := configData.Profiles[*profileName]
+	fmt.Fprintln(c.App.Writer, strings.Join(sortRepoNames(&profile), "\n"))
+}
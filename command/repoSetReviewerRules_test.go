@@ -0,0 +1,113 @@
+package command_test
+
+import (
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/guywithnose/pull-request-parser/command"
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+func TestCmdRepoSetReviewerRules(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	set.Int("required", 2, "doc")
+	teams := cli.StringSlice{"@own/core=guy,guy2", "own/docs=carol"}
+	set.Var(&teams, "team", "doc")
+	exclude := cli.StringSlice{"guy3"}
+	set.Var(&exclude, "exclude", "doc")
+	assert.Nil(t, set.Parse([]string{"own/rep"}))
+	assert.Nil(t, command.CmdRepoSetReviewerRules(cli.NewContext(nil, set, nil)))
+
+	expectedConfigFile, disposableConfigFile := getConfigWithTwoRepos(t)
+	removeFile(t, disposableConfigFile)
+	profile := expectedConfigFile.Profiles["foo"]
+	profile.TrackedRepos[1].ReviewerRules = &config.ReviewerRules{
+		Required: 2,
+		Teams:    map[string][]string{"own/core": {"guy", "guy2"}, "own/docs": {"carol"}},
+		Exclude:  []string{"guy3"},
+	}
+	expectedConfigFile.Profiles["foo"] = profile
+	assertConfigFile(t, expectedConfigFile, configFileName)
+}
+
+func TestCmdRepoSetReviewerRulesClear(t *testing.T) {
+	configData, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	configData.Profiles["foo"].TrackedRepos[1].ReviewerRules = &config.ReviewerRules{Required: 2}
+	assert.Nil(t, configData.Write(configFileName))
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep"}))
+	assert.Nil(t, command.CmdRepoSetReviewerRules(cli.NewContext(nil, set, nil)))
+
+	expectedConfigFile, disposableConfigFile := getConfigWithTwoRepos(t)
+	removeFile(t, disposableConfigFile)
+	assertConfigFile(t, expectedConfigFile, configFileName)
+}
+
+func TestCmdRepoSetReviewerRulesInvalidTeam(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	teams := cli.StringSlice{"own/core"}
+	set.Var(&teams, "team", "doc")
+	assert.Nil(t, set.Parse([]string{"own/rep"}))
+	err := command.CmdRepoSetReviewerRules(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "Invalid team: own/core, expected org/team=user1,user2")
+}
+
+func TestCmdRepoSetReviewerRulesNoConfig(t *testing.T) {
+	err := command.CmdRepoSetReviewerRules(cli.NewContext(nil, flag.NewFlagSet("test", 0), nil))
+	assert.EqualError(t, err, "You must specify a config file")
+}
+
+func TestCmdRepoSetReviewerRulesInvalidRepo(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep"}))
+
+	err := command.CmdRepoSetReviewerRules(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "Not a valid Repo: own/rep")
+}
+
+func TestCmdRepoSetReviewerRulesUsage(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	err := command.CmdRepoSetReviewerRules(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "Usage: \"prp profile repo set-reviewer-rules {repoName}\"")
+}
+
+func TestCompleteRepoSetReviewerRulesRepos(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	os.Args = []string{"repo", "set-reviewer-rules", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteRepoSetReviewerRules(cli.NewContext(app, set, nil))
+	assert.Equal(t, "foo/bar\nown/rep\n", writer.String())
+}
+
+func TestCompleteRepoSetReviewerRulesDone(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep"}))
+	os.Args = []string{"repo", "set-reviewer-rules", "own/rep", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteRepoSetReviewerRules(cli.NewContext(app, set, nil))
+	assert.Equal(t, "", writer.String())
+}
+
+func TestCompleteRepoSetReviewerRulesNoConfig(t *testing.T) {
+	set := flag.NewFlagSet("test", 0)
+	os.Args = []string{"repo", "set-reviewer-rules", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteRepoSetReviewerRules(cli.NewContext(app, set, nil))
+	assert.Equal(t, "", writer.String())
+}
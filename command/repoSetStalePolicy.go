@@ -0,0 +1,53 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/urfave/cli"
+)
+
+// CmdRepoSetStalePolicy sets when stale considers a repo's pull requests stale and what it does about them
+func CmdRepoSetStalePolicy(c *cli.Context) error {
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() != 1 {
+		return cli.NewExitError("Usage: \"prp profile repo set-stale-policy {repoName}\"", 1)
+	}
+
+	profile := configData.Profiles[*profileName]
+	repo, repoIndex, err := loadRepo(&profile, c.Args().Get(0))
+	if err != nil {
+		return err
+	}
+
+	policy := stalePolicyFromFlags(c)
+	repo.StalePolicy = &policy
+	if policy == (config.StalePolicy{}) {
+		repo.StalePolicy = nil
+	}
+
+	profile.TrackedRepos[repoIndex] = *repo
+	configData.Profiles[*profileName] = profile
+
+	return configData.Write(c.GlobalString("config"))
+}
+
+// CompleteRepoSetStalePolicy handles bash autocompletion for the 'profile repo set-stale-policy' command
+func CompleteRepoSetStalePolicy(c *cli.Context) {
+	if c.NArg() >= 1 {
+		return
+	}
+
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return
+	}
+
+	profile := configData.Profiles[*profileName]
+	fmt.Fprintln(c.App.Writer, strings.Join(sortRepoNames(&profile), "\n"))
+}
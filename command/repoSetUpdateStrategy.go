@@ -0,0 +1,60 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/urfave/cli"
+)
+
+// CmdRepoSetUpdateStrategy sets the strategy auto-rebase uses to update a repo's pull requests
+func CmdRepoSetUpdateStrategy(c *cli.Context) error {
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() != 2 {
+		return cli.NewExitError("Usage: \"prp profile repo set-update-strategy {repoName} {strategy}\"", 1)
+	}
+
+	repoName := c.Args().Get(0)
+	strategy := c.Args().Get(1)
+
+	profile := configData.Profiles[*profileName]
+	repo, repoIndex, err := loadRepo(&profile, repoName)
+	if err != nil {
+		return err
+	}
+
+	if !stringSliceContains(strategy, config.UpdateStrategies) {
+		return cli.NewExitError(fmt.Sprintf("Invalid update strategy: %s (must be one of %s)", strategy, strings.Join(config.UpdateStrategies, ", ")), 1)
+	}
+
+	repo.UpdateStrategy = strategy
+	profile.TrackedRepos[repoIndex] = *repo
+	configData.Profiles[*profileName] = profile
+
+	return configData.Write(c.GlobalString("config"))
+}
+
+// CompleteRepoSetUpdateStrategy handles bash autocompletion for the 'profile repo set-update-strategy' command
+func CompleteRepoSetUpdateStrategy(c *cli.Context) {
+	if c.NArg() >= 2 {
+		return
+	}
+
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return
+	}
+
+	profile := configData.Profiles[*profileName]
+
+	if c.NArg() == 0 {
+		fmt.Fprintln(c.App.Writer, strings.Join(sortRepoNames(&profile), "\n"))
+	} else {
+		fmt.Fprintln(c.App.Writer, strings.Join(config.UpdateStrategies, "\n"))
+	}
+}
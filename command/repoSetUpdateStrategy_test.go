@@ -0,0 +1,98 @@
+package command_test
+
+import (
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/guywithnose/pull-request-parser/command"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+func TestCmdRepoSetUpdateStrategy(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep", "merge"}))
+	assert.Nil(t, command.CmdRepoSetUpdateStrategy(cli.NewContext(nil, set, nil)))
+
+	expectedConfigFile, disposableConfigFile := getConfigWithTwoRepos(t)
+	removeFile(t, disposableConfigFile)
+	profile := expectedConfigFile.Profiles["foo"]
+	profile.TrackedRepos[1].UpdateStrategy = "merge"
+	expectedConfigFile.Profiles["foo"] = profile
+	assertConfigFile(t, expectedConfigFile, configFileName)
+}
+
+func TestCmdRepoSetUpdateStrategyInvalidStrategy(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep", "squash"}))
+	err := command.CmdRepoSetUpdateStrategy(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "Invalid update strategy: squash (must be one of rebase, merge, api)")
+}
+
+func TestCmdRepoSetUpdateStrategyNoConfig(t *testing.T) {
+	err := command.CmdRepoSetUpdateStrategy(cli.NewContext(nil, flag.NewFlagSet("test", 0), nil))
+	assert.EqualError(t, err, "You must specify a config file")
+}
+
+func TestCmdRepoSetUpdateStrategyInvalidRepo(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep", "merge"}))
+
+	err := command.CmdRepoSetUpdateStrategy(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "Not a valid Repo: own/rep")
+}
+
+func TestCmdRepoSetUpdateStrategyUsage(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	err := command.CmdRepoSetUpdateStrategy(cli.NewContext(nil, set, nil))
+	assert.EqualError(t, err, "Usage: \"prp profile repo set-update-strategy {repoName} {strategy}\"")
+}
+
+func TestCompleteRepoSetUpdateStrategyRepos(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	os.Args = []string{"repo", "set-update-strategy", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteRepoSetUpdateStrategy(cli.NewContext(app, set, nil))
+	assert.Equal(t, "foo/bar\nown/rep\n", writer.String())
+}
+
+func TestCompleteRepoSetUpdateStrategyStrategies(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep"}))
+	os.Args = []string{"repo", "set-update-strategy", "own/rep", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteRepoSetUpdateStrategy(cli.NewContext(app, set, nil))
+	assert.Equal(t, "rebase\nmerge\napi\n", writer.String())
+}
+
+func TestCompleteRepoSetUpdateStrategyDone(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep", "merge"}))
+	os.Args = []string{"repo", "set-update-strategy", "own/rep", "merge", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteRepoSetUpdateStrategy(cli.NewContext(app, set, nil))
+	assert.Equal(t, "", writer.String())
+}
+
+func TestCompleteRepoSetUpdateStrategyNoConfig(t *testing.T) {
+	set := flag.NewFlagSet("test", 0)
+	os.Args = []string{"repo", "set-update-strategy", "--completion"}
+	app, writer, _ := appWithTestWriters()
+	command.CompleteRepoSetUpdateStrategy(cli.NewContext(app, set, nil))
+	assert.Equal(t, "", writer.String())
+}
@@ -0,0 +1,182 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/urfave/cli"
+)
+
+// failedConclusions are the check run conclusions that rerun retries
+var failedConclusions = []string{"failure", "timed_out", "cancelled"}
+
+// CmdRerun reruns the failed builds of a pull request, or of all your pull requests with --all
+func CmdRerun(c *cli.Context) error {
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+
+	all := c.Bool("all")
+	if (all && c.NArg() != 0) || (!all && c.NArg() < 2) {
+		return cli.NewExitError("Usage: \"prp rerun {repoName} {pullRequestNumber} [context...]\" or \"prp rerun --all\"", 1)
+	}
+
+	profile := configData.Profiles[*profileName]
+	var selectedPullRequests []*pullRequest
+	contexts := []string{}
+	if all {
+		pullRequests, err := getPullRequestsByOwner(&profile, "", c.StringSlice("repo"), c.Bool("use-cache"), c.App.ErrWriter)
+		if err != nil {
+			return err
+		}
+
+		selectedPullRequests = sortPullRequests(selectPullRequests(pullRequests, 0))
+	} else {
+		pr, err := loadRerunPullRequest(&profile, c.Args().Get(0), c.Args().Get(1))
+		if err != nil {
+			return err
+		}
+
+		selectedPullRequests = []*pullRequest{pr}
+		contexts = c.Args()[2:]
+	}
+
+	var completeError error
+	for _, pr := range selectedPullRequests {
+		err = pr.rerunFailedBuilds(contexts, c.App.Writer, c.App.ErrWriter)
+		if err != nil {
+			completeError = cli.NewExitError("Unable to rerun all failed builds", 1)
+		}
+	}
+
+	return completeError
+}
+
+func loadRerunPullRequest(profile *config.Profile, repoName, pullRequestNumber string) (*pullRequest, error) {
+	repo, number, err := loadPullRequest(profile, repoName, pullRequestNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := getGithubClient(&profile.Token, &profile.APIURL, false)
+	if err != nil {
+		return nil, err
+	}
+
+	githubPR, _, err := client.PullRequests.Get(context.Background(), repo.Owner, repo.Name, number)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pullRequest{
+		client:        client,
+		Repo:          repo,
+		PullRequestID: number,
+		SHA:           githubPR.Head.GetSHA(),
+		IgnoredBuilds: repo.IgnoredBuilds,
+	}, nil
+}
+
+// rerunFailedBuilds reruns the failed check runs of pr's head
+// Only the builds in contexts are rerun unless it is empty, ignored builds are only rerun when they are in contexts
+func (pr *pullRequest) rerunFailedBuilds(contexts []string, writer, errorWriter io.Writer) error {
+	runs, err := listCheckRuns(pr.client, pr.Repo.Owner, pr.Repo.Name, pr.SHA)
+	if err != nil {
+		fmt.Fprintf(errorWriter, "Could not list the builds of PR #%d in %s/%s because: %v\n", pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, err)
+		return err
+	}
+
+	checkRunNames := make(map[string]bool)
+	rerunCount := 0
+	var failure error
+	for _, run := range runs {
+		checkRunNames[run.Name] = true
+		if !pr.shouldRerun(run.Name, contexts) || !stringSliceContains(run.Conclusion, failedConclusions) {
+			continue
+		}
+
+		rerunCount++
+		err = rerunCheckRun(pr.client, pr.Repo.Owner, pr.Repo.Name, run)
+		if err != nil {
+			fmt.Fprintf(errorWriter, "Could not rerun %s on PR #%d in %s/%s because: %v\n", run.Name, pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, err)
+			failure = err
+			continue
+		}
+
+		fmt.Fprintf(writer, "Reran %s on PR #%d in %s/%s\n", run.Name, pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name)
+	}
+
+	// Commit statuses are reported by CI systems outside of Github so they can't be rerun from here
+	statuses, err := pr.getLatestCommitStatuses(pr.SHA)
+	if err == nil {
+		failedStatuses := []string{}
+		for context, state := range statuses {
+			if (state == "failure" || state == "error") && !checkRunNames[context] && pr.shouldRerun(context, contexts) {
+				failedStatuses = append(failedStatuses, context)
+			}
+		}
+
+		sort.Strings(failedStatuses)
+		for _, context := range failedStatuses {
+			rerunCount++
+			fmt.Fprintf(
+				errorWriter,
+				"Can't rerun %s on PR #%d in %s/%s because it is a commit status, rerun it from its CI\n",
+				context,
+				pr.PullRequestID,
+				pr.Repo.Owner,
+				pr.Repo.Name,
+			)
+		}
+	}
+
+	if rerunCount == 0 {
+		fmt.Fprintf(writer, "No failed builds to rerun on PR #%d in %s/%s\n", pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name)
+	}
+
+	return failure
+}
+
+func (pr pullRequest) shouldRerun(context string, contexts []string) bool {
+	if len(contexts) != 0 {
+		return stringSliceContains(context, contexts)
+	}
+
+	return !pr.contextIsIgnored(context)
+}
+
+// CompleteRerun handles bash autocompletion for the 'rerun' command
+func CompleteRerun(c *cli.Context) {
+	lastParam := os.Args[len(os.Args)-2]
+	if lastParam != "--repo" && (c.Bool("all") || c.NArg() >= 2) {
+		completeFlags(c, "rerun")
+		return
+	}
+
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return
+	}
+
+	profile := configData.Profiles[*profileName]
+	if lastParam == "--repo" {
+		completeRepo(c.StringSlice("repo"), profile, c.App.Writer)
+		return
+	}
+
+	prs, err := getPullRequestsByOwner(&profile, allOwners, nil, true, c.App.ErrWriter)
+	if err != nil {
+		return
+	}
+
+	completions := completeRepoValues(profile, prs, c.Args(), c.NArg() == 1)
+	completions = unique(completions)
+	sort.Strings(completions)
+	fmt.Fprintln(c.App.Writer, strings.Join(completions, "\n"))
+}
@@ -0,0 +1,272 @@
+package command_test
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/guywithnose/pull-request-parser/command"
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+const rerunCheckRunsJSON = `{"check_runs":[` +
+	`{"id":11,"name":"build2","status":"completed","conclusion":"failure","app":{"slug":"github-actions"}},` +
+	`{"id":12,"name":"lint","status":"completed","conclusion":"timed_out","app":{"slug":"circleci-checks"}},` +
+	`{"id":13,"name":"test","status":"completed","conclusion":"success","app":{"slug":"github-actions"}},` +
+	`{"id":14,"name":"docs","status":"in_progress","conclusion":null,"app":{"slug":"github-actions"}}` +
+	`]}`
+
+func TestCmdRerun(t *testing.T) {
+	var testCases = []struct {
+		name             string
+		args             []string
+		setFlags         func(*flag.FlagSet)
+		expectedRequests []string
+		expectedOutput   string
+		expectedErrors   string
+	}{
+		{
+			"FailedCheckRuns",
+			[]string{"own/rep", "1"},
+			func(*flag.FlagSet) {},
+			[]string{"POST /repos/own/rep/actions/jobs/11/rerun", "POST /repos/own/rep/check-runs/12/rerequest"},
+			"Reran build2 on PR #1 in own/rep\nReran lint on PR #1 in own/rep\n",
+			"",
+		},
+		{
+			"Contexts",
+			[]string{"own/rep", "1", "lint", "test"},
+			func(*flag.FlagSet) {},
+			[]string{"POST /repos/own/rep/check-runs/12/rerequest"},
+			"Reran lint on PR #1 in own/rep\n",
+			"",
+		},
+		{
+			"All",
+			[]string{},
+			func(set *flag.FlagSet) { set.Bool("all", true, "doc") },
+			[]string{"POST /repos/own/rep/actions/jobs/11/rerun", "POST /repos/own/rep/check-runs/12/rerequest"},
+			"Reran build2 on PR #1 in own/rep\nReran lint on PR #1 in own/rep\n",
+			"",
+		},
+		{
+			"CommitStatus",
+			[]string{"own/rep", "2"},
+			func(*flag.FlagSet) {},
+			[]string{},
+			"",
+			"Can't rerun build1 on PR #2 in own/rep because it is a commit status, rerun it from its CI\n",
+		},
+		{
+			"Ignored",
+			[]string{"foo/bar", "1"},
+			func(*flag.FlagSet) {},
+			[]string{},
+			"No failed builds to rerun on PR #1 in foo/bar\n",
+			"",
+		},
+		{
+			"IgnoredContext",
+			[]string{"foo/bar", "1", "goo"},
+			func(*flag.FlagSet) {},
+			[]string{"POST /repos/foo/bar/actions/jobs/21/rerun"},
+			"Reran goo on PR #1 in foo/bar\n",
+			"",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := []string{}
+			inner := getAutoRebaseTestServer("")
+			defer inner.Close()
+			ts := getRerunTestServer(inner, &requests)
+			defer ts.Close()
+			writer, errWriter, err := runAgainstServer(t, ts, command.CmdRerun, func(*config.Profile) {}, withArgs(t, tc.args, tc.setFlags))
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedRequests, requests)
+			assert.Equal(t, tc.expectedOutput, writer.String())
+			assert.Equal(t, tc.expectedErrors, errWriter.String())
+		})
+	}
+}
+
+func TestCmdRerunFailure(t *testing.T) {
+	requests := []string{}
+	inner := getAutoRebaseTestServer("/repos/own/rep/actions/jobs/11/rerun")
+	defer inner.Close()
+	ts := getRecordingOverridingTestServer(inner, &requests, map[string]string{
+		"/repos/own/rep/pulls/1":                              getRerunPullRequestJSON(1, "sha1"),
+		"/repos/own/rep/commits/sha1/check-runs?per_page=100": rerunCheckRunsJSON,
+		"/repos/own/rep/check-runs/12/rerequest":              "",
+	})
+	defer ts.Close()
+	writer, errWriter, err := runAgainstServer(t, ts, command.CmdRerun, func(*config.Profile) {}, withArgs(t, []string{"own/rep", "1"}, func(*flag.FlagSet) {}))
+	assert.EqualError(t, err, "Unable to rerun all failed builds")
+	assert.Equal(t, []string{"POST /repos/own/rep/actions/jobs/11/rerun", "POST /repos/own/rep/check-runs/12/rerequest"}, requests)
+	assert.Equal(t, "Reran lint on PR #1 in own/rep\n", writer.String())
+	assert.Contains(t, errWriter.String(), "Could not rerun build2 on PR #1 in own/rep because: ")
+}
+
+func TestCmdRerunListFailure(t *testing.T) {
+	requests := []string{}
+	inner := getAutoRebaseTestServer("/repos/own/rep/commits/sha1/check-runs?per_page=100")
+	defer inner.Close()
+	ts := getRecordingOverridingTestServer(inner, &requests, map[string]string{"/repos/own/rep/pulls/1": getRerunPullRequestJSON(1, "sha1")})
+	defer ts.Close()
+	_, errWriter, err := runAgainstServer(t, ts, command.CmdRerun, func(*config.Profile) {}, withArgs(t, []string{"own/rep", "1"}, func(*flag.FlagSet) {}))
+	assert.EqualError(t, err, "Unable to rerun all failed builds")
+	assert.Equal(t, []string{}, requests)
+	assert.Contains(t, errWriter.String(), "Could not list the builds of PR #1 in own/rep because: ")
+}
+
+func TestCmdRerunPaginatesCheckRuns(t *testing.T) {
+	requests := []string{}
+	inner := getAutoRebaseTestServer("")
+	defer inner.Close()
+	recording := getRecordingOverridingTestServer(inner, &requests, map[string]string{
+		"/repos/own/rep/pulls/1": getRerunPullRequestJSON(1, "sha1"),
+		"/repos/own/rep/commits/sha1/check-runs?page=2&per_page=100": `{"check_runs":[{"id":12,"name":"lint","conclusion":"failure","app":{"slug":"circleci-checks"}}]}`,
+		"/repos/own/rep/check-runs/12/rerequest":                     "",
+		"/repos/own/rep/actions/jobs/11/rerun":                       "",
+	})
+	defer recording.Close()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.String() == "/repos/own/rep/commits/sha1/check-runs?per_page=100" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/own/rep/commits/sha1/check-runs?page=2&per_page=100>; rel="next"`, recording.URL))
+			fmt.Fprint(w, `{"check_runs":[{"id":11,"name":"build2","conclusion":"failure","app":{"slug":"github-actions"}}]}`)
+			return
+		}
+
+		recording.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+	writer, _, err := runAgainstServer(t, ts, command.CmdRerun, func(*config.Profile) {}, withArgs(t, []string{"own/rep", "1"}, func(*flag.FlagSet) {}))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"POST /repos/own/rep/actions/jobs/11/rerun", "POST /repos/own/rep/check-runs/12/rerequest"}, requests)
+	assert.Equal(t, "Reran build2 on PR #1 in own/rep\nReran lint on PR #1 in own/rep\n", writer.String())
+}
+
+func TestCmdRerunInvalidPullRequestNumber(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep", "x"}))
+	app, _, _ := appWithTestWriters()
+	err := command.CmdRerun(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Not a valid pull request number: x")
+}
+
+func TestCmdRerunNoConfig(t *testing.T) {
+	app, _, _ := appWithTestWriters()
+	err := command.CmdRerun(cli.NewContext(app, flag.NewFlagSet("test", 0), nil))
+	assert.EqualError(t, err, "You must specify a config file")
+}
+
+func TestCmdRerunUsage(t *testing.T) {
+	var testCases = []struct {
+		name string
+		args []string
+		all  bool
+	}{
+		{"NoPullRequest", []string{"own/rep"}, false},
+		{"AllWithArgs", []string{"own/rep"}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, configFileName := getConfigWithFooProfile(t)
+			defer removeFile(t, configFileName)
+			set := getBaseFlagSet(configFileName)
+			set.Bool("all", tc.all, "doc")
+			assert.Nil(t, set.Parse(tc.args))
+			app, _, _ := appWithTestWriters()
+			err := command.CmdRerun(cli.NewContext(app, set, nil))
+			assert.EqualError(t, err, "Usage: \"prp rerun {repoName} {pullRequestNumber} [context...]\" or \"prp rerun --all\"")
+		})
+	}
+}
+
+func TestCompleteRerun(t *testing.T) {
+	var testCases = []struct {
+		name           string
+		args           []string
+		expectedOutput string
+	}{
+		{"Repos", []string{}, "foo/bar\nown/rep\n"},
+		{"Numbers", []string{"own/rep"}, "1\n2\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := getAutoRebaseTestServer("")
+			defer ts.Close()
+			_, configFileName := getConfigWithAPIURL(t, ts.URL)
+			defer removeFile(t, configFileName)
+			set := getBaseFlagSet(configFileName)
+			assert.Nil(t, set.Parse(tc.args))
+			app, writer, _ := appWithTestWriters()
+			os.Args = append(append([]string{"rerun"}, tc.args...), "--completion")
+			command.CompleteRerun(cli.NewContext(app, set, nil))
+			assert.Equal(t, tc.expectedOutput, writer.String())
+		})
+	}
+}
+
+func TestCompleteRerunFlags(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep", "1"}))
+	app, writer, _ := appWithTestWriters()
+	app.Commands = []cli.Command{
+		{
+			Name: "rerun",
+			Flags: []cli.Flag{
+				cli.BoolFlag{Name: "all"},
+				cli.StringSliceFlag{Name: "repo, r"},
+			},
+		},
+	}
+	os.Args = []string{"rerun", "own/rep", "1", "--completion"}
+	command.CompleteRerun(cli.NewContext(app, set, nil))
+	assert.Equal(t, "--all\n--repo\n", writer.String())
+}
+
+func TestCompleteRerunRepo(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	set.Bool("all", true, "doc")
+	app, writer, _ := appWithTestWriters()
+	os.Args = []string{"rerun", "--all", "--repo", "--completion"}
+	command.CompleteRerun(cli.NewContext(app, set, nil))
+	assert.Equal(t, "foo/bar\nown/rep\n", writer.String())
+}
+
+// getRerunTestServer serves the check runs of PR #1 and #2 in own/rep and PR #1 in foo/bar and records every request that isn't a GET
+func getRerunTestServer(inner *httptest.Server, requests *[]string) *httptest.Server {
+	return getRecordingOverridingTestServer(inner, requests, map[string]string{
+		"/repos/own/rep/pulls/1":                                 getRerunPullRequestJSON(1, "sha1"),
+		"/repos/own/rep/pulls/2":                                 getRerunPullRequestJSON(2, "sha2"),
+		"/repos/foo/bar/pulls/1":                                 getRerunPullRequestJSON(1, "fooSha1"),
+		"/repos/own/rep/commits/sha1/check-runs?per_page=100":    rerunCheckRunsJSON,
+		"/repos/own/rep/commits/sha2/check-runs?per_page=100":    `{"check_runs":[]}`,
+		"/repos/foo/bar/commits/fooSha1/check-runs?per_page=100": `{"check_runs":[{"id":21,"name":"goo","conclusion":"failure","app":{"slug":"github-actions"}}]}`,
+		"/repos/own/rep/actions/jobs/11/rerun":                   "",
+		"/repos/own/rep/check-runs/12/rerequest":                 "",
+		"/repos/foo/bar/actions/jobs/21/rerun":                   "",
+	})
+}
+
+func getRerunPullRequestJSON(number int, sha string) string {
+	bytes, _ := json.Marshal(&github.PullRequest{Number: &number, Head: &github.PullRequestBranch{SHA: &sha}})
+	return string(bytes)
+}
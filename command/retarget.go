@@ -0,0 +1,153 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/guywithnose/runner"
+	"github.com/urfave/cli"
+)
+
+// retargeting describes how a pull request whose target branch is gone will be moved
+type retargeting struct {
+	pr              *pullRequest
+	reason          string
+	newTargetBranch string
+	previousBaseSHA string
+}
+
+// CmdRetarget moves pull requests whose target branch was merged or deleted onto a live branch
+func CmdRetarget(cmdWrapper runner.Builder) func(*cli.Context) error {
+	return func(c *cli.Context) error {
+		return cmdRetargetHelper(c, cmdWrapper)
+	}
+}
+
+func cmdRetargetHelper(c *cli.Context, cmdWrapper runner.Builder) error {
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() != 0 {
+		return cli.NewExitError("Usage: \"prp retarget\"", 1)
+	}
+
+	profile := configData.Profiles[*profileName]
+	verboseWriter := ioutil.Discard
+	if c.Bool("verbose") {
+		verboseWriter = c.App.ErrWriter
+	}
+
+	pullRequests, err := getPullRequestsByOwner(&profile, "", c.StringSlice("repo"), c.Bool("use-cache"), c.App.ErrWriter)
+	if err != nil {
+		return err
+	}
+
+	var completeError error
+	retargetings := []retargeting{}
+	targets := make(deadTargets)
+	for _, pr := range orderStacks(sortPullRequests(selectPullRequests(pullRequests, c.Int("pull-request-number")))) {
+		if pr.parent != nil {
+			continue
+		}
+
+		plan, err := findRetargeting(pr, c.String("base"), targets)
+		if err != nil {
+			fmt.Fprintf(c.App.ErrWriter, "Could not check the target of PR #%d in %s/%s because: %v\n", pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, err)
+			completeError = cli.NewExitError("Unable to retarget all pull requests", 1)
+			continue
+		}
+
+		if plan != nil {
+			retargetings = append(retargetings, *plan)
+		}
+	}
+
+	if len(retargetings) == 0 {
+		fmt.Fprintln(c.App.Writer, "No pull requests need to be retargeted")
+		return completeError
+	}
+
+	fmt.Fprintln(c.App.Writer, "The following pull requests will be retargeted:")
+	for _, plan := range retargetings {
+		fmt.Fprintf(c.App.Writer, "  PR #%d in %s/%s: %s %s, moving it onto %s\n", plan.pr.PullRequestID, plan.pr.Repo.Owner, plan.pr.Repo.Name, plan.pr.TargetBranch, plan.reason, plan.newTargetBranch)
+	}
+
+	if !c.Bool("yes") && !confirm(os.Stdin, c.App.Writer, "Continue?") {
+		return completeError
+	}
+
+	retargeted := []*pullRequest{}
+	for _, plan := range retargetings {
+		fmt.Fprintf(verboseWriter, "Retargeting PR #%d to %s\n", plan.pr.PullRequestID, plan.newTargetBranch)
+		err = plan.pr.retarget(plan.newTargetBranch, plan.previousBaseSHA)
+		if err != nil {
+			fmt.Fprintf(c.App.ErrWriter, "Could not retarget PR #%d in %s/%s because: %v\n", plan.pr.PullRequestID, plan.pr.Repo.Owner, plan.pr.Repo.Name, err)
+			completeError = cli.NewExitError("Unable to retarget all pull requests", 1)
+			continue
+		}
+
+		retargeted = append(retargeted, plan.pr)
+	}
+
+	rebaser := newRebaser(c.App.ErrWriter, verboseWriter, cmdWrapper, newJournal(journalFile(c)), nil, rebaseOptions{})
+	_, err = rebaser.rebaseSelectedPullRequests(retargeted)
+	if err != nil {
+		return err
+	}
+
+	return completeError
+}
+
+// findRetargeting returns how pr should be moved if its target branch is gone
+// A pull request whose parent was merged follows its parent, otherwise it is moved onto base or the default branch
+func findRetargeting(pr *pullRequest, base string, targets deadTargets) (*retargeting, error) {
+	mergedParent, err := pr.findMergedParent()
+	if err != nil {
+		return nil, err
+	}
+
+	if mergedParent != nil {
+		newTargetBranch := mergedParent.Base.GetRef()
+		if base != "" {
+			newTargetBranch = base
+		}
+
+		return &retargeting{
+			pr:              pr,
+			reason:          fmt.Sprintf("was merged in PR #%d", mergedParent.GetNumber()),
+			newTargetBranch: newTargetBranch,
+			previousBaseSHA: mergedParent.Head.GetSHA(),
+		}, nil
+	}
+
+	reason, previousBaseSHA, err := targets.find(pr)
+	if err != nil || reason == "" {
+		return nil, err
+	}
+
+	newTargetBranch := pr.BaseDefaultBranch
+	if base != "" {
+		newTargetBranch = base
+	}
+
+	return &retargeting{pr: pr, reason: reason, newTargetBranch: newTargetBranch, previousBaseSHA: previousBaseSHA}, nil
+}
+
+// CompleteRetarget handles bash autocompletion for the 'retarget' command
+func CompleteRetarget(c *cli.Context) {
+	lastParam := os.Args[len(os.Args)-2]
+	if lastParam != "--repo" {
+		completeFlags(c, "retarget")
+		return
+	}
+
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return
+	}
+
+	completeRepo(c.StringSlice("repo"), configData.Profiles[*profileName], c.App.Writer)
+}
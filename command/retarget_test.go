@@ -0,0 +1,230 @@
+package command_test
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/guywithnose/pull-request-parser/command"
+	"github.com/guywithnose/runner"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+func TestCmdRetarget(t *testing.T) {
+	var testCases = []struct {
+		name              string
+		pullRequestNumber int
+		branch            string
+		oldTarget         string
+		previousBaseSHA   string
+		reason            string
+	}{
+		{"MergedParent", 5, "feature-c", "feature-old", "shaOld", "feature-old was merged in PR #9"},
+		{"Deleted", 6, "feature-d", "feature-gone", "shaGone", "feature-gone was deleted"},
+		{"MergedWithoutPullRequest", 7, "feature-e", "feature-landed", "shaLanded", "feature-landed was merged into master"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+			ts := getStackTestServer(5, 6, 7, 8)
+			defer ts.Close()
+			cb := &runner.Test{ExpectedCommands: getRetargetRebaseCommands(repoDir, tc.branch, tc.oldTarget, tc.previousBaseSHA)}
+			writer, errWriter := runRetargetAgainstServer(t, ts, repoDir, cb, func(set *flag.FlagSet) {
+				set.Bool("yes", true, "doc")
+				set.Bool("verbose", true, "doc")
+				set.Int("pull-request-number", tc.pullRequestNumber, "doc")
+			})
+			assert.Equal(
+				t,
+				fmt.Sprintf("The following pull requests will be retargeted:\n  PR #%d in own/rep: %s, moving it onto master\n", tc.pullRequestNumber, tc.reason),
+				writer.String(),
+			)
+			assert.Contains(t, errWriter.String(), fmt.Sprintf("Retargeting PR #%d to master\n", tc.pullRequestNumber))
+			assert.Contains(t, errWriter.String(), fmt.Sprintf("Rebasing commits after %s onto upstream/master\n", tc.previousBaseSHA))
+		})
+	}
+}
+
+func TestCmdRetargetDeclined(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	ts := getStackTestServer(5, 6, 7, 8)
+	defer ts.Close()
+	defer replaceStdin(t, "n\n")()
+	cb := &runner.Test{ExpectedCommands: []*runner.ExpectedCommand{}}
+	writer, _ := runRetargetAgainstServer(t, ts, repoDir, cb, func(set *flag.FlagSet) {})
+	assert.Equal(
+		t,
+		strings.Join(
+			[]string{
+				"The following pull requests will be retargeted:",
+				"  PR #5 in own/rep: feature-old was merged in PR #9, moving it onto master",
+				"  PR #6 in own/rep: feature-gone was deleted, moving it onto master",
+				"  PR #7 in own/rep: feature-landed was merged into master, moving it onto master",
+				"Continue? [y/N] ",
+			},
+			"\n",
+		),
+		writer.String(),
+	)
+}
+
+func TestCmdRetargetBase(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	ts := getStackTestServer(5, 6)
+	defer ts.Close()
+	defer replaceStdin(t, "n\n")()
+	cb := &runner.Test{ExpectedCommands: []*runner.ExpectedCommand{}}
+	writer, _ := runRetargetAgainstServer(t, ts, repoDir, cb, func(set *flag.FlagSet) {
+		set.String("base", "develop", "doc")
+	})
+	assert.Equal(
+		t,
+		strings.Join(
+			[]string{
+				"The following pull requests will be retargeted:",
+				"  PR #5 in own/rep: feature-old was merged in PR #9, moving it onto develop",
+				"  PR #6 in own/rep: feature-gone was deleted, moving it onto develop",
+				"Continue? [y/N] ",
+			},
+			"\n",
+		),
+		writer.String(),
+	)
+}
+
+func TestCmdRetargetNothingToRetarget(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	ts := getStackTestServer(3, 4, 8)
+	defer ts.Close()
+	cb := &runner.Test{ExpectedCommands: []*runner.ExpectedCommand{}}
+	writer, _ := runRetargetAgainstServer(t, ts, repoDir, cb, func(set *flag.FlagSet) {})
+	assert.Equal(t, "No pull requests need to be retargeted\n", writer.String())
+}
+
+func TestCmdRetargetRebaseFailure(t *testing.T) {
+	repoDir := fmt.Sprintf("%s/repo", os.TempDir())
+	assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/.git", repoDir), 0777))
+	defer removeFile(t, repoDir)
+	ts := getStackTestServer(6)
+	defer ts.Close()
+	_, configFileName := getConfigWithAPIURLAndPath(t, ts.URL, repoDir)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	set := getBaseFlagSet(configFileName)
+	set.Bool("yes", true, "doc")
+	app, _, errWriter := appWithTestWriters()
+	cb := &runner.Test{
+		ExpectedCommands: []*runner.ExpectedCommand{
+			runner.NewExpectedCommand(repoDir, "git remote -v", "origin\town:feature-dSSHURL (push)\nupstream\town:feature-goneSSHURL (fetch)", 0),
+			runner.NewExpectedCommand(repoDir, "git diff-index --quiet HEAD", "", 0),
+			runner.NewExpectedCommand(repoDir, "git fetch origin", "failure fetching", 1),
+		},
+	}
+	err := command.CmdRetarget(cb)(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Unable to rebase all pull requests")
+	assert.Equal(t, []*runner.ExpectedCommand{}, cb.ExpectedCommands)
+	assert.Equal(t, []error(nil), cb.Errors)
+	assert.Contains(t, errWriter.String(), "Could not rebase PR #6 in own/rep because: ")
+}
+
+func TestCmdRetargetNoConfig(t *testing.T) {
+	app, _, _ := appWithTestWriters()
+	err := command.CmdRetarget(&runner.Test{})(cli.NewContext(app, flag.NewFlagSet("test", 0), nil))
+	assert.EqualError(t, err, "You must specify a config file")
+}
+
+func TestCmdRetargetUsage(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"foo"}))
+	app, _, _ := appWithTestWriters()
+	err := command.CmdRetarget(&runner.Test{})(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Usage: \"prp retarget\"")
+}
+
+func TestCompleteRetargetFlags(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	app, writer, _ := appWithTestWriters()
+	app.Commands = []cli.Command{
+		{
+			Name: "retarget",
+			Flags: []cli.Flag{
+				cli.StringSliceFlag{Name: "repo, r"},
+				cli.StringFlag{Name: "base, b"},
+				cli.BoolFlag{Name: "yes, y"},
+			},
+		},
+	}
+	os.Args = []string{"retarget", "--completion"}
+	command.CompleteRetarget(cli.NewContext(app, set, nil))
+	assert.Equal(t, "--repo\n--base\n--yes\n", writer.String())
+}
+
+func TestCompleteRetargetRepo(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	app, writer, _ := appWithTestWriters()
+	os.Args = []string{"retarget", "--repo", "--completion"}
+	command.CompleteRetarget(cli.NewContext(app, set, nil))
+	assert.Equal(t, "foo/bar\nown/rep\n", writer.String())
+}
+
+func runRetargetAgainstServer(t *testing.T, ts *httptest.Server, repoDir string, cb *runner.Test, setFlags func(*flag.FlagSet)) (*bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+	assert.Nil(t, os.MkdirAll(fmt.Sprintf("%s/.git", repoDir), 0777))
+	defer removeFile(t, repoDir)
+	_, configFileName := getConfigWithAPIURLAndPath(t, ts.URL, repoDir)
+	defer removeFile(t, configFileName)
+	defer removeFile(t, fmt.Sprintf("%s.journal", configFileName))
+	set := getBaseFlagSet(configFileName)
+	setFlags(set)
+	app, writer, errWriter := appWithTestWriters()
+	assert.Nil(t, command.CmdRetarget(cb)(cli.NewContext(app, set, nil)))
+	assert.Equal(t, []*runner.ExpectedCommand{}, cb.ExpectedCommands)
+	assert.Equal(t, []error(nil), cb.Errors)
+	return writer, errWriter
+}
+
+func getRetargetRebaseCommands(repoDir, branch, oldTarget, previousBaseSHA string) []*runner.ExpectedCommand {
+	return []*runner.ExpectedCommand{
+		runner.NewExpectedCommand(repoDir, "git remote -v", fmt.Sprintf("origin\town:%sSSHURL (push)\nupstream\town:%sSSHURL (fetch)", branch, oldTarget), 0),
+		runner.NewExpectedCommand(repoDir, "git diff-index --quiet HEAD", "", 0),
+		runner.NewExpectedCommand(repoDir, "git fetch origin", "", 0),
+		runner.NewExpectedCommand(repoDir, "git fetch upstream", "", 0),
+		runner.NewExpectedCommand(repoDir, "git symbolic-ref HEAD", "currentBranch", 0),
+		runner.NewExpectedCommand(repoDir, fmt.Sprintf("git checkout -b prp-%s", branch), "", 0),
+		runner.NewExpectedCommand(repoDir, fmt.Sprintf("git reset --hard origin/%s", branch), "", 0),
+		runner.NewExpectedCommand(repoDir, fmt.Sprintf("git rebase --onto upstream/master %s", previousBaseSHA), "", 0),
+		runner.NewExpectedCommand(repoDir, fmt.Sprintf("git rev-parse origin/%s HEAD", branch), "oldSHA\nnewSHA", 0),
+		runner.NewExpectedCommand(repoDir, fmt.Sprintf("git push origin prp-%s:%s --force-with-lease=%s:oldSHA", branch, branch, branch), "", 0),
+		runner.NewExpectedCommand(repoDir, "git checkout currentBranch", "", 0),
+		runner.NewExpectedCommand(repoDir, fmt.Sprintf("git branch -D prp-%s", branch), "", 0),
+	}
+}
+
+func replaceStdin(t *testing.T, input string) func() {
+	t.Helper()
+	stdin, err := ioutil.TempFile("", "stdin")
+	assert.Nil(t, err)
+	_, err = stdin.WriteString(input)
+	assert.Nil(t, err)
+	_, err = stdin.Seek(0, 0)
+	assert.Nil(t, err)
+	oldStdin := os.Stdin
+	os.Stdin = stdin
+	return func() {
+		os.Stdin = oldStdin
+		removeFile(t, stdin.Name())
+	}
+}
@@ -0,0 +1,178 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/github"
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/urfave/cli"
+)
+
+// Review events accepted by the Github API
+const (
+	reviewApprove        = "APPROVE"
+	reviewRequestChanges = "REQUEST_CHANGES"
+	reviewComment        = "COMMENT"
+)
+
+var reviewDescriptions = map[string]string{
+	reviewApprove:        "Approved",
+	reviewRequestChanges: "Requested changes on",
+	reviewComment:        "Commented on",
+}
+
+// CmdReview approves, requests changes on or comments on a pull request
+func CmdReview(c *cli.Context) error {
+	return submitReview(c, "Usage: \"prp review {repoName} {pullRequestNumber} --approve|--request-changes|--comment\"", reviewEvent)
+}
+
+// CmdApprove approves a pull request
+func CmdApprove(c *cli.Context) error {
+	return submitReview(c, "Usage: \"prp approve {repoName} {pullRequestNumber}\"", func(*cli.Context) (string, error) {
+		return reviewApprove, nil
+	})
+}
+
+func submitReview(c *cli.Context, usage string, getEvent func(*cli.Context) (string, error)) error {
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() != 2 {
+		return cli.NewExitError(usage, 1)
+	}
+
+	event, err := getEvent(c)
+	if err != nil {
+		return err
+	}
+
+	profile := configData.Profiles[*profileName]
+	repo, number, err := loadPullRequest(&profile, c.Args().Get(0), c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	client, err := getGithubClient(&profile.Token, &profile.APIURL, false)
+	if err != nil {
+		return err
+	}
+
+	review := &github.PullRequestReviewRequest{Event: &event}
+	message := c.String("message")
+	if message != "" {
+		review.Body = &message
+	}
+
+	_, _, err = client.PullRequests.CreateReview(context.Background(), repo.Owner, repo.Name, number, review)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.App.Writer, "%s PR #%d in %s/%s\n", reviewDescriptions[event], number, repo.Owner, repo.Name)
+	return nil
+}
+
+func reviewEvent(c *cli.Context) (string, error) {
+	events := []string{}
+	if c.Bool("approve") {
+		events = append(events, reviewApprove)
+	}
+
+	if c.Bool("request-changes") {
+		events = append(events, reviewRequestChanges)
+	}
+
+	if c.Bool("comment") {
+		events = append(events, reviewComment)
+	}
+
+	if len(events) != 1 {
+		return "", cli.NewExitError("You must specify exactly one of --approve, --request-changes or --comment", 1)
+	}
+
+	// Github rejects these reviews without a body
+	if events[0] != reviewApprove && c.String("message") == "" {
+		return "", cli.NewExitError("You must specify a message with --message to request changes or comment", 1)
+	}
+
+	return events[0], nil
+}
+
+// CompleteReview handles bash autocompletion for the 'review' command
+func CompleteReview(c *cli.Context) {
+	completeReview(c, "review")
+}
+
+// CompleteApprove handles bash autocompletion for the 'approve' command
+func CompleteApprove(c *cli.Context) {
+	completeReview(c, "approve")
+}
+
+func completeReview(c *cli.Context, commandName string) {
+	if c.NArg() >= 2 {
+		completeFlags(c, commandName)
+		return
+	}
+
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return
+	}
+
+	profile := configData.Profiles[*profileName]
+	prs, err := getPullRequestsNeedingReview(&profile, c.App.ErrWriter)
+	if err != nil {
+		return
+	}
+
+	completions := completeRepoValues(profile, prs, c.Args(), c.NArg() == 1)
+	completions = unique(completions)
+	sort.Strings(completions)
+	fmt.Fprintln(c.App.Writer, strings.Join(completions, "\n"))
+}
+
+// getPullRequestsNeedingReview lists the pull requests on tracked repos that other people opened and you haven't approved yet
+func getPullRequestsNeedingReview(profile *config.Profile, errWriter io.Writer) (<-chan *pullRequest, error) {
+	client, err := getGithubClient(&profile.Token, &profile.APIURL, true)
+	if err != nil {
+		return nil, err
+	}
+
+	user, _, err := client.Users.Get(context.Background(), "")
+	if err != nil {
+		return nil, err
+	}
+
+	prs := newParser(client, user, profile).getBasePullRequestData(errWriter)
+	results := make(chan *pullRequest, 10)
+	go func() {
+		wg := sync.WaitGroup{}
+		for pr := range prs {
+			if !pr.NeedsMyApproval {
+				continue
+			}
+
+			wg.Add(1)
+			go func(pr *pullRequest) {
+				pr.getApprovals(user)
+				if pr.NeedsMyApproval {
+					results <- pr
+				}
+
+				wg.Done()
+			}(pr)
+		}
+
+		wg.Wait()
+		close(results)
+	}()
+
+	return results, nil
+}
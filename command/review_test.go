@@ -0,0 +1,243 @@
+package command_test
+
+import (
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/guywithnose/pull-request-parser/command"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+func TestCmdReview(t *testing.T) {
+	var testCases = []struct {
+		name            string
+		setFlags        func(*flag.FlagSet)
+		expectedRequest string
+		expectedOutput  string
+	}{
+		{
+			"Approve",
+			func(set *flag.FlagSet) { set.Bool("approve", true, "doc") },
+			`POST /repos/own/rep/pulls/1/reviews {"event":"APPROVE"}`,
+			"Approved PR #1 in own/rep\n",
+		},
+		{
+			"ApproveWithMessage",
+			func(set *flag.FlagSet) {
+				set.Bool("approve", true, "doc")
+				set.String("message", "Nice work", "doc")
+			},
+			`POST /repos/own/rep/pulls/1/reviews {"body":"Nice work","event":"APPROVE"}`,
+			"Approved PR #1 in own/rep\n",
+		},
+		{
+			"RequestChanges",
+			func(set *flag.FlagSet) {
+				set.Bool("request-changes", true, "doc")
+				set.String("message", "Needs tests", "doc")
+			},
+			`POST /repos/own/rep/pulls/1/reviews {"body":"Needs tests","event":"REQUEST_CHANGES"}`,
+			"Requested changes on PR #1 in own/rep\n",
+		},
+		{
+			"Comment",
+			func(set *flag.FlagSet) {
+				set.Bool("comment", true, "doc")
+				set.String("message", "Why?", "doc")
+			},
+			`POST /repos/own/rep/pulls/1/reviews {"body":"Why?","event":"COMMENT"}`,
+			"Commented on PR #1 in own/rep\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := []string{}
+			ts := getRecordingTestServer(&requests, "")
+			defer ts.Close()
+			_, configFileName := getConfigWithAPIURL(t, ts.URL)
+			defer removeFile(t, configFileName)
+			set := getBaseFlagSet(configFileName)
+			tc.setFlags(set)
+			assert.Nil(t, set.Parse([]string{"own/rep", "1"}))
+			app, writer, _ := appWithTestWriters()
+			assert.Nil(t, command.CmdReview(cli.NewContext(app, set, nil)))
+			assert.Equal(t, []string{tc.expectedRequest}, requests)
+			assert.Equal(t, tc.expectedOutput, writer.String())
+		})
+	}
+}
+
+func TestCmdReviewInvalidFlags(t *testing.T) {
+	var testCases = []struct {
+		name          string
+		setFlags      func(*flag.FlagSet)
+		expectedError string
+	}{
+		{
+			"NoEvent",
+			func(set *flag.FlagSet) {},
+			"You must specify exactly one of --approve, --request-changes or --comment",
+		},
+		{
+			"TwoEvents",
+			func(set *flag.FlagSet) {
+				set.Bool("approve", true, "doc")
+				set.Bool("comment", true, "doc")
+			},
+			"You must specify exactly one of --approve, --request-changes or --comment",
+		},
+		{
+			"NoMessage",
+			func(set *flag.FlagSet) { set.Bool("request-changes", true, "doc") },
+			"You must specify a message with --message to request changes or comment",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := []string{}
+			ts := getRecordingTestServer(&requests, "")
+			defer ts.Close()
+			_, configFileName := getConfigWithAPIURL(t, ts.URL)
+			defer removeFile(t, configFileName)
+			set := getBaseFlagSet(configFileName)
+			tc.setFlags(set)
+			assert.Nil(t, set.Parse([]string{"own/rep", "1"}))
+			app, _, _ := appWithTestWriters()
+			assert.EqualError(t, command.CmdReview(cli.NewContext(app, set, nil)), tc.expectedError)
+			assert.Equal(t, []string{}, requests)
+		})
+	}
+}
+
+func TestCmdReviewInvalidPullRequest(t *testing.T) {
+	var testCases = []struct {
+		name          string
+		args          []string
+		expectedError string
+	}{
+		{"Repo", []string{"own/other", "1"}, "Not a valid Repo: own/other"},
+		{"Number", []string{"own/rep", "one"}, "Not a valid pull request number: one"},
+		{"NegativeNumber", []string{"own/rep", "-1"}, "Not a valid pull request number: -1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, configFileName := getConfigWithTwoRepos(t)
+			defer removeFile(t, configFileName)
+			set := getBaseFlagSet(configFileName)
+			set.Bool("approve", true, "doc")
+			assert.Nil(t, set.Parse(append([]string{"--"}, tc.args...)))
+			app, _, _ := appWithTestWriters()
+			assert.EqualError(t, command.CmdReview(cli.NewContext(app, set, nil)), tc.expectedError)
+		})
+	}
+}
+
+func TestCmdReviewFailure(t *testing.T) {
+	requests := []string{}
+	ts := getRecordingTestServer(&requests, "/repos/own/rep/pulls/1/reviews")
+	defer ts.Close()
+	_, configFileName := getConfigWithAPIURL(t, ts.URL)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	set.Bool("approve", true, "doc")
+	assert.Nil(t, set.Parse([]string{"own/rep", "1"}))
+	app, writer, _ := appWithTestWriters()
+	err := command.CmdReview(cli.NewContext(app, set, nil))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "500")
+	assert.Equal(t, "", writer.String())
+}
+
+func TestCmdReviewNoConfig(t *testing.T) {
+	app, _, _ := appWithTestWriters()
+	err := command.CmdReview(cli.NewContext(app, flag.NewFlagSet("test", 0), nil))
+	assert.EqualError(t, err, "You must specify a config file")
+}
+
+func TestCmdReviewUsage(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	set.Bool("approve", true, "doc")
+	assert.Nil(t, set.Parse([]string{"own/rep"}))
+	app, _, _ := appWithTestWriters()
+	err := command.CmdReview(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Usage: \"prp review {repoName} {pullRequestNumber} --approve|--request-changes|--comment\"")
+}
+
+func TestCmdApprove(t *testing.T) {
+	requests := []string{}
+	ts := getRecordingTestServer(&requests, "")
+	defer ts.Close()
+	_, configFileName := getConfigWithAPIURL(t, ts.URL)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep", "1"}))
+	app, writer, _ := appWithTestWriters()
+	assert.Nil(t, command.CmdApprove(cli.NewContext(app, set, nil)))
+	assert.Equal(t, []string{`POST /repos/own/rep/pulls/1/reviews {"event":"APPROVE"}`}, requests)
+	assert.Equal(t, "Approved PR #1 in own/rep\n", writer.String())
+}
+
+func TestCmdApproveUsage(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	app, _, _ := appWithTestWriters()
+	err := command.CmdApprove(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Usage: \"prp approve {repoName} {pullRequestNumber}\"")
+}
+
+func TestCompleteReviewRepo(t *testing.T) {
+	ts := getParseTestServer("")
+	defer ts.Close()
+	_, configFileName := getConfigWithAPIURL(t, ts.URL)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	app, writer, _ := appWithTestWriters()
+	command.CompleteReview(cli.NewContext(app, set, nil))
+	assert.Equal(t, "foo/bar\nown/rep\n", writer.String())
+}
+
+func TestCompleteReviewPullRequestNumber(t *testing.T) {
+	ts := getParseTestServer("")
+	defer ts.Close()
+	_, configFileName := getConfigWithAPIURL(t, ts.URL)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep"}))
+	app, writer, _ := appWithTestWriters()
+	command.CompleteApprove(cli.NewContext(app, set, nil))
+	assert.Equal(t, "2\n", writer.String())
+}
+
+func TestCompleteReviewFlags(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"own/rep", "1"}))
+	app, writer, _ := appWithTestWriters()
+	app.Commands = []cli.Command{
+		{
+			Name: "review",
+			Flags: []cli.Flag{
+				cli.BoolFlag{Name: "approve, a"},
+				cli.StringFlag{Name: "message, m"},
+			},
+		},
+	}
+	os.Args = []string{"review", "own/rep", "1", "--completion"}
+	command.CompleteReview(cli.NewContext(app, set, nil))
+	assert.Equal(t, "--approve\n--message\n", writer.String())
+}
+
+func TestCompleteReviewNoConfig(t *testing.T) {
+	app, writer, _ := appWithTestWriters()
+	command.CompleteReview(cli.NewContext(app, flag.NewFlagSet("test", 0), nil))
+	assert.Equal(t, "", writer.String())
+}
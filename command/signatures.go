@@ -0,0 +1,46 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+)
+
+// signatureProblems describes the results of git's %G? format that aren't good signatures
+var signatureProblems = map[string]string{
+	"N": "it is not signed",
+	"B": "its signature is bad",
+	"X": "its signature has expired",
+	"Y": "it was signed with an expired key",
+	"R": "it was signed with a revoked key",
+	"E": "its signature could not be checked",
+}
+
+// verifySignatures refuses the commits in revisionRange unless every one of them has a good signature
+func (r rebaser) verifySignatures(path, revisionRange string) error {
+	fmt.Fprintf(r.verboseWriter, "Verifying the signatures of %s\n", revisionRange)
+	output, err := r.cmdWrapper.New(path, "git", "log", "--format=%H %G?", revisionRange).Output()
+	if err != nil {
+		return wrapExitError(err, fmt.Sprintf("Unable to check the signatures of %s", revisionRange))
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+
+		// G is a good signature and U is a good signature from a key that isn't trusted
+		if fields[1] == "G" || fields[1] == "U" {
+			continue
+		}
+
+		problem, ok := signatureProblems[fields[1]]
+		if !ok {
+			problem = fmt.Sprintf("its signature status is %s", fields[1])
+		}
+
+		return fmt.Errorf("Refusing to push commit %s because %s", fields[0], problem)
+	}
+
+	return nil
+}
@@ -0,0 +1,296 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+
+	"github.com/google/go-github/github"
+)
+
+// orderStacks links pull requests that target another pull request's branch to that pull request
+// and sorts them so that every parent comes before its children
+func orderStacks(prs []*pullRequest) []*pullRequest {
+	branches := make(map[string]*pullRequest, len(prs))
+	for _, pr := range prs {
+		branches[fmt.Sprintf("%s:%s", pr.HeadSSHURL, pr.Branch)] = pr
+	}
+
+	for _, pr := range prs {
+		pr.parent = branches[fmt.Sprintf("%s:%s", pr.BaseSSHURL, pr.TargetBranch)]
+	}
+
+	depths := make(map[*pullRequest]int, len(prs))
+	for _, pr := range prs {
+		depths[pr] = stackDepth(pr)
+	}
+
+	ordered := append([]*pullRequest{}, prs...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return depths[ordered[i]] < depths[ordered[j]]
+	})
+
+	return ordered
+}
+
+func stackDepth(pr *pullRequest) int {
+	visited := map[*pullRequest]bool{pr: true}
+	depth := 0
+	for parent := pr.parent; parent != nil && !visited[parent]; parent = parent.parent {
+		visited[parent] = true
+		depth++
+	}
+
+	return depth
+}
+
+// findMergedParent looks for the merged pull request whose branch is the target branch of pr
+// Long lived branches like develop are merged over and over, so a merged pull request only counts
+// when the target branch was deleted or still points at the commit that was merged
+func (pr pullRequest) findMergedParent() (*github.PullRequest, error) {
+	if pr.BaseDefaultBranch == "" || pr.TargetBranch == pr.BaseDefaultBranch {
+		return nil, nil
+	}
+
+	opt := &github.PullRequestListOptions{
+		State:       "closed",
+		Head:        fmt.Sprintf("%s:%s", pr.Repo.Owner, pr.TargetBranch),
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	mergedPullRequests := []*github.PullRequest{}
+	for {
+		closedPullRequests, resp, err := pr.client.PullRequests.List(context.Background(), pr.Repo.Owner, pr.Repo.Name, opt)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, closedPullRequest := range closedPullRequests {
+			if closedPullRequest.MergedAt != nil {
+				mergedPullRequests = append(mergedPullRequests, closedPullRequest)
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opt.Page = resp.NextPage
+	}
+
+	if len(mergedPullRequests) == 0 {
+		return nil, nil
+	}
+
+	branch, response, err := pr.client.Repositories.GetBranch(context.Background(), pr.Repo.Owner, pr.Repo.Name, pr.TargetBranch)
+	if response != nil && response.StatusCode == http.StatusNotFound {
+		// The branch was deleted after the last time it was merged
+		lastMerged := mergedPullRequests[0]
+		for _, mergedPullRequest := range mergedPullRequests[1:] {
+			if mergedPullRequest.MergedAt.After(*lastMerged.MergedAt) {
+				lastMerged = mergedPullRequest
+			}
+		}
+
+		return lastMerged, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, mergedPullRequest := range mergedPullRequests {
+		if mergedPullRequest.Head.GetSHA() == branch.Commit.GetSHA() {
+			return mergedPullRequest, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// findDeadTarget checks whether the target branch of pr was deleted or merged into the default branch
+// It returns why along with the commit pr was based on, or "" if the target branch is alive
+// A branch only counts as merged when it is behind the default branch without commits of its own,
+// a branch that was just created or synced from the default branch is even with it and stays alive
+// That is a guess, git-flow branches like develop are in that state all the time, so only 'prp retarget' uses it and it asks first
+func (pr pullRequest) findDeadTarget() (string, string, error) {
+	if pr.BaseDefaultBranch == "" || pr.TargetBranch == pr.BaseDefaultBranch {
+		return "", "", nil
+	}
+
+	comparison, response, err := pr.client.Repositories.CompareCommits(context.Background(), pr.Repo.Owner, pr.Repo.Name, pr.BaseDefaultBranch, pr.TargetBranch)
+	if response != nil && response.StatusCode == http.StatusNotFound {
+		return "was deleted", pr.BaseSHA, nil
+	}
+
+	if err != nil {
+		return "", "", err
+	}
+
+	if comparison.GetAheadBy() == 0 && comparison.GetBehindBy() > 0 {
+		return fmt.Sprintf("was merged into %s", pr.BaseDefaultBranch), comparison.MergeBaseCommit.GetSHA(), nil
+	}
+
+	return "", "", nil
+}
+
+// deadTarget is the result of findDeadTarget for a target branch
+type deadTarget struct {
+	reason          string
+	previousBaseSHA string
+}
+
+// deadTargets remembers the target branches that were checked so pull requests sharing a target only compare it once
+type deadTargets map[string]deadTarget
+
+func (d deadTargets) find(pr *pullRequest) (string, string, error) {
+	key := targetKey(pr)
+	target, ok := d[key]
+	if !ok {
+		reason, previousBaseSHA, err := pr.findDeadTarget()
+		if err != nil {
+			return "", "", err
+		}
+
+		target = deadTarget{reason: reason, previousBaseSHA: previousBaseSHA}
+		d[key] = target
+	}
+
+	// The base of a pull request whose target was deleted is its own
+	if target.reason == "was deleted" {
+		return target.reason, pr.BaseSHA, nil
+	}
+
+	return target.reason, target.previousBaseSHA, nil
+}
+
+// goneTarget is what auto-rebase knows for sure about a target branch that is gone
+type goneTarget struct {
+	mergedParent *github.PullRequest
+	deleted      bool
+}
+
+// goneTargets remembers the target branches that were checked so pull requests sharing a target only check it once
+type goneTargets map[string]goneTarget
+
+// find returns the merged pull request whose branch is the target branch of pr and whether the target branch was deleted
+func (g goneTargets) find(pr *pullRequest) (goneTarget, error) {
+	key := targetKey(pr)
+	if target, ok := g[key]; ok {
+		return target, nil
+	}
+
+	target := goneTarget{}
+	if pr.BaseDefaultBranch == "" || pr.TargetBranch == pr.BaseDefaultBranch {
+		g[key] = target
+		return target, nil
+	}
+
+	mergedParent, err := pr.findMergedParent()
+	if err != nil {
+		return target, err
+	}
+
+	target.mergedParent = mergedParent
+	if mergedParent == nil {
+		_, response, err := pr.client.Repositories.GetBranch(context.Background(), pr.Repo.Owner, pr.Repo.Name, pr.TargetBranch)
+		if response != nil && response.StatusCode == http.StatusNotFound {
+			target.deleted = true
+		} else if err != nil {
+			return target, err
+		}
+	}
+
+	g[key] = target
+	return target, nil
+}
+
+// retarget changes the base branch of pr and remembers the old base so the next rebase only replays pr's own commits
+func (pr *pullRequest) retarget(newTargetBranch, previousBaseSHA string) error {
+	_, _, err := pr.client.PullRequests.Edit(
+		context.Background(),
+		pr.Repo.Owner,
+		pr.Repo.Name,
+		pr.PullRequestID,
+		&github.PullRequest{Base: &github.PullRequestBranch{Ref: &newTargetBranch}},
+	)
+	if err != nil {
+		return err
+	}
+
+	pr.TargetBranch = newTargetBranch
+	pr.BaseLabel = fmt.Sprintf("%s:%s", pr.Repo.Owner, newTargetBranch)
+	pr.previousBaseSHA = previousBaseSHA
+	pr.Rebased = false
+	return nil
+}
+
+// stackRecord remembers the branch each stacked pull request was stacked on
+// Once the parent is merged its pull request isn't open anymore, so this is how auto-rebase knows the child was stacked
+type stackRecord struct {
+	path    string
+	parents map[string]string
+	changed bool
+}
+
+func loadStackRecord(path string) (*stackRecord, error) {
+	record := &stackRecord{path: path, parents: make(map[string]string)}
+	stacksJSON, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return record, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(stacksJSON, &record.parents)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read %s\n%v", path, err)
+	}
+
+	return record, nil
+}
+
+// remember records the target branch of every pull request in prs that orderStacks linked to a parent
+func (s *stackRecord) remember(prs []*pullRequest) {
+	if s == nil {
+		return
+	}
+
+	for _, pr := range prs {
+		if pr.parent != nil && s.parents[pullRequestKey(pr)] != pr.TargetBranch {
+			s.parents[pullRequestKey(pr)] = pr.TargetBranch
+			s.changed = true
+		}
+	}
+}
+
+// wasStackedOn returns whether pr was seen stacked on the branch it targets now
+func (s *stackRecord) wasStackedOn(pr *pullRequest) bool {
+	return s != nil && s.parents[pullRequestKey(pr)] == pr.TargetBranch
+}
+
+func (s *stackRecord) forget(pr *pullRequest) {
+	if s == nil {
+		return
+	}
+
+	if _, ok := s.parents[pullRequestKey(pr)]; ok {
+		delete(s.parents, pullRequestKey(pr))
+		s.changed = true
+	}
+}
+
+func (s *stackRecord) save() error {
+	if s == nil || !s.changed {
+		return nil
+	}
+
+	stacksJSON, _ := json.MarshalIndent(s.parents, "", "  ")
+	return ioutil.WriteFile(s.path, stacksJSON, 0644)
+}
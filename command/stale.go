@@ -0,0 +1,293 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/urfave/cli"
+)
+
+// Defaults for the parts of a stale policy that aren't set
+const (
+	defaultStaleDays        = 30
+	defaultStaleGracePeriod = 7
+)
+
+// staleWarningPrefix starts every warning stale posts so it can tell them apart from real activity
+const staleWarningPrefix = "This pull request has had no activity for"
+
+const day = 24 * time.Hour
+
+// reaper finds stale pull requests and warns, labels or closes them
+type reaper struct {
+	writer      io.Writer
+	errorWriter io.Writer
+	user        *github.User
+	policy      config.StalePolicy
+	dryRun      bool
+	now         time.Time
+}
+
+// staleness describes how long a pull request has gone without activity
+type staleness struct {
+	lastActivity time.Time
+	lastWarning  time.Time
+	warned       bool
+}
+
+// CmdStale lists pull requests that nobody has touched for a while and warns, labels or closes them
+func CmdStale(c *cli.Context) error {
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() != 0 {
+		return cli.NewExitError("Usage: \"prp stale\"", 1)
+	}
+
+	profile := configData.Profiles[*profileName]
+	client, err := getGithubClient(&profile.Token, &profile.APIURL, c.Bool("use-cache"))
+	if err != nil {
+		return err
+	}
+
+	user, _, err := client.Users.Get(context.Background(), "")
+	if err != nil {
+		return err
+	}
+
+	prs := newParser(client, user, &profile).getBasePullRequestData(c.App.ErrWriter)
+	prs = filterPullRequestsByRepo(prs, c.String("owner"), c.StringSlice("repo"))
+
+	reaper := &reaper{
+		writer:      c.App.Writer,
+		errorWriter: c.App.ErrWriter,
+		user:        user,
+		policy:      stalePolicyFromFlags(c),
+		dryRun:      c.Bool("dry-run"),
+		now:         time.Now(),
+	}
+
+	return reaper.reap(selectPullRequests(prs, 0))
+}
+
+func (r reaper) reap(prs []*pullRequest) error {
+	stalenesses := r.getStalenesses(prs)
+	var completeError error
+	found := false
+	for _, pr := range sortPullRequests(prs) {
+		staleness, ok := stalenesses[pr]
+		if !ok {
+			continue
+		}
+
+		policy := r.policyFor(pr)
+		inactiveDays := int(r.now.Sub(staleness.lastActivity) / day)
+		if inactiveDays < policy.Days {
+			continue
+		}
+
+		found = true
+		fmt.Fprintf(r.writer, "PR #%d in %s/%s has had no activity for %d days: %s\n", pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, inactiveDays, pr.Title)
+		err := r.applyPolicy(pr, policy, inactiveDays, staleness)
+		if err != nil {
+			fmt.Fprintf(r.errorWriter, "Could not update stale PR #%d in %s/%s because: %v\n", pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, err)
+			completeError = cli.NewExitError("Unable to update all stale pull requests", 1)
+		}
+	}
+
+	if !found {
+		fmt.Fprintln(r.writer, "No pull requests are stale")
+	}
+
+	return completeError
+}
+
+// policyFor combines the flags' policy with pr's repository's policy and fills in the defaults
+func (r reaper) policyFor(pr *pullRequest) config.StalePolicy {
+	policy := r.policy.Merge(pr.Repo.StalePolicy)
+	if policy.Days == 0 {
+		policy.Days = defaultStaleDays
+	}
+
+	if policy.GracePeriod == 0 {
+		policy.GracePeriod = defaultStaleGracePeriod
+	}
+
+	return policy
+}
+
+// getStalenesses finds out when each pull request last saw activity
+// Pull requests that are newer than their policy's days can't be stale and are left out
+func (r reaper) getStalenesses(prs []*pullRequest) map[*pullRequest]staleness {
+	stalenesses := make(map[*pullRequest]staleness)
+	mutex := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for _, pr := range prs {
+		policy := r.policyFor(pr)
+		if r.now.Sub(pr.CreatedAt) < time.Duration(policy.Days)*day {
+			continue
+		}
+
+		wg.Add(1)
+		go func(pr *pullRequest) {
+			defer wg.Done()
+			staleness, err := pr.getStaleness(r.user)
+			if err != nil {
+				fmt.Fprintf(r.errorWriter, "Could not load the timeline of PR #%d in %s/%s because: %v\n", pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name, err)
+				return
+			}
+
+			pr.getLabels()
+			mutex.Lock()
+			stalenesses[pr] = staleness
+			mutex.Unlock()
+		}(pr)
+	}
+
+	wg.Wait()
+	return stalenesses
+}
+
+// getStaleness looks through pr's timeline for the last commit, comment or review
+// The warnings user posted are not activity, they only tell whether pr was already warned
+func (pr *pullRequest) getStaleness(user *github.User) (staleness, error) {
+	events, err := listTimeline(pr.client, pr.Repo.Owner, pr.Repo.Name, pr.PullRequestID)
+	if err != nil {
+		return staleness{}, err
+	}
+
+	result := staleness{lastActivity: pr.CreatedAt}
+	for _, event := range events {
+		switch event.Event {
+		case "commented":
+			if event.User.GetLogin() == user.GetLogin() && strings.HasPrefix(event.Body, staleWarningPrefix) {
+				if event.Time().After(result.lastWarning) {
+					result.lastWarning = event.Time()
+				}
+
+				continue
+			}
+		case "committed", "reviewed":
+		default:
+			continue
+		}
+
+		if event.Time().After(result.lastActivity) {
+			result.lastActivity = event.Time()
+		}
+	}
+
+	result.warned = result.lastWarning.After(result.lastActivity)
+	return result, nil
+}
+
+func (r reaper) applyPolicy(pr *pullRequest, policy config.StalePolicy, inactiveDays int, staleness staleness) error {
+	if r.shouldClose(policy, inactiveDays, staleness) {
+		if r.dryRun {
+			fmt.Fprintf(r.writer, "  Would close it\n")
+			return nil
+		}
+
+		_, _, err := pr.client.PullRequests.Edit(context.Background(), pr.Repo.Owner, pr.Repo.Name, pr.PullRequestID, &github.PullRequest{State: github.String("closed")})
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(r.writer, "  Closed it\n")
+		return nil
+	}
+
+	if policy.Warn && !staleness.warned {
+		if r.dryRun {
+			fmt.Fprintf(r.writer, "  Would warn its author\n")
+		} else {
+			body := staleWarning(policy, inactiveDays)
+			_, _, err := pr.client.Issues.CreateComment(context.Background(), pr.Repo.Owner, pr.Repo.Name, pr.PullRequestID, &github.IssueComment{Body: &body})
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(r.writer, "  Warned its author\n")
+		}
+	}
+
+	if policy.Label != "" && !stringSliceContains(policy.Label, pr.Labels) {
+		if r.dryRun {
+			fmt.Fprintf(r.writer, "  Would add the label %s\n", policy.Label)
+			return nil
+		}
+
+		_, _, err := pr.client.Issues.AddLabelsToIssue(context.Background(), pr.Repo.Owner, pr.Repo.Name, pr.PullRequestID, []string{policy.Label})
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(r.writer, "  Added the label %s\n", policy.Label)
+	}
+
+	return nil
+}
+
+// shouldClose tells whether the grace period of a pull request that has been inactive for inactiveDays is over
+// When the policy warns, the grace period starts with the last warning so nothing is closed without one
+func (r reaper) shouldClose(policy config.StalePolicy, inactiveDays int, staleness staleness) bool {
+	if !policy.Close {
+		return false
+	}
+
+	if policy.Warn {
+		return staleness.warned && r.now.Sub(staleness.lastWarning) >= time.Duration(policy.GracePeriod)*day
+	}
+
+	return inactiveDays >= policy.Days+policy.GracePeriod
+}
+
+func staleWarning(policy config.StalePolicy, inactiveDays int) string {
+	warning := fmt.Sprintf("%s %d days.", staleWarningPrefix, inactiveDays)
+	if policy.Close {
+		warning = fmt.Sprintf("%s It will be closed in %d days unless it is updated.", warning, policy.GracePeriod)
+	}
+
+	return warning
+}
+
+func stalePolicyFromFlags(c *cli.Context) config.StalePolicy {
+	return config.StalePolicy{
+		Days:        c.Int("days"),
+		Warn:        c.Bool("warn"),
+		Label:       c.String("label"),
+		Close:       c.Bool("close"),
+		GracePeriod: c.Int("grace-period"),
+	}
+}
+
+// CompleteStale handles bash autocompletion for the 'stale' command
+func CompleteStale(c *cli.Context) {
+	lastParam := os.Args[len(os.Args)-2]
+	if lastParam != "--repo" && lastParam != "--owner" {
+		completeFlags(c, "stale")
+		return
+	}
+
+	configData, profileName, err := loadProfile(c)
+	if err != nil {
+		return
+	}
+
+	profile := configData.Profiles[*profileName]
+	if lastParam == "--owner" {
+		completeUser(&profile, c.App.Writer, c.App.ErrWriter)
+		return
+	}
+
+	completeRepo(c.StringSlice("repo"), profile, c.App.Writer)
+}
@@ -0,0 +1,291 @@
+package command_test
+
+import (
+	"flag"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guywithnose/pull-request-parser/command"
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+func TestCmdStale(t *testing.T) {
+	var testCases = []struct {
+		name             string
+		modifyRepo       func(*config.Repo)
+		setFlags         func(*flag.FlagSet)
+		expectedRequests []string
+		expectedOutput   []string
+	}{
+		{
+			"List",
+			func(*config.Repo) {},
+			func(*flag.FlagSet) {},
+			[]string{},
+			[]string{
+				"PR #1 in foo/bar has had no activity for 40 days: fooPrOne",
+				"PR #1 in own/rep has had no activity for 45 days: prOne",
+			},
+		},
+		{
+			"WarnAndLabel",
+			func(*config.Repo) {},
+			func(set *flag.FlagSet) {
+				set.Bool("warn", true, "doc")
+				set.String("label", "stale", "doc")
+			},
+			[]string{
+				`POST /repos/foo/bar/issues/1/labels ["stale"]`,
+				`POST /repos/own/rep/issues/1/comments {"body":"This pull request has had no activity for 45 days."}`,
+				`POST /repos/own/rep/issues/1/labels ["stale"]`,
+			},
+			[]string{
+				"PR #1 in foo/bar has had no activity for 40 days: fooPrOne",
+				"  Added the label stale",
+				"PR #1 in own/rep has had no activity for 45 days: prOne",
+				"  Warned its author",
+				"  Added the label stale",
+			},
+		},
+		{
+			"WarnBeforeClosing",
+			func(*config.Repo) {},
+			func(set *flag.FlagSet) {
+				set.Int("days", 40, "doc")
+				set.Int("grace-period", 10, "doc")
+				set.Bool("warn", true, "doc")
+				set.Bool("close", true, "doc")
+			},
+			[]string{
+				`POST /repos/own/rep/issues/1/comments {"body":"This pull request has had no activity for 45 days. It will be closed in 10 days unless it is updated."}`,
+			},
+			[]string{
+				"PR #1 in foo/bar has had no activity for 40 days: fooPrOne",
+				"PR #1 in own/rep has had no activity for 45 days: prOne",
+				"  Warned its author",
+			},
+		},
+		{
+			"CloseAfterWarning",
+			func(*config.Repo) {},
+			func(set *flag.FlagSet) {
+				set.Bool("warn", true, "doc")
+				set.Bool("close", true, "doc")
+				set.Int("grace-period", 5, "doc")
+			},
+			[]string{
+				`PATCH /repos/foo/bar/pulls/1 {"state":"closed"}`,
+				`POST /repos/own/rep/issues/1/comments {"body":"This pull request has had no activity for 45 days. It will be closed in 5 days unless it is updated."}`,
+			},
+			[]string{
+				"PR #1 in foo/bar has had no activity for 40 days: fooPrOne",
+				"  Closed it",
+				"PR #1 in own/rep has had no activity for 45 days: prOne",
+				"  Warned its author",
+			},
+		},
+		{
+			"Close",
+			func(*config.Repo) {},
+			func(set *flag.FlagSet) {
+				set.Bool("close", true, "doc")
+				set.Int("grace-period", 12, "doc")
+			},
+			[]string{`PATCH /repos/own/rep/pulls/1 {"state":"closed"}`},
+			[]string{
+				"PR #1 in foo/bar has had no activity for 40 days: fooPrOne",
+				"PR #1 in own/rep has had no activity for 45 days: prOne",
+				"  Closed it",
+			},
+		},
+		{
+			"RepoPolicy",
+			func(repo *config.Repo) { repo.StalePolicy = &config.StalePolicy{Days: 50, Label: "stale"} },
+			func(*flag.FlagSet) {},
+			[]string{},
+			[]string{"PR #1 in foo/bar has had no activity for 40 days: fooPrOne"},
+		},
+		{
+			"RepoPolicyActions",
+			func(repo *config.Repo) { repo.StalePolicy = &config.StalePolicy{Label: "stale"} },
+			func(*flag.FlagSet) {},
+			[]string{`POST /repos/own/rep/issues/1/labels ["stale"]`},
+			[]string{
+				"PR #1 in foo/bar has had no activity for 40 days: fooPrOne",
+				"PR #1 in own/rep has had no activity for 45 days: prOne",
+				"  Added the label stale",
+			},
+		},
+		{
+			"DryRun",
+			func(*config.Repo) {},
+			func(set *flag.FlagSet) {
+				set.Bool("dry-run", true, "doc")
+				set.Bool("warn", true, "doc")
+				set.String("label", "stale", "doc")
+				set.Bool("close", true, "doc")
+				set.Int("grace-period", 12, "doc")
+			},
+			[]string{},
+			[]string{
+				"PR #1 in foo/bar has had no activity for 40 days: fooPrOne",
+				"  Would add the label stale",
+				"PR #1 in own/rep has had no activity for 45 days: prOne",
+				"  Would warn its author",
+				"  Would add the label stale",
+			},
+		},
+		{
+			"Owner",
+			func(*config.Repo) {},
+			func(set *flag.FlagSet) { set.String("owner", "guy", "doc") },
+			[]string{},
+			[]string{"PR #1 in own/rep has had no activity for 45 days: prOne"},
+		},
+		{
+			"NothingStale",
+			func(*config.Repo) {},
+			func(set *flag.FlagSet) { set.Int("days", 60, "doc") },
+			[]string{},
+			[]string{"No pull requests are stale"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := []string{}
+			inner := getParseTestServer("")
+			defer inner.Close()
+			ts := getStaleTestServer(inner, &requests, map[string]string{"/repos/own/rep/pulls/1": "{}", "/repos/foo/bar/pulls/1": "{}"})
+			defer ts.Close()
+			writer, errWriter, err := runAgainstServer(t, ts, command.CmdStale, withRepo(tc.modifyRepo), tc.setFlags)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedRequests, requests)
+			assert.Equal(t, strings.Join(append(tc.expectedOutput, ""), "\n"), writer.String())
+			assert.Equal(t, "", errWriter.String())
+		})
+	}
+}
+
+func TestCmdStaleTimelineFailure(t *testing.T) {
+	requests := []string{}
+	inner := getParseTestServer("")
+	defer inner.Close()
+	ts := getStaleTestServer(inner, &requests, map[string]string{"/repos/own/rep/issues/1/timeline?per_page=100": "not json"})
+	defer ts.Close()
+	writer, errWriter, err := runAgainstServer(t, ts, command.CmdStale, func(*config.Profile) {}, func(*flag.FlagSet) {})
+	assert.Nil(t, err)
+	assert.Equal(t, "PR #1 in foo/bar has had no activity for 40 days: fooPrOne\n", writer.String())
+	assert.Contains(t, errWriter.String(), "Could not load the timeline of PR #1 in own/rep because: ")
+}
+
+func TestCmdStaleCloseFailure(t *testing.T) {
+	requests := []string{}
+	inner := getParseTestServer("/repos/own/rep/pulls/1")
+	defer inner.Close()
+	ts := getStaleTestServer(inner, &requests, map[string]string{})
+	defer ts.Close()
+	writer, errWriter, err := runAgainstServer(t, ts, command.CmdStale, func(*config.Profile) {}, func(set *flag.FlagSet) {
+		set.Bool("close", true, "doc")
+		set.Int("grace-period", 12, "doc")
+	})
+	assert.EqualError(t, err, "Unable to update all stale pull requests")
+	assert.Equal(t, []string{`PATCH /repos/own/rep/pulls/1 {"state":"closed"}`}, requests)
+	assert.Equal(
+		t,
+		"PR #1 in foo/bar has had no activity for 40 days: fooPrOne\nPR #1 in own/rep has had no activity for 45 days: prOne\n",
+		writer.String(),
+	)
+	assert.Contains(t, errWriter.String(), "Could not update stale PR #1 in own/rep because: ")
+}
+
+func TestCmdStaleNoConfig(t *testing.T) {
+	app, _, _ := appWithTestWriters()
+	err := command.CmdStale(cli.NewContext(app, flag.NewFlagSet("test", 0), nil))
+	assert.EqualError(t, err, "You must specify a config file")
+}
+
+func TestCmdStaleUsage(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	assert.Nil(t, set.Parse([]string{"foo"}))
+	app, _, _ := appWithTestWriters()
+	err := command.CmdStale(cli.NewContext(app, set, nil))
+	assert.EqualError(t, err, "Usage: \"prp stale\"")
+}
+
+func TestCompleteStaleFlags(t *testing.T) {
+	_, configFileName := getConfigWithFooProfile(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	app, writer, _ := appWithTestWriters()
+	app.Commands = []cli.Command{
+		{
+			Name: "stale",
+			Flags: []cli.Flag{
+				cli.StringFlag{Name: "owner, o"},
+				cli.BoolFlag{Name: "close"},
+			},
+		},
+	}
+	os.Args = []string{"stale", "--completion"}
+	command.CompleteStale(cli.NewContext(app, set, nil))
+	assert.Equal(t, "--owner\n--close\n", writer.String())
+}
+
+func TestCompleteStaleRepo(t *testing.T) {
+	_, configFileName := getConfigWithTwoRepos(t)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	app, writer, _ := appWithTestWriters()
+	os.Args = []string{"stale", "--repo", "--completion"}
+	command.CompleteStale(cli.NewContext(app, set, nil))
+	assert.Equal(t, "foo/bar\nown/rep\n", writer.String())
+}
+
+func TestCompleteStaleOwner(t *testing.T) {
+	ts := getParseTestServer("")
+	defer ts.Close()
+	_, configFileName := getConfigWithAPIURL(t, ts.URL)
+	defer removeFile(t, configFileName)
+	set := getBaseFlagSet(configFileName)
+	app, writer, _ := appWithTestWriters()
+	os.Args = []string{"stale", "--owner", "--completion"}
+	command.CompleteStale(cli.NewContext(app, set, nil))
+	assert.Equal(t, "fooGuy\nfooGuy2\nguy\nguy2\n", writer.String())
+}
+
+// getStaleTestServer serves timelines where PR #1 in own/rep was last commented on 45 days ago,
+// PR #1 in foo/bar was last reviewed 40 days ago and already warned, and the other pull requests are active
+// It records every request that isn't a GET
+func getStaleTestServer(inner *httptest.Server, requests *[]string, responses map[string]string) *httptest.Server {
+	daysAgo := func(days int) string {
+		return time.Now().Add(-time.Duration(days) * 24 * time.Hour).UTC().Format(time.RFC3339)
+	}
+
+	return getRecordingOverridingTestServer(inner, requests, mergeResponses(map[string]string{
+		"/repos/own/rep/issues/1/timeline?per_page=100": fmt.Sprintf(
+			`[{"event":"committed","committer":{"date":"%s"}},{"event":"commented","created_at":"%s","user":{"login":"guy"},"body":"ping"}]`,
+			daysAgo(60),
+			daysAgo(45),
+		),
+		"/repos/own/rep/issues/2/timeline?per_page=100": fmt.Sprintf(`[{"event":"committed","committer":{"date":"%s"}}]`, daysAgo(3)),
+		"/repos/foo/bar/issues/1/timeline?per_page=100": fmt.Sprintf(
+			`[{"event":"reviewed","submitted_at":"%s","user":{"login":"guy"}},`+
+				`{"event":"labeled","created_at":"%s","label":{"name":"label2"}},`+
+				`{"event":"commented","created_at":"%s","user":{"login":"fooGuy"},"body":"This pull request has had no activity for 35 days."}]`,
+			daysAgo(40),
+			daysAgo(10),
+			daysAgo(5),
+		),
+		"/repos/foo/bar/issues/2/timeline?per_page=100": fmt.Sprintf(`[{"event":"commented","created_at":"%s","user":{"login":"guy"},"body":"ping"}]`, daysAgo(20)),
+		"/repos/own/rep/issues/1/comments":              "{}",
+	}, responses))
+}
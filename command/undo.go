@@ -0,0 +1,96 @@
+package command
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/urfave/cli"
+)
+
+// undoablePushes returns the most recent push of every pull request that has not been undone yet
+func undoablePushes(entries []journalEntry, repos []string, pullRequestNumber int) []journalEntry {
+	order := []string{}
+	latest := make(map[string]journalEntry)
+	for _, entry := range entries {
+		if entry.Outcome != outcomePushed && entry.Outcome != outcomeUndone {
+			continue
+		}
+
+		if len(repos) != 0 && !stringSliceContains(entry.Repo, repos) {
+			continue
+		}
+
+		if pullRequestNumber != 0 && entry.PullRequestID != pullRequestNumber {
+			continue
+		}
+
+		key := fmt.Sprintf("%s#%d", entry.Repo, entry.PullRequestID)
+		if _, ok := latest[key]; !ok {
+			order = append(order, key)
+		}
+
+		latest[key] = entry
+	}
+
+	pushes := []journalEntry{}
+	for _, key := range order {
+		if latest[key].Outcome == outcomePushed {
+			pushes = append(pushes, latest[key])
+		}
+	}
+
+	return pushes
+}
+
+func (r rebaser) undoPushes(repos []string, pullRequestNumber int, writer io.Writer, reader io.Reader, skipConfirmation bool) error {
+	entries, err := r.journal.entries()
+	if err != nil {
+		return cli.NewExitError(fmt.Sprintf("Unable to read journal %s\n%v", r.journal.path, err), 1)
+	}
+
+	pushes := undoablePushes(entries, repos, pullRequestNumber)
+	if len(pushes) == 0 {
+		fmt.Fprintln(writer, "Nothing to undo")
+		return nil
+	}
+
+	fmt.Fprintln(writer, "The following pushes will be undone:")
+	for _, push := range pushes {
+		fmt.Fprintf(writer, "  PR #%d in %s: %s from %s back to %s\n", push.PullRequestID, push.Repo, push.Branch, push.NewSHA, push.OldSHA)
+	}
+
+	if !skipConfirmation && !confirm(reader, writer, "Continue?") {
+		return nil
+	}
+
+	var completeError error
+	for _, push := range pushes {
+		err = r.undoPush(push)
+		if err != nil {
+			fmt.Fprintf(r.errorWriter, "Could not undo PR #%d in %s because: %v\n", push.PullRequestID, push.Repo, err)
+			completeError = cli.NewExitError("Unable to undo all pushes", 1)
+		}
+	}
+
+	return completeError
+}
+
+func (r rebaser) undoPush(push journalEntry) error {
+	fmt.Fprintf(r.verboseWriter, "Resetting %s/%s to %s\n", push.Remote, push.Branch, push.OldSHA)
+	err := r.runCommand(
+		push.Path,
+		"git",
+		"push",
+		fmt.Sprintf("--force-with-lease=%s:%s", push.Branch, push.NewSHA),
+		push.Remote,
+		fmt.Sprintf("%s:refs/heads/%s", push.OldSHA, push.Branch),
+	)
+	if err != nil {
+		return wrapExitError(err, fmt.Sprintf("Unable to push %s to %s/%s, it may have changed since it was rebased", push.OldSHA, push.Remote, push.Branch))
+	}
+
+	push.OldSHA, push.NewSHA = push.NewSHA, push.OldSHA
+	push.Outcome = outcomeUndone
+	r.record(push)
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/google/go-github/github"
@@ -314,8 +315,14 @@ func handleLabelRequests(r *http.Request, _ http.ResponseWriter, _ *httptest.Ser
 	return nil
 }
 
+// handleStatusRequests ignores the query so statuses can be requested with or without paging
 func handleStatusRequests(r *http.Request, _ http.ResponseWriter, _ *httptest.Server) *string {
-	if r.URL.String() == "/repos/own/rep/commits/sha1/statuses" {
+	if strings.HasSuffix(r.URL.Path, "/check-runs") {
+		response := `{"check_runs":[]}`
+		return &response
+	}
+
+	if r.URL.Path == "/repos/own/rep/commits/sha1/statuses" {
 		bytes, _ := json.Marshal([]*github.RepoStatus{
 			newStatus("build1", "success"),
 			newStatus("build1", "pending"),
@@ -324,7 +331,7 @@ func handleStatusRequests(r *http.Request, _ http.ResponseWriter, _ *httptest.Se
 		return &response
 	}
 
-	if r.URL.String() == "/repos/foo/bar/commits/fooSha1/statuses" {
+	if r.URL.Path == "/repos/foo/bar/commits/fooSha1/statuses" {
 		bytes, _ := json.Marshal([]*github.RepoStatus{
 			newStatus("build1", "pending"),
 			newStatus("build2", "success"),
@@ -336,7 +343,7 @@ func handleStatusRequests(r *http.Request, _ http.ResponseWriter, _ *httptest.Se
 		return &response
 	}
 
-	if r.URL.String() == "/repos/own/rep/commits/sha2/statuses" {
+	if r.URL.Path == "/repos/own/rep/commits/sha2/statuses" {
 		bytes, _ := json.Marshal([]*github.RepoStatus{
 			newStatus("build1", "failure"),
 			newStatus("build1", "pending"),
@@ -345,7 +352,7 @@ func handleStatusRequests(r *http.Request, _ http.ResponseWriter, _ *httptest.Se
 		return &response
 	}
 
-	if r.URL.String() == "/repos/foo/bar/commits/fooSha2/statuses" {
+	if r.URL.Path == "/repos/foo/bar/commits/fooSha2/statuses" {
 		bytes, _ := json.Marshal([]*github.RepoStatus{})
 		response := string(bytes)
 		return &response
@@ -382,12 +389,115 @@ func handleCommitsComparisonRequests(r *http.Request, _ http.ResponseWriter, _ *
 	return nil
 }
 
+func handleUpdateBranchRequests(r *http.Request, _ http.ResponseWriter, _ *httptest.Server) *string {
+	if r.Method == "PUT" && r.URL.String() == "/repos/own/rep/pulls/1/update-branch" {
+		response := `{"message":"Updating pull request branch."}`
+		return &response
+	}
+
+	return nil
+}
+
+func handleGraphQLRequests(r *http.Request, _ http.ResponseWriter, _ *httptest.Server) *string {
+	if r.Method != "POST" || r.URL.String() != "/graphql" {
+		return nil
+	}
+
+	body, _ := ioutil.ReadAll(r.Body)
+	response := `{"data":{"repository":{"pullRequest":{"id":"PR_1"}}}}`
+	if bytes.Contains(body, []byte("mutation")) {
+		response = `{"data":{}}`
+	}
+
+	return &response
+}
+
+// getRecordingTestServer answers every request with an empty object and records "{method} {url} {body}" for each one
+func getRecordingTestServer(requests *[]string, failureURL string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		*requests = append(*requests, strings.TrimSpace(fmt.Sprintf("%s %s %s", r.Method, r.URL.String(), body)))
+		if r.URL.String() == failureURL {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		fmt.Fprint(w, "{}")
+	}))
+}
+
+// getRecordingOverridingTestServer answers with responses when they have the url and with inner otherwise
+// It records "{method} {url} {body}" for every request that isn't a GET
+func getRecordingOverridingTestServer(inner *httptest.Server, requests *[]string, responses map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			body, _ := ioutil.ReadAll(r.Body)
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+			*requests = append(*requests, strings.TrimSpace(fmt.Sprintf("%s %s %s", r.Method, r.URL.String(), body)))
+		}
+
+		if response, ok := responses[r.URL.String()]; ok {
+			fmt.Fprint(w, response)
+			return
+		}
+
+		inner.Config.Handler.ServeHTTP(w, r)
+	}))
+}
+
+// mergeResponses returns defaults with responses served in their place
+func mergeResponses(defaults, responses map[string]string) map[string]string {
+	merged := make(map[string]string, len(defaults)+len(responses))
+	for url, response := range defaults {
+		merged[url] = response
+	}
+
+	for url, response := range responses {
+		merged[url] = response
+	}
+
+	return merged
+}
+
+// runAgainstServer runs cmd with the foo profile pointed at ts after modifyProfile changed it and setFlags added to its flags
+// It returns what cmd wrote to the app's writer and error writer
+func runAgainstServer(t *testing.T, ts *httptest.Server, cmd func(*cli.Context) error, modifyProfile func(*config.Profile), setFlags func(*flag.FlagSet)) (*bytes.Buffer, *bytes.Buffer, error) {
+	t.Helper()
+	conf, configFileName := getConfigWithAPIURL(t, ts.URL)
+	defer removeFile(t, configFileName)
+	profile := conf.Profiles["foo"]
+	modifyProfile(&profile)
+	conf.Profiles["foo"] = profile
+	assert.Nil(t, conf.Write(configFileName))
+	set := getBaseFlagSet(configFileName)
+	setFlags(set)
+	app, writer, errWriter := appWithTestWriters()
+	err := cmd(cli.NewContext(app, set, nil))
+	return writer, errWriter, err
+}
+
+// withRepo returns a modifyProfile for runAgainstServer that changes own/rep
+func withRepo(modifyRepo func(*config.Repo)) func(*config.Profile) {
+	return func(profile *config.Profile) {
+		modifyRepo(&profile.TrackedRepos[1])
+	}
+}
+
+// withArgs returns a setFlags for runAgainstServer that parses args after setFlags added its flags
+func withArgs(t *testing.T, args []string, setFlags func(*flag.FlagSet)) func(*flag.FlagSet) {
+	return func(set *flag.FlagSet) {
+		setFlags(set)
+		assert.Nil(t, set.Parse(args))
+	}
+}
+
 func newPullRequest(number int, title, owner, label, ref, sha, baseLabel, baseRef string) *github.PullRequest {
 	headSSHURL := fmt.Sprintf("%sSSHURL", label)
 	baseSSHURL := fmt.Sprintf("%sSSHURL", baseLabel)
 	return &github.PullRequest{
 		Number: &number,
 		Title:  &title,
+		State:  github.String("open"),
 		Head: &github.PullRequestBranch{
 			Label: &label,
 			Ref:   &ref,
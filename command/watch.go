@@ -0,0 +1,271 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/gregjones/httpcache"
+	"github.com/gregjones/httpcache/diskcache"
+	"github.com/guywithnose/pull-request-parser/config"
+	"github.com/urfave/cli"
+)
+
+// maxBackoff is the most intervals the watcher waits between cycles after repeated failures
+const maxBackoff = 8
+
+type watchOptions struct {
+	interval  time.Duration
+	maxCycles int
+	useCache  bool
+	waitForCI bool
+	ciTimeout time.Duration
+}
+
+func watchOptionsFromFlags(c *cli.Context) watchOptions {
+	return watchOptions{
+		interval:  c.Duration("interval"),
+		maxCycles: c.Int("max-cycles"),
+		useCache:  c.Bool("use-cache"),
+		waitForCI: c.Bool("wait-for-ci"),
+		ciTimeout: c.Duration("ci-timeout"),
+	}
+}
+
+// watcher keeps pull requests rebased by repeatedly running auto-rebase
+type watcher struct {
+	rebaser *rebaser
+	profile *config.Profile
+	writer  io.Writer
+	options watchOptions
+	// targets holds the commit each target branch pointed to when its pull request was last up to date
+	targets map[string]string
+}
+
+func newWatcher(rebaser *rebaser, profile *config.Profile, writer io.Writer, options watchOptions) *watcher {
+	return &watcher{
+		rebaser: rebaser,
+		profile: profile,
+		writer:  writer,
+		options: options,
+		targets: make(map[string]string),
+	}
+}
+
+func (w *watcher) watch(owner string, repos []string, pullRequestNumber int) error {
+	err := w.options.validate()
+	if err != nil {
+		return err
+	}
+
+	client, cache, err := getWatchClient(w.profile, w.options)
+	if err != nil {
+		return err
+	}
+
+	return repeatCycles(w.options, w.writer, func() (string, bool, error) {
+		cache.nextCycle()
+		summary, err := w.runCycle(client, owner, repos, pullRequestNumber)
+		if w.options.waitForCI {
+			newBuildWaiter(w.rebaser.verboseWriter, w.options.ciTimeout).waitForBuilds(summary.rebased, w.writer)
+		}
+
+		return describeRebaseSummary(summary, err), err != nil || len(summary.failed) != 0, err
+	})
+}
+
+func (options watchOptions) validate() error {
+	if options.interval <= 0 {
+		return cli.NewExitError(fmt.Sprintf("Invalid interval: %s (must be positive)", options.interval), 1)
+	}
+
+	return nil
+}
+
+// getWatchClient returns a client that keeps responses between cycles so unchanged resources are only revalidated with their ETags
+// The returned cycleCache is nil when the disk cache is used
+func getWatchClient(profile *config.Profile, options watchOptions) (*github.Client, *cycleCache, error) {
+	if options.useCache {
+		client, err := getCachingGithubClient(&profile.Token, &profile.APIURL, diskcache.New(fmt.Sprintf("%s/prpCache", os.TempDir())))
+		return client, nil, err
+	}
+
+	cache := newCycleCache()
+	client, err := getCachingGithubClient(&profile.Token, &profile.APIURL, cache)
+	return client, cache, err
+}
+
+// cycleCache is an in-memory httpcache.Cache that only keeps the responses used during the last cycle
+// Responses for pull requests and branches that went away are dropped instead of piling up while watching
+type cycleCache struct {
+	mutex    sync.Mutex
+	current  map[string][]byte
+	previous map[string][]byte
+}
+
+var _ httpcache.Cache = &cycleCache{}
+
+func newCycleCache() *cycleCache {
+	return &cycleCache{current: make(map[string][]byte), previous: make(map[string][]byte)}
+}
+
+func (c *cycleCache) Get(key string) ([]byte, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if response, ok := c.current[key]; ok {
+		return response, true
+	}
+
+	response, ok := c.previous[key]
+	if ok {
+		c.current[key] = response
+	}
+
+	return response, ok
+}
+
+func (c *cycleCache) Set(key string, response []byte) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.current[key] = response
+}
+
+func (c *cycleCache) Delete(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.current, key)
+	delete(c.previous, key)
+}
+
+// nextCycle forgets the responses that weren't used since it was last called
+func (c *cycleCache) nextCycle() {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.previous = c.current
+	c.current = make(map[string][]byte)
+}
+
+// repeatCycles runs cycle every interval until maxCycles is reached or the process is interrupted
+// A one line summary is printed after every cycle and the wait grows after failed cycles
+func repeatCycles(options watchOptions, writer io.Writer, cycle func() (summary string, failed bool, err error)) error {
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	failures := 0
+	for number := 1; ; number++ {
+		summary, failed, err := cycle()
+		if failed {
+			failures++
+		} else {
+			failures = 0
+		}
+
+		wait := options.nextWait(failures)
+		fmt.Fprintf(writer, "%s cycle %d: %s, next cycle in %s\n", time.Now().Format("15:04:05"), number, summary, wait)
+		if options.maxCycles != 0 && number >= options.maxCycles {
+			return err
+		}
+
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (w *watcher) runCycle(client *github.Client, owner string, repos []string, pullRequestNumber int) (rebaseSummary, error) {
+	pullRequests, err := getPullRequestsWithClient(client, w.profile, owner, repos, w.rebaser.errorWriter)
+	if err != nil {
+		fmt.Fprintf(w.rebaser.errorWriter, "Unable to list pull requests\n%v\n", err)
+		return rebaseSummary{}, cli.NewExitError("Unable to list pull requests", 1)
+	}
+
+	selectedPullRequests := selectPullRequests(pullRequests, pullRequestNumber)
+
+	targets := w.getTargets(client, selectedPullRequests)
+	for _, pr := range selectedPullRequests {
+		target := targets[targetKey(pr)]
+		if !pr.Rebased && target != "" && w.targets[pullRequestKey(pr)] == target {
+			fmt.Fprintf(w.rebaser.verboseWriter, "%s has not moved since PR #%d in %s/%s was updated\n", pr.TargetBranch, pr.PullRequestID, pr.Repo.Owner, pr.Repo.Name)
+			pr.Rebased = true
+		}
+	}
+
+	summary, err := w.rebaser.rebaseSelectedPullRequests(selectedPullRequests)
+
+	// Skipped and failed pull requests are not remembered so they are tried again next cycle
+	w.targets = make(map[string]string)
+	for _, pr := range append(summary.rebased, summary.upToDate...) {
+		if target := targets[targetKey(pr)]; target != "" {
+			w.targets[pullRequestKey(pr)] = target
+		}
+	}
+
+	return summary, err
+}
+
+// getTargets returns the commit each target branch points to
+// Branches that can't be resolved map to "" so their pull requests are always checked
+func (w *watcher) getTargets(client *github.Client, pullRequests []*pullRequest) map[string]string {
+	targets := make(map[string]string)
+	for _, pr := range pullRequests {
+		key := targetKey(pr)
+		if _, ok := targets[key]; ok {
+			continue
+		}
+
+		ref, _, err := client.Git.GetRef(context.Background(), pr.Repo.Owner, pr.Repo.Name, fmt.Sprintf("heads/%s", pr.TargetBranch))
+		if err != nil {
+			fmt.Fprintf(w.rebaser.verboseWriter, "Unable to resolve %s in %s/%s\n%v\n", pr.TargetBranch, pr.Repo.Owner, pr.Repo.Name, err)
+			targets[key] = ""
+			continue
+		}
+
+		targets[key] = ref.Object.GetSHA()
+	}
+
+	return targets
+}
+
+func (options watchOptions) nextWait(failures int) time.Duration {
+	backoff := 1
+	for i := 0; i < failures && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+
+	return options.interval * time.Duration(backoff)
+}
+
+func describeRebaseSummary(summary rebaseSummary, err error) string {
+	if err != nil && len(summary.failed) == 0 {
+		return err.Error()
+	}
+
+	return fmt.Sprintf(
+		"%d rebased, %d up to date, %d skipped, %d failed",
+		len(summary.rebased),
+		len(summary.upToDate),
+		len(summary.skipped),
+		len(summary.failed),
+	)
+}
+
+func pullRequestKey(pr *pullRequest) string {
+	return fmt.Sprintf("%s/%s#%d", pr.Repo.Owner, pr.Repo.Name, pr.PullRequestID)
+}
+
+func targetKey(pr *pullRequest) string {
+	return fmt.Sprintf("%s/%s:%s", pr.Repo.Owner, pr.Repo.Name, pr.TargetBranch)
+}
@@ -15,14 +15,201 @@ type Profile struct {
 	TrackedRepos []Repo `json:"trackedRepos,omitempty"`
 	Token        string `json:"token,omitempty"`
 	APIURL       string `json:"apiUrl,omitempty"`
+	// CommentTemplates maps names to the text/template messages comment can post
+	CommentTemplates map[string]string `json:"commentTemplates,omitempty"`
 }
 
 // Repo defines the structure of pull request parser tracked repo entry
 type Repo struct {
-	Owner         string   `json:"owner,omitempty"`
-	Name          string   `json:"name,omitempty"`
-	LocalPath     string   `json:"localPath,omitempty"`
-	IgnoredBuilds []string `json:"ignoredBuilds,omitempty"`
+	Owner               string               `json:"owner,omitempty"`
+	Name                string               `json:"name,omitempty"`
+	LocalPath           string               `json:"localPath,omitempty"`
+	IgnoredBuilds       []string             `json:"ignoredBuilds,omitempty"`
+	UpdateStrategy      string               `json:"updateStrategy,omitempty"`
+	RebasePolicy        *RebasePolicy        `json:"rebasePolicy,omitempty"`
+	Autosquash          bool                 `json:"autosquash,omitempty"`
+	PrePushHooks        []string             `json:"prePushHooks,omitempty"`
+	PostPushHooks       []string             `json:"postPushHooks,omitempty"`
+	SignCommits         bool                 `json:"signCommits,omitempty"`
+	MergePolicy         *MergePolicy         `json:"mergePolicy,omitempty"`
+	StalePolicy         *StalePolicy         `json:"stalePolicy,omitempty"`
+	PullRequestDefaults *PullRequestDefaults `json:"pullRequestDefaults,omitempty"`
+	ReviewerRules       *ReviewerRules       `json:"reviewerRules,omitempty"`
+}
+
+// Hook types define when auto-rebase runs a hook command
+const (
+	HookPrePush  = "pre-push"
+	HookPostPush = "post-push"
+)
+
+// HookTypes lists all of the valid hook types
+var HookTypes = []string{HookPrePush, HookPostPush}
+
+// Hooks returns the hook commands of the given type or nil if the type is not valid
+func (repo *Repo) Hooks(hookType string) *[]string {
+	switch hookType {
+	case HookPrePush:
+		return &repo.PrePushHooks
+	case HookPostPush:
+		return &repo.PostPushHooks
+	}
+
+	return nil
+}
+
+// RebasePolicy defines the conditions a pull request must meet before auto-rebase updates it
+type RebasePolicy struct {
+	MinApprovals         int      `json:"minApprovals,omitempty"`
+	RequiredLabels       []string `json:"requiredLabels,omitempty"`
+	SkipDrafts           bool     `json:"skipDrafts,omitempty"`
+	RequirePassingBuilds bool     `json:"requirePassingBuilds,omitempty"`
+}
+
+// MergePolicy defines the conditions a pull request must meet before merge merges it and how it is merged
+type MergePolicy struct {
+	MinApprovals   int      `json:"minApprovals,omitempty"`
+	RequiredLabels []string `json:"requiredLabels,omitempty"`
+	Method         string   `json:"method,omitempty"`
+	DeleteBranch   bool     `json:"deleteBranch,omitempty"`
+}
+
+// StalePolicy defines when stale considers a pull request stale and what it does about it
+// Days and GracePeriod are numbers of days
+type StalePolicy struct {
+	Days        int    `json:"days,omitempty"`
+	Warn        bool   `json:"warn,omitempty"`
+	Label       string `json:"label,omitempty"`
+	Close       bool   `json:"close,omitempty"`
+	GracePeriod int    `json:"gracePeriod,omitempty"`
+}
+
+// PullRequestDefaults defines what open fills in when it opens a pull request
+// Template is the path of a text/template file that renders the body, relative paths start at the repo's local path
+type PullRequestDefaults struct {
+	Template  string   `json:"template,omitempty"`
+	Reviewers []string `json:"reviewers,omitempty"`
+	Labels    []string `json:"labels,omitempty"`
+}
+
+// ReviewerRules defines who assign-reviewers asks to review a repo's pull requests
+// Teams maps the teams named in CODEOWNERS, like org/team, to their members
+// Required is how many reviewers each pull request needs
+type ReviewerRules struct {
+	Required int                 `json:"required,omitempty"`
+	Teams    map[string][]string `json:"teams,omitempty"`
+	Exclude  []string            `json:"exclude,omitempty"`
+}
+
+// Merge methods define how Github merges a pull request
+const (
+	MergeMethodMerge  = "merge"
+	MergeMethodSquash = "squash"
+	MergeMethodRebase = "rebase"
+)
+
+// MergeMethods lists all of the valid merge methods
+var MergeMethods = []string{MergeMethodMerge, MergeMethodSquash, MergeMethodRebase}
+
+// Update strategies define how auto-rebase brings a pull request up to date with its target branch
+const (
+	UpdateStrategyRebase = "rebase"
+	UpdateStrategyMerge  = "merge"
+	UpdateStrategyAPI    = "api"
+)
+
+// UpdateStrategies lists all of the valid update strategies
+var UpdateStrategies = []string{UpdateStrategyRebase, UpdateStrategyMerge, UpdateStrategyAPI}
+
+// Merge returns a policy that enforces the conditions of both policies
+func (policy RebasePolicy) Merge(other *RebasePolicy) RebasePolicy {
+	if other == nil {
+		return policy
+	}
+
+	merged := RebasePolicy{
+		MinApprovals:         policy.MinApprovals,
+		RequiredLabels:       append([]string{}, policy.RequiredLabels...),
+		SkipDrafts:           policy.SkipDrafts || other.SkipDrafts,
+		RequirePassingBuilds: policy.RequirePassingBuilds || other.RequirePassingBuilds,
+	}
+
+	if other.MinApprovals > merged.MinApprovals {
+		merged.MinApprovals = other.MinApprovals
+	}
+
+	for _, label := range other.RequiredLabels {
+		if !containsString(merged.RequiredLabels, label) {
+			merged.RequiredLabels = append(merged.RequiredLabels, label)
+		}
+	}
+
+	return merged
+}
+
+// Merge returns a policy that enforces the conditions of both policies
+// The method of policy wins when both are set and the head branch is deleted if either policy deletes it
+func (policy MergePolicy) Merge(other *MergePolicy) MergePolicy {
+	if other == nil {
+		return policy
+	}
+
+	conditions := RebasePolicy{MinApprovals: policy.MinApprovals, RequiredLabels: policy.RequiredLabels}.Merge(
+		&RebasePolicy{MinApprovals: other.MinApprovals, RequiredLabels: other.RequiredLabels},
+	)
+
+	merged := MergePolicy{
+		MinApprovals:   conditions.MinApprovals,
+		RequiredLabels: conditions.RequiredLabels,
+		Method:         policy.Method,
+		DeleteBranch:   policy.DeleteBranch || other.DeleteBranch,
+	}
+
+	if merged.Method == "" {
+		merged.Method = other.Method
+	}
+
+	return merged
+}
+
+// Merge returns a policy that takes every action either policy takes
+// The values of policy win over the values of other when both are set
+func (policy StalePolicy) Merge(other *StalePolicy) StalePolicy {
+	if other == nil {
+		return policy
+	}
+
+	merged := StalePolicy{
+		Days:        policy.Days,
+		Warn:        policy.Warn || other.Warn,
+		Label:       policy.Label,
+		Close:       policy.Close || other.Close,
+		GracePeriod: policy.GracePeriod,
+	}
+
+	if merged.Days == 0 {
+		merged.Days = other.Days
+	}
+
+	if merged.Label == "" {
+		merged.Label = other.Label
+	}
+
+	if merged.GracePeriod == 0 {
+		merged.GracePeriod = other.GracePeriod
+	}
+
+	return merged
+}
+
+func containsString(haystack []string, needle string) bool {
+	for _, straw := range haystack {
+		if straw == needle {
+			return true
+		}
+	}
+
+	return false
 }
 
 // LoadFromFile loads a PrpConfig from a file
@@ -140,6 +140,47 @@ func TestProfileUpdate(t *testing.T) {
 	assert.Equal(t, "bar", profile.APIURL)
 }
 
+func TestRebasePolicyMerge(t *testing.T) {
+	policy := config.RebasePolicy{MinApprovals: 1, RequiredLabels: []string{"ready"}}
+	merged := policy.Merge(&config.RebasePolicy{MinApprovals: 2, RequiredLabels: []string{"ready", "qa"}, SkipDrafts: true})
+	assert.Equal(t, config.RebasePolicy{MinApprovals: 2, RequiredLabels: []string{"ready", "qa"}, SkipDrafts: true}, merged)
+	assert.Equal(t, []string{"ready"}, policy.RequiredLabels)
+}
+
+func TestRebasePolicyMergeNil(t *testing.T) {
+	policy := config.RebasePolicy{RequirePassingBuilds: true}
+	assert.Equal(t, policy, policy.Merge(nil))
+}
+
+func TestMergePolicyMerge(t *testing.T) {
+	policy := config.MergePolicy{MinApprovals: 2, RequiredLabels: []string{"ready"}, Method: config.MergeMethodSquash}
+	merged := policy.Merge(&config.MergePolicy{MinApprovals: 1, RequiredLabels: []string{"qa"}, Method: config.MergeMethodRebase, DeleteBranch: true})
+	assert.Equal(t, config.MergePolicy{MinApprovals: 2, RequiredLabels: []string{"ready", "qa"}, Method: config.MergeMethodSquash, DeleteBranch: true}, merged)
+	assert.Equal(t, []string{"ready"}, policy.RequiredLabels)
+}
+
+func TestMergePolicyMergeMethodFromOther(t *testing.T) {
+	policy := config.MergePolicy{}
+	merged := policy.Merge(&config.MergePolicy{Method: config.MergeMethodRebase})
+	assert.Equal(t, config.MergePolicy{RequiredLabels: []string{}, Method: config.MergeMethodRebase}, merged)
+}
+
+func TestMergePolicyMergeNil(t *testing.T) {
+	policy := config.MergePolicy{DeleteBranch: true}
+	assert.Equal(t, policy, policy.Merge(nil))
+}
+
+func TestStalePolicyMerge(t *testing.T) {
+	policy := config.StalePolicy{Days: 14, Warn: true}
+	merged := policy.Merge(&config.StalePolicy{Days: 30, Label: "stale", Close: true, GracePeriod: 7})
+	assert.Equal(t, config.StalePolicy{Days: 14, Warn: true, Label: "stale", Close: true, GracePeriod: 7}, merged)
+}
+
+func TestStalePolicyMergeNil(t *testing.T) {
+	policy := config.StalePolicy{Days: 14, Label: "stale"}
+	assert.Equal(t, policy, policy.Merge(nil))
+}
+
 func getTestingConfig() *config.PrpConfig {
 	return &config.PrpConfig{
 		Profiles: map[string]config.Profile{